@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/funcs"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var CheckCommand *cli.Command
+
+func init() {
+	checkParam := params.NewMigrateCheckParam()
+
+	cliCommand := &cli.Command{
+		Name:    "check",
+		Aliases: []string{"list"},
+		Usage:   "Check whether servers in the account can be migrated",
+		Action: func(c *cli.Context) error {
+			// Set values of the profile to flags not set by the command line
+			if err := applyProfile(c, CheckCommand.Flags); err != nil {
+				return err
+			}
+
+			// Set option values
+			if c.IsSet("selector") {
+				checkParam.Selector = c.StringSlice("selector")
+			}
+			checkParam.History = c.String("throughput-history")
+			if c.IsSet("output-type") {
+				checkParam.OutputType = c.String("output-type")
+			}
+
+			// read API Keys from files/commands/stdin
+			if err := readCredentials(c); err != nil {
+				return err
+			}
+
+			// interactive input when API Keys are empty
+			inputAPIKeys()
+
+			// Validate global params
+			if errors := command.GlobalOption.Validate(false); len(errors) > 0 {
+				return command.FlattenErrorsWithPrefix(errors, "GlobalOptions")
+			}
+
+			// Validate specific for each command params
+			if errors := checkParam.Validate(); len(errors) > 0 {
+				return command.FlattenErrorsWithPrefix(errors, "Options")
+			}
+
+			// create command context
+			ctx := command.NewContext(c, c.Args().Slice(), checkParam)
+
+			return funcs.MigrateCheck(ctx, checkParam)
+		},
+		Flags: append(commonFlags(),
+			&cli.StringSliceFlag{
+				Name:  "selector",
+				Usage: "Set target filter by selector expression",
+			},
+			throughputHistoryFlag(),
+			&cli.StringFlag{
+				Name:    "output-type",
+				Aliases: []string{"o"},
+				Usage:   "Output type [table/csv/json]",
+				Value:   "table",
+			},
+		),
+	}
+	CheckCommand = cliCommand
+}
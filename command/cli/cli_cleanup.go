@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/funcs"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var CleanupCommand *cli.Command
+
+func init() {
+	cleanupParam := params.NewMigrateCleanupParam()
+
+	cliCommand := &cli.Command{
+		Name:        "cleanup",
+		Usage:       "Delete the original disks of migrated servers of the run recorded in the journal",
+		Description: "Without IDs, the original disks of all migrated servers of the run are deleted.",
+		ArgsUsage:   "[ID]...",
+		Action: func(c *cli.Context) error {
+			// Set values of the profile to flags not set by the command line
+			if err := applyProfile(c, CleanupCommand.Flags); err != nil {
+				return err
+			}
+
+			// Set option values
+			cleanupParam.Journal = c.String("journal")
+			if c.IsSet("run") {
+				cleanupParam.RunID = c.String("run")
+			}
+			if c.IsSet("assumeyes") {
+				cleanupParam.Assumeyes = c.Bool("assumeyes")
+			}
+			ids, err := parseIDArgs(c.Args().Slice())
+			if err != nil {
+				return err
+			}
+			cleanupParam.IDs = ids
+
+			// read API Keys from files/commands/stdin
+			if err := readCredentials(c); err != nil {
+				return err
+			}
+
+			// interactive input when API Keys are empty
+			inputAPIKeys()
+
+			// Validate global params
+			if errors := command.GlobalOption.Validate(false); len(errors) > 0 {
+				return command.FlattenErrorsWithPrefix(errors, "GlobalOptions")
+			}
+
+			// Validate specific for each command params
+			if errors := cleanupParam.Validate(); len(errors) > 0 {
+				return command.FlattenErrorsWithPrefix(errors, "Options")
+			}
+
+			if !cleanupParam.Assumeyes && !isTerminal() {
+				return fmt.Errorf("When using redirect/pipe, specify --assumeyes(-y) option")
+			}
+
+			// create command context
+			ctx := command.NewContext(c, c.Args().Slice(), cleanupParam)
+
+			return funcs.MigrateCleanup(ctx, cleanupParam)
+		},
+		Flags: append(commonFlags(),
+			journalFlag(),
+			runFlag(),
+			assumeyesFlag(),
+		),
+	}
+	CleanupCommand = cliCommand
+}
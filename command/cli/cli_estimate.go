@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/funcs"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var EstimateCommand *cli.Command
+
+func init() {
+	estimateParam := params.NewMigrateEstimateParam()
+
+	cliCommand := &cli.Command{
+		Name:  "estimate",
+		Usage: "Estimate downtime and total time of the migration",
+		Action: func(c *cli.Context) error {
+			// Set values of the profile to flags not set by the command line
+			if err := applyProfile(c, EstimateCommand.Flags); err != nil {
+				return err
+			}
+
+			// Set option values
+			if c.IsSet("selector") {
+				estimateParam.Selector = c.StringSlice("selector")
+			}
+			if c.IsSet("workers") {
+				estimateParam.Workers = c.Int("workers")
+			}
+			if c.IsSet("cleanup-disk") {
+				estimateParam.CleanupDisk = c.Bool("cleanup-disk")
+			}
+			if c.IsSet("pre-clone") {
+				estimateParam.PreClone = c.Bool("pre-clone")
+			}
+			estimateParam.History = c.String("throughput-history")
+			if c.IsSet("output-type") {
+				estimateParam.OutputType = c.String("output-type")
+			}
+
+			// read API Keys from files/commands/stdin
+			if err := readCredentials(c); err != nil {
+				return err
+			}
+
+			// interactive input when API Keys are empty
+			inputAPIKeys()
+
+			// Validate global params
+			if errors := command.GlobalOption.Validate(false); len(errors) > 0 {
+				return command.FlattenErrorsWithPrefix(errors, "GlobalOptions")
+			}
+
+			// Validate specific for each command params
+			if errors := estimateParam.Validate(); len(errors) > 0 {
+				return command.FlattenErrorsWithPrefix(errors, "Options")
+			}
+
+			// create command context
+			ctx := command.NewContext(c, c.Args().Slice(), estimateParam)
+
+			return funcs.MigrateEstimate(ctx, estimateParam)
+		},
+		Flags: append(commonFlags(),
+			&cli.StringSliceFlag{
+				Name:  "selector",
+				Usage: "Set target filter by selector expression",
+			},
+			&cli.IntFlag{
+				Name:  "workers",
+				Usage: "Number of servers migrated in parallel",
+				Value: migrate.DefaultMaxWorkerCount,
+			},
+			&cli.BoolFlag{
+				Name:  "cleanup-disk",
+				Usage: "If true, estimate with deleting original disks after migration",
+			},
+			&cli.BoolFlag{
+				Name:  "pre-clone",
+				Usage: "If true, estimate with cloning disks before shutdown",
+			},
+			throughputHistoryFlag(),
+			&cli.StringFlag{
+				Name:    "output-type",
+				Aliases: []string{"o"},
+				Usage:   "Output type [table/json]",
+				Value:   "table",
+			},
+		),
+	}
+	EstimateCommand = cliCommand
+}
@@ -2,12 +2,12 @@ package cli
 
 import (
 	"fmt"
-	"strings"
 
-	"github.com/fatih/color"
 	"github.com/sacloud/cloud-plan-migrate/command"
 	"github.com/sacloud/cloud-plan-migrate/command/funcs"
 	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"github.com/sacloud/cloud-plan-migrate/resolver"
 	"gopkg.in/urfave/cli.v2"
 )
 
@@ -17,238 +17,362 @@ func init() {
 	migrateParam := params.NewMigrateMigrateParam()
 
 	cliCommand := &cli.Command{
-		Name:  "migrate",
-		Usage: "Migrate server/disk plan",
+		Name:    "apply",
+		Aliases: []string{"migrate"},
+		Usage:   "Migrate server/disk plan",
 		Action: func(c *cli.Context) error {
-			// Set option values
-			if c.IsSet("selector") {
-				migrateParam.Selector = c.StringSlice("selector")
-			}
-			if c.IsSet("assumeyes") {
-				migrateParam.Assumeyes = c.Bool("assumeyes")
-			}
-			if c.IsSet("cleanup-disk") {
-				migrateParam.CleanupDisk = c.Bool("cleanup-disk")
-			}
-			if c.IsSet("disable-reboot") {
-				migrateParam.DisableReboot = c.Bool("disable-reboot")
-			}
-			if c.IsSet("id") {
-				migrateParam.ID = c.Int64("id")
-			}
-
-			if c.NArg() == 0 && len(migrateParam.Selector) == 0 {
-				cli.ShowAppHelp(c)
-				return nil
-			}
-
-			// interactive input when API Keys are empty
-			if isTerminal() {
-				c := color.New(color.BgMagenta)
-				if command.GlobalOption.AccessToken == "" {
-					// read input
-					var input string
-					c.Fprintln(command.GlobalOption.Out, "\nYour API AccessToken is not set")
-					fmt.Fprintf(command.GlobalOption.Out, "\t%s: ", "Enter your token")
-					fmt.Fscanln(command.GlobalOption.In, &input)
-					command.GlobalOption.AccessToken = input
-				}
-				if command.GlobalOption.AccessTokenSecret == "" {
-					// read input
-					var input string
-					c.Fprintln(command.GlobalOption.Out, "\nYour API AccessTokenSecret is not set")
-					fmt.Fprintf(command.GlobalOption.Out, "\t%s: ", "Eneter your secret")
-					fmt.Fscanln(command.GlobalOption.In, &input)
-					command.GlobalOption.AccessTokenSecret = input
-				}
-			}
-
-			// Validate global params
-			if errors := command.GlobalOption.Validate(false); len(errors) > 0 {
-				return command.FlattenErrorsWithPrefix(errors, "GlobalOptions")
-			}
-
-			// Validate specific for each command params
-			if errors := migrateParam.Validate(); len(errors) > 0 {
-				return command.FlattenErrorsWithPrefix(errors, "Options")
+			// Set values of the profile to flags not set by the command line
+			if err := applyProfile(c, MigrateCommand.Flags); err != nil {
+				return err
 			}
 
-			// create command context
-			ctx := command.NewContext(c, c.Args().Slice(), migrateParam)
-
-			apiClient := ctx.GetAPIClient().Server
-			ids := []int64{}
-
-			if c.NArg() == 0 {
-
-				if len(migrateParam.Selector) == 0 {
-					return fmt.Errorf("ID or Name argument or --selector option is required")
-				}
-				apiClient.Reset().Limit(1000)
-				res, err := apiClient.Find()
-				if err != nil {
-					return fmt.Errorf("Find ID is failed: %s", err)
-				}
-				for _, v := range res.Servers {
-					if hasTags(&v, migrateParam.Selector) {
-						ids = append(ids, v.GetID())
-					}
-				}
-				if len(ids) == 0 {
-					return fmt.Errorf("Find ID is failed: Not Found[with search param tags=%s]", migrateParam.Selector)
-				}
-
-			} else {
-
-				for _, arg := range c.Args().Slice() {
-
-					for _, a := range strings.Split(arg, "\n") {
-						idOrName := a
-						if id, ok := toSakuraID(idOrName); ok {
-							ids = append(ids, id)
-						} else {
-							apiClient.Reset()
-							apiClient.Limit(1000)
-							apiClient.SetFilterBy("Name", idOrName)
-							res, err := apiClient.Find()
-							if err != nil {
-								return fmt.Errorf("Find ID is failed: %s", err)
-							}
-							if res.Count == 0 {
-								return fmt.Errorf("Find ID is failed: Not Found[with search param %q]", idOrName)
-							}
-							for _, v := range res.Servers {
-								if len(migrateParam.Selector) == 0 || hasTags(&v, migrateParam.Selector) {
-									ids = append(ids, v.GetID())
-								}
-							}
-						}
-					}
-
-				}
-
-			}
+			// Set option values
+			setMigrateParam(c, migrateParam)
 
-			ids = command.UniqIDs(ids)
-			if len(ids) == 0 {
-				return fmt.Errorf("Target resource is not found")
+			query := &resolver.Query{
+				Args:      c.Args().Slice(),
+				Selector:  migrateParam.Selector,
+				Inventory: migrateParam.Inventory,
+				Wave:      migrateParam.Wave,
+				Exclude:   migrateParam.Exclude,
 			}
-			migrateParam.IDs = ids
-
-			// confirm
-			if !migrateParam.Assumeyes {
-				if !isTerminal() {
-					return fmt.Errorf("When using redirect/pipe, specify --assumeyes(-y) option")
-				}
-				if !command.ConfirmContinue("migrate", ids...) {
-					return nil
-				}
+			if !query.HasTargets() {
+				cli.ShowAppHelp(c)
+				return nil
 			}
 
-			return funcs.MigrateMigrate(ctx, migrateParam)
+			return runMigration(c, migrateParam, func(r *resolver.Resolver) (*resolver.Result, error) {
+				return resolveTargets(r, query, migrateParam.DenyList)
+			})
 		},
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:        "token",
-				Usage:       "API Token of SakuraCloud",
-				EnvVars:     []string{"SAKURACLOUD_ACCESS_TOKEN"},
-				DefaultText: "none",
-				Destination: &command.GlobalOption.AccessToken,
-			},
-			&cli.StringFlag{
-				Name:        "secret",
-				Usage:       "API Secret of SakuraCloud",
-				EnvVars:     []string{"SAKURACLOUD_ACCESS_TOKEN_SECRET"},
-				DefaultText: "none",
-				Destination: &command.GlobalOption.AccessTokenSecret,
-			},
-			&cli.StringFlag{
-				Name:        "zone",
-				Usage:       "Target zone of SakuraCloud",
-				Value:       command.DefaultZone,
-				DefaultText: command.DefaultZone,
-				Destination: &command.GlobalOption.Zone,
-				Hidden:      true,
-			},
-			&cli.IntFlag{
-				Name:        "timeout",
-				Usage:       "Number of timeout minutes for polling functions",
-				EnvVars:     []string{"SAKURACLOUD_TIMEOUT"},
-				Value:       60 * 24, // 24h
-				Destination: &command.GlobalOption.Timeout,
-				Hidden:      true,
-			},
-			&cli.StringFlag{
-				Name:        "accept-language",
-				Usage:       "Accept-Language Header",
-				EnvVars:     []string{"SAKURACLOUD_ACCEPT_LANGUAGE"},
-				Destination: &command.GlobalOption.AcceptLanguage,
-				Hidden:      true,
-			},
-			&cli.IntFlag{
-				Name:        "retry-max",
-				Usage:       "Number of API-Client retries",
-				EnvVars:     []string{"SAKURACLOUD_RETRY_MAX"},
-				Destination: &command.GlobalOption.RetryMax,
-				Value:       10,
-				Hidden:      true,
-			},
-			&cli.Int64Flag{
-				Name:        "retry-interval",
-				Usage:       "API client retry interval seconds",
-				EnvVars:     []string{"SAKURACLOUD_RETRY_INTERVAL"},
-				Destination: &command.GlobalOption.RetryIntervalSec,
-				Value:       5,
-				Hidden:      true,
-			},
-			&cli.BoolFlag{
-				Name:        "no-color",
-				Usage:       "Flag of not using ANSI color output",
-				EnvVars:     []string{"NO_COLOR"},
-				Destination: &command.GlobalOption.NoColor,
-				Hidden:      true,
-			},
-			&cli.StringFlag{
-				Name:        "api-root-url",
-				EnvVars:     []string{"cloud-plan-migrate_API_ROOT_URL"},
-				Destination: &command.GlobalOption.APIRootURL,
-				Hidden:      true,
-			},
-			&cli.StringSliceFlag{
-				Name:   "zones",
-				Hidden: true,
-			},
-			&cli.BoolFlag{
-				Name:        "trace",
-				Usage:       "Flag of SakuraCloud debug-mode",
-				EnvVars:     []string{"SAKURACLOUD_TRACE_MODE"},
-				Destination: &command.GlobalOption.TraceMode,
-				Value:       false,
-				Hidden:      true,
-			},
-			&cli.BoolFlag{
-				Name:  "cleanup-disk",
-				Usage: "If true, delete target disk after migration",
-			},
-			&cli.BoolFlag{
-				Name:  "disable-reboot",
-				Usage: "If true, don't boot target server after migration",
-			},
-			&cli.StringSliceFlag{
-				Name:  "selector",
-				Usage: "Set target filter by tag",
-			},
-			&cli.BoolFlag{
-				Name:    "assumeyes",
-				Aliases: []string{"y"},
-				Usage:   "Assume that the answer to any question which would be asked is yes",
-			},
+		Flags: append(append(migrateFlags(), targetFlags()...),
 			&cli.Int64Flag{
 				Name:   "id",
 				Usage:  "Set target ID",
 				Hidden: true,
 			},
-		},
+		),
 	}
 	MigrateCommand = cliCommand
 }
+
+// DeprecatedRootAction runs apply as the deprecated alias of the root command(without subcommands)
+func DeprecatedRootAction(c *cli.Context) error {
+	if c.NArg() > 0 || c.IsSet("selector") || c.IsSet("inventory") {
+		fmt.Fprintln(command.GlobalOption.Err,
+			`Warning: migration without the subcommand is deprecated and will be removed, use "apply" instead`)
+	}
+	return MigrateCommand.Action(c)
+}
+
+// setMigrateParam sets option values of apply/resume to migrateParam
+func setMigrateParam(c *cli.Context, migrateParam *params.MigrateMigrateParam) {
+	if c.IsSet("selector") {
+		migrateParam.Selector = c.StringSlice("selector")
+	}
+	if c.IsSet("assumeyes") {
+		migrateParam.Assumeyes = c.Bool("assumeyes")
+	}
+	if c.IsSet("workers") {
+		migrateParam.Workers = c.Int("workers")
+	}
+	if c.IsSet("cleanup-disk") {
+		migrateParam.CleanupDisk = c.Bool("cleanup-disk")
+	}
+	if c.IsSet("pre-clone") {
+		migrateParam.PreClone = c.Bool("pre-clone")
+	}
+	if c.IsSet("accept-pre-clone-data-loss") {
+		migrateParam.AcceptDataLoss = c.Bool("accept-pre-clone-data-loss")
+	}
+	if c.IsSet("disable-reboot") {
+		migrateParam.DisableReboot = c.Bool("disable-reboot")
+	}
+	if c.IsSet("strict-verify") {
+		migrateParam.StrictVerify = c.Bool("strict-verify")
+	}
+	if c.IsSet("update-references") {
+		migrateParam.UpdateRefs = c.Bool("update-references")
+	}
+	if c.IsSet("reference-command") {
+		migrateParam.RefCommand = c.String("reference-command")
+	}
+	if c.IsSet("disk-plan") {
+		migrateParam.DiskPlan = c.String("disk-plan")
+	}
+	if c.IsSet("before-shutdown-command") {
+		migrateParam.BeforeShutdown = c.String("before-shutdown-command")
+	}
+	if c.IsSet("after-migrate-command") {
+		migrateParam.AfterMigrate = c.String("after-migrate-command")
+	}
+	if c.IsSet("after-boot-command") {
+		migrateParam.AfterBoot = c.String("after-boot-command")
+	}
+	if c.IsSet("list-references") {
+		migrateParam.ListRefs = c.Bool("list-references")
+	}
+	if c.IsSet("compact") {
+		migrateParam.Compact = c.Bool("compact")
+	}
+	if c.IsSet("tui") {
+		migrateParam.TUI = c.Bool("tui")
+	}
+	if c.IsSet("maintenance-window") {
+		migrateParam.Windows = c.StringSlice("maintenance-window")
+	}
+	migrateParam.Webhooks = c.StringSlice("webhook")
+	migrateParam.SlackWebhooks = c.StringSlice("slack-webhook")
+	if c.IsSet("log-level") {
+		migrateParam.LogLevel = c.String("log-level")
+	}
+	if c.IsSet("log-format") {
+		migrateParam.LogFormat = c.String("log-format")
+	}
+	migrateParam.LogFile = c.String("log-file")
+	migrateParam.LogDir = c.String("log-dir")
+	if c.IsSet("log-stderr") {
+		migrateParam.LogStderr = c.Bool("log-stderr")
+	}
+	if c.IsSet("log-syslog") {
+		migrateParam.LogSyslog = c.Bool("log-syslog")
+	}
+	migrateParam.MetricsAddr = c.String("metrics-addr")
+	if c.IsSet("record") {
+		migrateParam.Record = c.String("record")
+	}
+	migrateParam.MailTo = c.StringSlice("mail-to")
+	migrateParam.MailFrom = c.String("mail-from")
+	migrateParam.MailSubject = c.String("mail-subject")
+	migrateParam.SMTPAddr = c.String("smtp-addr")
+	migrateParam.SMTPUser = c.String("smtp-user")
+	migrateParam.SMTPPassword = c.String("smtp-password")
+	if c.IsSet("webhook-template") {
+		migrateParam.WebhookTmpl = c.String("webhook-template")
+	}
+	if c.IsSet("exclude") {
+		migrateParam.Exclude = c.StringSlice("exclude")
+	}
+	migrateParam.DenyList = c.String("deny-list")
+	migrateParam.Journal = c.String("journal")
+	migrateParam.History = c.String("throughput-history")
+	if c.IsSet("inventory") {
+		migrateParam.Inventory = c.String("inventory")
+	}
+	if c.IsSet("wave") {
+		migrateParam.Wave = c.String("wave")
+	}
+	if c.IsSet("id") {
+		migrateParam.ID = c.Int64("id")
+	}
+}
+
+// runMigration resolves target servers and runs the migration after the confirmation
+func runMigration(c *cli.Context, migrateParam *params.MigrateMigrateParam, resolve func(r *resolver.Resolver) (*resolver.Result, error)) error {
+	// read API Keys from files/commands/stdin
+	if err := readCredentials(c); err != nil {
+		return err
+	}
+
+	// interactive input when API Keys are empty
+	inputAPIKeys()
+
+	// Validate global params
+	if errors := command.GlobalOption.Validate(false); len(errors) > 0 {
+		return command.FlattenErrorsWithPrefix(errors, "GlobalOptions")
+	}
+
+	// Validate specific for each command params
+	if errors := migrateParam.Validate(); len(errors) > 0 {
+		return command.FlattenErrorsWithPrefix(errors, "Options")
+	}
+
+	// create command context
+	ctx := command.NewContext(c, c.Args().Slice(), migrateParam)
+
+	client := command.NewIaaSClient(ctx, nil)
+	targets, err := resolve(newResolver(client, migrateParam.Assumeyes))
+	if err != nil {
+		return err
+	}
+	migrateParam.IDs = targets.IDs()
+	migrateParam.ServerOptions = targets.ServerOptions()
+	migrateParam.Protection = targets.Protection
+
+	// pre-clone may be enabled by the inventory
+	if err := migrateParam.ValidatePreClone(); err != nil {
+		return command.FlattenErrorsWithPrefix([]error{err}, "Options")
+	}
+
+	if migrateParam.TUI && !isTerminal() {
+		return fmt.Errorf("--tui option requires terminal")
+	}
+
+	// confirm
+	if !migrateParam.Assumeyes && !migrateParam.ListRefs {
+		if !isTerminal() {
+			return fmt.Errorf("When using redirect/pipe, specify --assumeyes(-y) option")
+		}
+		outputTargets(targets.Targets)
+		if !command.ConfirmContinue("migrate", migrateParam.IDs...) {
+			return nil
+		}
+	}
+
+	return funcs.MigrateMigrate(ctx, migrateParam)
+}
+
+// migrateFlags returns flags of the migration shared by apply and resume
+func migrateFlags() []cli.Flag {
+	return append(commonFlags(),
+		&cli.IntFlag{
+			Name:  "workers",
+			Usage: "Number of servers migrated in parallel",
+			Value: migrate.DefaultMaxWorkerCount,
+		},
+		&cli.BoolFlag{
+			Name:  "cleanup-disk",
+			Usage: "If true, delete target disk after migration",
+		},
+		&cli.BoolFlag{
+			Name:  "pre-clone",
+			Usage: "If true, clone disks before shutdown to minimize downtime(crash-consistent, writes during the copy are not migrated, so only safe for disks that are read-only during the copy, requires --accept-pre-clone-data-loss)",
+		},
+		&cli.BoolFlag{
+			Name:  "accept-pre-clone-data-loss",
+			Usage: "Accept that data written to disks during the copy of pre-clone is LOST(pre-clone is only safe for disks that are read-only during the copy), required with pre-clone",
+		},
+		&cli.BoolFlag{
+			Name:  "disable-reboot",
+			Usage: "If true, don't boot target server after migration",
+		},
+		&cli.BoolFlag{
+			Name:  "strict-verify",
+			Usage: "If true, stop migration before boot when network interfaces are changed by migration",
+		},
+		&cli.BoolFlag{
+			Name:  "update-references",
+			Usage: "If true, rewrite tags/descriptions of other resources referring to the old server ID after migration",
+		},
+		&cli.StringFlag{
+			Name:  "reference-command",
+			Usage: "Command to update references in external systems (executed with OLD_SERVER_ID/NEW_SERVER_ID env)",
+		},
+		&cli.StringFlag{
+			Name:  "disk-plan",
+			Usage: "Plan of cloned disks [ssd/hdd], the plan of original disks if empty",
+		},
+		&cli.StringFlag{
+			Name:  "before-shutdown-command",
+			Usage: "Command executed before cloning disks and shutting down each server, the server is not migrated if it fails (executed with SERVER_ID/SERVER_NAME env)",
+		},
+		&cli.StringFlag{
+			Name:  "after-migrate-command",
+			Usage: "Command executed after the plan of each server is changed (executed with SERVER_ID/SERVER_NAME/MIGRATED_SERVER_ID env)",
+		},
+		&cli.StringFlag{
+			Name:  "after-boot-command",
+			Usage: "Command executed after each server is booted (executed with SERVER_ID/SERVER_NAME/MIGRATED_SERVER_ID env)",
+		},
+		&cli.BoolFlag{
+			Name:  "list-references",
+			Usage: "If true, list references to be updated and exit without migration",
+		},
+		&cli.BoolFlag{
+			Name:  "compact",
+			Usage: "If true, show only running/failed servers and collapse others into totals",
+		},
+		&cli.StringSliceFlag{
+			Name:  "maintenance-window",
+			Usage: "Set windows to shut down servers('HH:MM-HH:MM' daily or 'YYYY-MM-DD HH:MM/YYYY-MM-DD HH:MM')",
+		},
+		&cli.BoolFlag{
+			Name:  "tui",
+			Usage: "If true, show interactive screen to inspect servers, pause/resume, skip and rollback",
+		},
+		&cli.StringSliceFlag{
+			Name:    "webhook",
+			Usage:   "Set URLs of webhooks notified of run start/end and completion/failure of each server",
+			EnvVars: []string{"CLOUD_PLAN_MIGRATE_WEBHOOK"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "slack-webhook",
+			Usage:   "Set URLs of Slack-compatible incoming webhooks notified of migration events",
+			EnvVars: []string{"CLOUD_PLAN_MIGRATE_SLACK_WEBHOOK"},
+		},
+		&cli.StringFlag{
+			Name:  "webhook-template",
+			Usage: "Path of the payload template(Go text/template) for --webhook",
+		},
+		&cli.StringFlag{
+			Name:  "log-level",
+			Usage: "Set log level(debug/info/warn/error)",
+			Value: "info",
+		},
+		&cli.StringFlag{
+			Name:  "log-format",
+			Usage: "Set log format(text/logfmt/json)",
+			Value: "text",
+		},
+		&cli.StringFlag{
+			Name:    "log-file",
+			Usage:   "Path of the log file(default: migrate-<timestamp>.log in --log-dir)",
+			EnvVars: []string{"CLOUD_PLAN_MIGRATE_LOG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "log-dir",
+			Usage:   "Directory of the log file and the report(default: current directory)",
+			EnvVars: []string{"CLOUD_PLAN_MIGRATE_LOG_DIR"},
+		},
+		&cli.BoolFlag{
+			Name:  "log-stderr",
+			Usage: "If true, write logs to stderr in addition to the log file",
+		},
+		&cli.BoolFlag{
+			Name:  "log-syslog",
+			Usage: "If true, write logs to the local syslog in addition to the log file(except on Windows)",
+		},
+		&cli.StringFlag{
+			Name:    "metrics-addr",
+			Usage:   "Set address to expose Prometheus metrics at /metrics during the migration(e.g. ':9100')",
+			EnvVars: []string{"CLOUD_PLAN_MIGRATE_METRICS_ADDR"},
+		},
+		&cli.StringFlag{
+			Name:  "record",
+			Usage: "Set file path to record API calls of the migration as JSON lines(secrets are redacted)",
+		},
+		&cli.StringSliceFlag{
+			Name:    "mail-to",
+			Usage:   "Set recipients of the summary mail sent at the end of the migration",
+			EnvVars: []string{"CLOUD_PLAN_MIGRATE_MAIL_TO"},
+		},
+		&cli.StringFlag{
+			Name:    "mail-from",
+			Usage:   "Sender address of the summary mail",
+			EnvVars: []string{"CLOUD_PLAN_MIGRATE_MAIL_FROM"},
+		},
+		&cli.StringFlag{
+			Name:  "mail-subject",
+			Usage: "Subject of the summary mail(default: result totals)",
+		},
+		&cli.StringFlag{
+			Name:    "smtp-addr",
+			Usage:   "Address of the SMTP server(host:port)",
+			EnvVars: []string{"CLOUD_PLAN_MIGRATE_SMTP_ADDR"},
+		},
+		&cli.StringFlag{
+			Name:    "smtp-user",
+			Usage:   "User name of SMTP authentication",
+			EnvVars: []string{"CLOUD_PLAN_MIGRATE_SMTP_USER"},
+		},
+		&cli.StringFlag{
+			Name:        "smtp-password",
+			Usage:       "Password of SMTP authentication",
+			EnvVars:     []string{"CLOUD_PLAN_MIGRATE_SMTP_PASSWORD"},
+			DefaultText: "none",
+		},
+		throughputHistoryFlag(),
+		journalFlag(),
+		assumeyesFlag(),
+	)
+}
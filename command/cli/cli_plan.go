@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/funcs"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"github.com/sacloud/cloud-plan-migrate/resolver"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var PlanCommand *cli.Command
+
+func init() {
+	planParam := params.NewMigratePlanParam()
+
+	cliCommand := &cli.Command{
+		Name:      "plan",
+		Usage:     "Show eligibility and the estimate of target servers without migration",
+		ArgsUsage: "[ID or Name]...",
+		Action: func(c *cli.Context) error {
+			// Set values of the profile to flags not set by the command line
+			if err := applyProfile(c, PlanCommand.Flags); err != nil {
+				return err
+			}
+
+			// Set option values
+			if c.IsSet("selector") {
+				planParam.Selector = c.StringSlice("selector")
+			}
+			if c.IsSet("exclude") {
+				planParam.Exclude = c.StringSlice("exclude")
+			}
+			planParam.DenyList = c.String("deny-list")
+			if c.IsSet("inventory") {
+				planParam.Inventory = c.String("inventory")
+			}
+			if c.IsSet("wave") {
+				planParam.Wave = c.String("wave")
+			}
+			if c.IsSet("workers") {
+				planParam.Workers = c.Int("workers")
+			}
+			if c.IsSet("cleanup-disk") {
+				planParam.CleanupDisk = c.Bool("cleanup-disk")
+			}
+			if c.IsSet("pre-clone") {
+				planParam.PreClone = c.Bool("pre-clone")
+			}
+			planParam.History = c.String("throughput-history")
+			if c.IsSet("output-type") {
+				planParam.OutputType = c.String("output-type")
+			}
+
+			query := &resolver.Query{
+				Args:      c.Args().Slice(),
+				Selector:  planParam.Selector,
+				Inventory: planParam.Inventory,
+				Wave:      planParam.Wave,
+				Exclude:   planParam.Exclude,
+			}
+			if !query.HasTargets() {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return nil
+			}
+
+			// read API Keys from files/commands/stdin
+			if err := readCredentials(c); err != nil {
+				return err
+			}
+
+			// interactive input when API Keys are empty
+			inputAPIKeys()
+
+			// Validate global params
+			if errors := command.GlobalOption.Validate(false); len(errors) > 0 {
+				return command.FlattenErrorsWithPrefix(errors, "GlobalOptions")
+			}
+
+			// Validate specific for each command params
+			if errors := planParam.Validate(); len(errors) > 0 {
+				return command.FlattenErrorsWithPrefix(errors, "Options")
+			}
+
+			// create command context
+			ctx := command.NewContext(c, c.Args().Slice(), planParam)
+
+			r := newResolver(command.NewIaaSClient(ctx, nil), false)
+			targets, err := resolveTargets(r, query, planParam.DenyList)
+			if err != nil {
+				return err
+			}
+			planParam.IDs = targets.IDs()
+			planParam.ServerOptions = targets.ServerOptions()
+
+			return funcs.MigratePlan(ctx, planParam)
+		},
+		Flags: append(append(commonFlags(), targetFlags()...),
+			&cli.IntFlag{
+				Name:  "workers",
+				Usage: "Number of servers migrated in parallel",
+				Value: migrate.DefaultMaxWorkerCount,
+			},
+			&cli.BoolFlag{
+				Name:  "cleanup-disk",
+				Usage: "If true, estimate with deleting original disks after migration",
+			},
+			&cli.BoolFlag{
+				Name:  "pre-clone",
+				Usage: "If true, estimate with cloning disks before shutdown",
+			},
+			throughputHistoryFlag(),
+			&cli.StringFlag{
+				Name:    "output-type",
+				Aliases: []string{"o"},
+				Usage:   "Output type [table/json]",
+				Value:   "table",
+			},
+		),
+	}
+	PlanCommand = cliCommand
+}
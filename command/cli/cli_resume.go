@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"github.com/sacloud/cloud-plan-migrate/resolver"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var ResumeCommand *cli.Command
+
+func init() {
+	resumeParam := params.NewMigrateMigrateParam()
+
+	cliCommand := &cli.Command{
+		Name:  "resume",
+		Usage: "Migrate servers left unmigrated by the run recorded in the journal",
+		Action: func(c *cli.Context) error {
+			// Set values of the profile to flags not set by the command line
+			if err := applyProfile(c, ResumeCommand.Flags); err != nil {
+				return err
+			}
+
+			// Set option values
+			setMigrateParam(c, resumeParam)
+			runID := c.String("run")
+
+			return runMigration(c, resumeParam, func(r *resolver.Resolver) (*resolver.Result, error) {
+				return resumeTargets(r, resumeParam, runID)
+			})
+		},
+		Flags: append(migrateFlags(), denyListFlag(), runFlag()),
+	}
+	ResumeCommand = cliCommand
+}
+
+// resumeTargets finds servers which were not migrated in the run(queued, waiting or rolled back)
+//
+// Servers interrupted while running are resumed from the step recorded in the journal with the cloned disks.
+// Failed servers and servers interrupted after the plan change are reported to stderr and not resumed.
+// Per-server options of the inventory are not restored.
+func resumeTargets(r *resolver.Resolver, migrateParam *params.MigrateMigrateParam, runID string) (*resolver.Result, error) {
+	run, err := command.FindJournalRun(migrateParam.Journal, runID)
+	if err != nil {
+		return nil, fmt.Errorf("Reading journal is failed: %s", err)
+	}
+
+	var ids []string
+	var failed, running []string
+	for _, s := range run.Servers {
+		switch {
+		case command.IsResumeTarget(s):
+			ids = append(ids, fmt.Sprintf("%d", s.ServerID))
+		case s.State == migrate.ServerStateFailed:
+			failed = append(failed, fmt.Sprintf("%d(%s)", s.ServerID, s.ServerName))
+		case s.State == migrate.ServerStateRunning:
+			running = append(running, fmt.Sprintf("%d(%s)=>%d", s.ServerID, s.ServerName, s.MigratedServerID))
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(command.GlobalOption.Err, "Failed servers are not resumed, roll back them first: %s\n", strings.Join(failed, ", "))
+	}
+	if len(running) > 0 {
+		fmt.Fprintf(command.GlobalOption.Err, "Servers interrupted after the plan change are not resumed, connect the cloned disks and boot them manually: %s\n", strings.Join(running, ", "))
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("No servers to resume in run %s", run.RunID)
+	}
+
+	result, err := resolveTargets(r, &resolver.Query{Args: ids}, migrateParam.DenyList)
+	if err != nil {
+		return nil, err
+	}
+	setResumeRecords(result, run)
+	return result, nil
+}
+
+// setResumeRecords sets records of servers interrupted while running to options of targets, to continue from the recorded step
+func setResumeRecords(result *resolver.Result, run *migrate.JournalRun) {
+	for _, t := range result.Targets {
+		record := run.Server(t.Server.ID)
+		if record == nil || record.State != migrate.ServerStateRunning {
+			continue
+		}
+		if t.Options == nil {
+			t.Options = &migrate.ServerOptions{}
+		}
+		t.Options.Resume = record
+	}
+}
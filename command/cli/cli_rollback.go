@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/funcs"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var RollbackCommand *cli.Command
+
+func init() {
+	rollbackParam := params.NewMigrateRollbackParam()
+
+	cliCommand := &cli.Command{
+		Name:        "rollback",
+		Usage:       "Restore failed servers of the run recorded in the journal",
+		Description: "Without IDs, all failed servers of the run are rolled back.",
+		ArgsUsage:   "[ID]...",
+		Action: func(c *cli.Context) error {
+			// Set values of the profile to flags not set by the command line
+			if err := applyProfile(c, RollbackCommand.Flags); err != nil {
+				return err
+			}
+
+			// Set option values
+			rollbackParam.Journal = c.String("journal")
+			if c.IsSet("run") {
+				rollbackParam.RunID = c.String("run")
+			}
+			if c.IsSet("assumeyes") {
+				rollbackParam.Assumeyes = c.Bool("assumeyes")
+			}
+			ids, err := parseIDArgs(c.Args().Slice())
+			if err != nil {
+				return err
+			}
+			rollbackParam.IDs = ids
+
+			// read API Keys from files/commands/stdin
+			if err := readCredentials(c); err != nil {
+				return err
+			}
+
+			// interactive input when API Keys are empty
+			inputAPIKeys()
+
+			// Validate global params
+			if errors := command.GlobalOption.Validate(false); len(errors) > 0 {
+				return command.FlattenErrorsWithPrefix(errors, "GlobalOptions")
+			}
+
+			// Validate specific for each command params
+			if errors := rollbackParam.Validate(); len(errors) > 0 {
+				return command.FlattenErrorsWithPrefix(errors, "Options")
+			}
+
+			if !rollbackParam.Assumeyes && !isTerminal() {
+				return fmt.Errorf("When using redirect/pipe, specify --assumeyes(-y) option")
+			}
+
+			// create command context
+			ctx := command.NewContext(c, c.Args().Slice(), rollbackParam)
+
+			return funcs.MigrateRollback(ctx, rollbackParam)
+		},
+		Flags: append(commonFlags(),
+			journalFlag(),
+			runFlag(),
+			assumeyesFlag(),
+		),
+	}
+	RollbackCommand = cliCommand
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/funcs"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"gopkg.in/urfave/cli.v2"
+)
+
+var StatusCommand *cli.Command
+
+func init() {
+	statusParam := params.NewMigrateStatusParam()
+
+	cliCommand := &cli.Command{
+		Name:  "status",
+		Usage: "Show the state of servers of the run recorded in the journal",
+		Action: func(c *cli.Context) error {
+			// Set option values
+			statusParam.Journal = c.String("journal")
+			if c.IsSet("run") {
+				statusParam.RunID = c.String("run")
+			}
+			if c.IsSet("output-type") {
+				statusParam.OutputType = c.String("output-type")
+			}
+
+			// Validate specific for each command params
+			if errors := statusParam.Validate(); len(errors) > 0 {
+				return command.FlattenErrorsWithPrefix(errors, "Options")
+			}
+
+			// create command context
+			ctx := command.NewContext(c, c.Args().Slice(), statusParam)
+
+			return funcs.MigrateStatus(ctx, statusParam)
+		},
+		Flags: []cli.Flag{
+			journalFlag(),
+			runFlag(),
+			&cli.StringFlag{
+				Name:    "output-type",
+				Aliases: []string{"o"},
+				Usage:   "Output type [table/json]",
+				Value:   "table",
+			},
+		},
+	}
+	StatusCommand = cliCommand
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"gopkg.in/urfave/cli.v2"
+)
+
+// commonFlags returns flags for API client shared by all commands
+func commonFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "profile",
+			Usage:   "Name of the profile(~/.usacloud/<profile>/config.json, shared with usacloud)",
+			EnvVars: []string{"USACLOUD_PROFILE"},
+		},
+		&cli.StringFlag{
+			Name:        "token",
+			Usage:       "API Token of SakuraCloud",
+			EnvVars:     []string{"SAKURACLOUD_ACCESS_TOKEN"},
+			DefaultText: "none",
+			Destination: &command.GlobalOption.AccessToken,
+		},
+		&cli.StringFlag{
+			Name:        "secret",
+			Usage:       "API Secret of SakuraCloud",
+			EnvVars:     []string{"SAKURACLOUD_ACCESS_TOKEN_SECRET"},
+			DefaultText: "none",
+			Destination: &command.GlobalOption.AccessTokenSecret,
+		},
+		&cli.StringFlag{
+			Name:  "token-file",
+			Usage: "Read API Token from the file(must not be accessible by group or others)",
+		},
+		&cli.StringFlag{
+			Name:  "secret-file",
+			Usage: "Read API Secret from the file(must not be accessible by group or others)",
+		},
+		&cli.StringFlag{
+			Name:  "token-command",
+			Usage: "Read API Token from the output of the command(e.g. a password manager CLI)",
+		},
+		&cli.StringFlag{
+			Name:  "secret-command",
+			Usage: "Read API Secret from the output of the command(e.g. a password manager CLI)",
+		},
+		&cli.BoolFlag{
+			Name:  "secret-stdin",
+			Usage: "Read API Secret from the first line of stdin",
+		},
+		&cli.StringFlag{
+			Name:        "zone",
+			Usage:       "Target zone of SakuraCloud",
+			Value:       command.DefaultZone,
+			DefaultText: command.DefaultZone,
+			Destination: &command.GlobalOption.Zone,
+			Hidden:      true,
+		},
+		&cli.IntFlag{
+			Name:        "timeout",
+			Usage:       "Number of timeout minutes for polling functions",
+			EnvVars:     []string{"SAKURACLOUD_TIMEOUT"},
+			Value:       60 * 24, // 24h
+			Destination: &command.GlobalOption.Timeout,
+			Hidden:      true,
+		},
+		&cli.StringFlag{
+			Name:        "accept-language",
+			Usage:       "Accept-Language Header",
+			EnvVars:     []string{"SAKURACLOUD_ACCEPT_LANGUAGE"},
+			Destination: &command.GlobalOption.AcceptLanguage,
+			Hidden:      true,
+		},
+		&cli.IntFlag{
+			Name:        "retry-max",
+			Usage:       "Number of API-Client retries",
+			EnvVars:     []string{"SAKURACLOUD_RETRY_MAX"},
+			Destination: &command.GlobalOption.RetryMax,
+			Value:       10,
+			Hidden:      true,
+		},
+		&cli.Int64Flag{
+			Name:        "retry-interval",
+			Usage:       "API client retry interval seconds",
+			EnvVars:     []string{"SAKURACLOUD_RETRY_INTERVAL"},
+			Destination: &command.GlobalOption.RetryIntervalSec,
+			Value:       5,
+			Hidden:      true,
+		},
+		&cli.Float64Flag{
+			Name:        "api-rate-limit",
+			Usage:       "Max API requests per second shared by all workers(0: unlimited)",
+			EnvVars:     []string{"CLOUD_PLAN_MIGRATE_API_RATE_LIMIT"},
+			Destination: &command.GlobalOption.APIRateLimit,
+		},
+		&cli.IntFlag{
+			Name:        "slow-api-call",
+			Usage:       "Seconds to log API calls as slow(0: disabled)",
+			EnvVars:     []string{"CLOUD_PLAN_MIGRATE_SLOW_API_CALL"},
+			Destination: &command.GlobalOption.SlowAPICallSec,
+			Value:       10,
+		},
+		&cli.BoolFlag{
+			Name:        "no-color",
+			Usage:       "Flag of not using ANSI color output",
+			EnvVars:     []string{"NO_COLOR"},
+			Destination: &command.GlobalOption.NoColor,
+			Hidden:      true,
+		},
+		&cli.StringFlag{
+			Name:        "api-root-url",
+			EnvVars:     []string{"cloud-plan-migrate_API_ROOT_URL"},
+			Destination: &command.GlobalOption.APIRootURL,
+			Hidden:      true,
+		},
+		&cli.StringSliceFlag{
+			Name:   "zones",
+			Hidden: true,
+		},
+		&cli.BoolFlag{
+			Name:        "trace",
+			Usage:       "Flag of SakuraCloud debug-mode",
+			EnvVars:     []string{"SAKURACLOUD_TRACE_MODE"},
+			Destination: &command.GlobalOption.TraceMode,
+			Value:       false,
+			Hidden:      true,
+		},
+	}
+}
+
+func journalFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "journal",
+		Usage:   "Path of the journal file recording the state of servers of each run",
+		EnvVars: []string{"CLOUD_PLAN_MIGRATE_JOURNAL"},
+		Value:   command.DefaultJournalPath(),
+	}
+}
+
+func assumeyesFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:    "assumeyes",
+		Aliases: []string{"y"},
+		Usage:   "Assume that the answer to any question which would be asked is yes",
+	}
+}
+
+func throughputHistoryFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "throughput-history",
+		Usage:   "Path of the file recording clone throughput of previous migrations",
+		EnvVars: []string{"CLOUD_PLAN_MIGRATE_THROUGHPUT_HISTORY"},
+		Value:   command.DefaultThroughputHistoryPath(),
+	}
+}
+
+func runFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "run",
+		Usage: "ID of the run in the journal(default: the last run)",
+	}
+}
@@ -1,10 +1,21 @@
 package cli
 
 import (
+	"fmt"
 	"os"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 
+	"github.com/fatih/color"
 	"github.com/mattn/go-isatty"
+	"github.com/mattn/go-tty"
+	"github.com/olekukonko/tablewriter"
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/resolver"
+	"github.com/sacloud/libsacloud/sacloud"
+	"gopkg.in/urfave/cli.v2"
 )
 
 type FlagHandler interface {
@@ -12,6 +23,75 @@ type FlagHandler interface {
 	Set(name, value string) error
 	String(name string) string
 	StringSlice(name string) []string
+	Bool(name string) bool
+}
+
+// applyProfile sets values of the profile to flags not set by the command line or environment variables
+//
+// Values for flags which are not in flags(e.g. options of other commands) are ignored.
+func applyProfile(c FlagHandler, flags []cli.Flag) error {
+	name := c.String("profile")
+	explicit := name != ""
+	if !explicit {
+		name = command.CurrentProfileName()
+	}
+
+	profile, err := command.LoadProfile(name)
+	if err != nil {
+		return fmt.Errorf("Loading profile is failed: %s", err)
+	}
+	if profile == nil {
+		if explicit {
+			return fmt.Errorf("Loading profile is failed: profile %q is not found", name)
+		}
+		return nil
+	}
+
+	values, err := profile.FlagValues()
+	if err != nil {
+		return fmt.Errorf("Loading profile is failed: %s", err)
+	}
+	defined := map[string]cli.Flag{}
+	for _, f := range flags {
+		for _, n := range f.Names() {
+			defined[n] = f
+		}
+	}
+	var names []string
+	for n := range values {
+		if f, ok := defined[n]; ok && n != "profile" && !c.IsSet(n) && !isSetByEnv(f) {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		for _, v := range values[n] {
+			if err := c.Set(n, v); err != nil {
+				return fmt.Errorf("Loading profile is failed: invalid value of %q: %s", n, err)
+			}
+		}
+	}
+	return nil
+}
+
+// isSetByEnv reports whether any of environment variables of the flag is set
+//
+// Context.IsSet of urfave/cli doesn't report flags set by environment variables.
+func isSetByEnv(f cli.Flag) bool {
+	v := reflect.ValueOf(f)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	envVars := v.FieldByName("EnvVars")
+	if !envVars.IsValid() {
+		return false
+	}
+	for _, env := range envVars.Interface().([]string) {
+		if os.Getenv(strings.TrimSpace(env)) != "" {
+			return true
+		}
+	}
+	return false
 }
 
 func toSakuraID(id string) (int64, bool) {
@@ -22,25 +102,133 @@ func toSakuraID(id string) (int64, bool) {
 	return i, true
 }
 
-func hasTags(target interface{}, tags []string) bool {
-	type tagHandler interface {
-		HasTag(target string) bool
+// outputTargets shows target servers with matched conditions
+func outputTargets(targets []*resolver.Target) {
+	table := tablewriter.NewWriter(command.GlobalOption.Out)
+	table.SetHeader(append(serverHeader, "Matched"))
+	table.SetAutoFormatHeaders(false)
+	for _, t := range targets {
+		table.Append(append(serverRecord(t.Server), t.Reason()))
 	}
+	table.Render()
+}
 
-	tagHolder, ok := target.(tagHandler)
-	if !ok {
-		return false
+func outputServers(servers []*sacloud.Server) {
+	table := tablewriter.NewWriter(command.GlobalOption.Out)
+	table.SetHeader(serverHeader)
+	table.SetAutoFormatHeaders(false)
+	for _, s := range servers {
+		table.Append(serverRecord(s))
 	}
+	table.Render()
+}
+
+var serverHeader = []string{"ID", "Name", "Plan", "Core", "Memory", "State", "Disks", "Tags"}
 
-	// 完全一致 + AND条件
-	res := true
-	for _, p := range tags {
-		if !tagHolder.HasTag(p) {
-			res = false
-			break
+func serverRecord(s *sacloud.Server) []string {
+	var disks []string
+	for _, d := range s.Disks {
+		disks = append(disks, fmt.Sprintf("%dGB", d.GetSizeGB()))
+	}
+	plan := ""
+	if s.ServerPlan != nil {
+		plan = fmt.Sprintf("g%d", s.ServerPlan.Generation/100)
+	}
+	return []string{
+		s.GetStrID(),
+		s.Name,
+		plan,
+		fmt.Sprintf("%d", s.GetCPU()),
+		fmt.Sprintf("%dGB", s.GetMemoryGB()),
+		s.GetInstanceStatus(),
+		strings.Join(disks, ","),
+		strings.Join(s.Tags, ","),
+	}
+}
+
+// readCredentials reads API keys from files, commands or stdin specified by options
+func readCredentials(c FlagHandler) error {
+	sources := []struct {
+		name  string
+		dest  *string
+		file  string
+		cmd   string
+		stdin bool
+	}{
+		{name: "token", dest: &command.GlobalOption.AccessToken, file: c.String("token-file"), cmd: c.String("token-command")},
+		{name: "secret", dest: &command.GlobalOption.AccessTokenSecret, file: c.String("secret-file"), cmd: c.String("secret-command"), stdin: c.Bool("secret-stdin")},
+	}
+	for _, s := range sources {
+		var options []string
+		if s.file != "" {
+			options = append(options, fmt.Sprintf("--%s-file", s.name))
+		}
+		if s.cmd != "" {
+			options = append(options, fmt.Sprintf("--%s-command", s.name))
 		}
+		if s.stdin {
+			options = append(options, fmt.Sprintf("--%s-stdin", s.name))
+		}
+		if len(options) > 1 {
+			return fmt.Errorf("Reading %s is failed: %s can't be used together", s.name, strings.Join(options, "/"))
+		}
+
+		var value string
+		var err error
+		switch {
+		case s.file != "":
+			value, err = command.ReadSecretFile(s.file)
+		case s.cmd != "":
+			value, err = command.ReadSecretCommand(s.cmd)
+		case s.stdin:
+			value, err = command.ReadSecret(command.GlobalOption.In)
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Reading %s is failed: %s", s.name, err)
+		}
+		*s.dest = value
 	}
-	return res
+	return nil
+}
+
+// inputAPIKeys reads API keys interactively when API keys are empty
+//
+// The secret is read with masked echo.
+func inputAPIKeys() {
+	if isTerminal() {
+		c := color.New(color.BgMagenta)
+		if command.GlobalOption.AccessToken == "" {
+			// read input
+			var input string
+			c.Fprintln(command.GlobalOption.Out, "\nYour API AccessToken is not set")
+			fmt.Fprintf(command.GlobalOption.Out, "\t%s: ", "Enter your token")
+			fmt.Fscanln(command.GlobalOption.In, &input)
+			command.GlobalOption.AccessToken = input
+		}
+		if command.GlobalOption.AccessTokenSecret == "" {
+			c.Fprintln(command.GlobalOption.Out, "\nYour API AccessTokenSecret is not set")
+			fmt.Fprintf(command.GlobalOption.Out, "\t%s: ", "Enter your secret")
+			command.GlobalOption.AccessTokenSecret = readPassword()
+		}
+	}
+}
+
+// readPassword reads a line from the terminal with masked echo
+func readPassword() string {
+	t, err := tty.Open()
+	if err != nil {
+		// not to echo the secret, treat as empty and leave it to the validation
+		fmt.Fprintln(command.GlobalOption.Out, "")
+		return ""
+	}
+	defer t.Close()
+	input, err := t.ReadPassword()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(input)
 }
 
 func isTerminal() bool {
@@ -49,3 +237,16 @@ func isTerminal() bool {
 	}
 	return is(os.Stdin.Fd()) && is(os.Stdout.Fd())
 }
+
+// parseIDArgs parses arguments as server IDs
+func parseIDArgs(args []string) ([]int64, error) {
+	var ids []int64
+	for _, arg := range args {
+		id, ok := toSakuraID(arg)
+		if !ok {
+			return nil, fmt.Errorf("Invalid server ID: %q", arg)
+		}
+		ids = append(ids, id)
+	}
+	return command.UniqIDs(ids), nil
+}
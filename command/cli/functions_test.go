@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/urfave/cli.v2"
+)
+
+func TestApplyProfile(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "profile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	t.Setenv(command.ProfileDirEnv, dir)
+
+	writeProfile := func(name, content string) {
+		path := filepath.Join(dir, ".usacloud", name, "config.json")
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+	writeProfile("default", `{"AccessToken": "default-token"}`)
+	writeProfile("prod", `{
+		"AccessToken": "prod-token",
+		"CloudPlanMigrate": {
+			"workers": 5,
+			"cleanup-disk": true,
+			"maintenance-window": ["01:00-05:00", "22:00-23:00"],
+			"reference-command": "/usr/local/bin/update-cmdb"
+		}
+	}`)
+	writeProfile("invalid", `{"CloudPlanMigrate": {"workers": "many"}}`)
+
+	// not to exit by errors of the action
+	exiter, errWriter := cli.OsExiter, cli.ErrWriter
+	cli.OsExiter = func(int) {}
+	cli.ErrWriter = ioutil.Discard
+	defer func() { cli.OsExiter, cli.ErrWriter = exiter, errWriter }()
+
+	type values struct {
+		Token       string
+		Workers     int
+		CleanupDisk bool
+		Windows     []string
+	}
+
+	run := func(args ...string) (*values, error) {
+		flags := []cli.Flag{
+			&cli.StringFlag{Name: "profile"},
+			&cli.StringFlag{Name: "token", EnvVars: []string{"TEST_ACCESS_TOKEN"}},
+			&cli.IntFlag{Name: "workers", Value: 10},
+			&cli.BoolFlag{Name: "cleanup-disk"},
+			&cli.StringSliceFlag{Name: "maintenance-window"},
+		}
+		var result *values
+		app := &cli.App{
+			Flags: flags,
+			Action: func(c *cli.Context) error {
+				if err := applyProfile(c, flags); err != nil {
+					return err
+				}
+				result = &values{
+					Token:       c.String("token"),
+					Workers:     c.Int("workers"),
+					CleanupDisk: c.Bool("cleanup-disk"),
+					Windows:     c.StringSlice("maintenance-window"),
+				}
+				return nil
+			},
+		}
+		err := app.Run(append([]string{"test"}, args...))
+		return result, err
+	}
+
+	expects := []struct {
+		name   string
+		args   []string
+		env    string // value of TEST_ACCESS_TOKEN
+		expect *values
+		err    string
+	}{
+		{
+			name:   "default profile",
+			expect: &values{Token: "default-token", Workers: 10, Windows: []string{}},
+		},
+		{
+			name: "profile options",
+			args: []string{"--profile", "prod"},
+			expect: &values{
+				Token:       "prod-token",
+				Workers:     5,
+				CleanupDisk: true,
+				Windows:     []string{"01:00-05:00", "22:00-23:00"},
+			},
+		},
+		{
+			name: "explicit flags override the profile",
+			args: []string{"--profile", "prod", "--workers", "2", "--maintenance-window", "03:00-04:00", "--token", "arg-token"},
+			expect: &values{
+				Token:       "arg-token",
+				Workers:     2,
+				CleanupDisk: true,
+				Windows:     []string{"03:00-04:00"},
+			},
+		},
+		{
+			name:   "environment variables override the profile",
+			args:   []string{"--profile", "prod"},
+			env:    "env-token",
+			expect: &values{Token: "env-token", Workers: 5, CleanupDisk: true, Windows: []string{"01:00-05:00", "22:00-23:00"}},
+		},
+		{
+			name: "profile not found",
+			args: []string{"--profile", "staging"},
+			err:  `Loading profile is failed: profile "staging" is not found`,
+		},
+		{
+			name: "invalid value",
+			args: []string{"--profile", "invalid"},
+			err:  `Loading profile is failed: invalid value of "workers": `,
+		},
+	}
+
+	for _, expect := range expects {
+		t.Run(expect.name, func(t *testing.T) {
+			if expect.env != "" {
+				t.Setenv("TEST_ACCESS_TOKEN", expect.env)
+			}
+			result, err := run(expect.args...)
+			if expect.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), expect.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, expect.expect, result)
+		})
+	}
+}
+
+func TestApplyProfile_MigrateOptions(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "profile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	t.Setenv(command.ProfileDirEnv, dir)
+
+	path := filepath.Join(dir, ".usacloud", "prod", "config.json")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{
+		"CloudPlanMigrate": {
+			"disk-plan": "ssd",
+			"before-shutdown-command": "/usr/local/bin/stop-app",
+			"after-migrate-command": "/usr/local/bin/update-cmdb",
+			"after-boot-command": "/usr/local/bin/check-app",
+			"webhook": ["https://example.com/hook"]
+		}
+	}`), 0600))
+
+	// hooks and plan policies of the profile are set to the params of apply/resume
+	migrateParam := params.NewMigrateMigrateParam()
+	flags := migrateFlags()
+	app := &cli.App{
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			if err := applyProfile(c, flags); err != nil {
+				return err
+			}
+			setMigrateParam(c, migrateParam)
+			return nil
+		},
+	}
+	assert.NoError(t, app.Run([]string{"test", "--profile", "prod", "--after-boot-command", "/usr/local/bin/smoke-test"}))
+
+	assert.Equal(t, "ssd", migrateParam.DiskPlan)
+	assert.Equal(t, "/usr/local/bin/stop-app", migrateParam.BeforeShutdown)
+	assert.Equal(t, "/usr/local/bin/update-cmdb", migrateParam.AfterMigrate)
+	assert.Equal(t, "/usr/local/bin/smoke-test", migrateParam.AfterBoot)
+	assert.Equal(t, []string{"https://example.com/hook"}, migrateParam.Webhooks)
+	assert.Empty(t, migrateParam.Validate())
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/iaas"
+	"github.com/sacloud/cloud-plan-migrate/resolver"
+	"github.com/sacloud/libsacloud/sacloud"
+	"gopkg.in/urfave/cli.v2"
+)
+
+// newResolver returns Resolver asking which servers to be targets when names are ambiguous
+//
+// Without the terminal or with assumeyes, ambiguous names are errors.
+func newResolver(client iaas.Client, assumeyes bool) *resolver.Resolver {
+	r := resolver.New(client)
+	if !assumeyes && isTerminal() {
+		r.Choose = chooseServers
+	}
+	return r
+}
+
+// resolveTargets resolves target servers of the query guarded by the deny-list
+//
+// Excluded servers are reported to stderr.
+func resolveTargets(r *resolver.Resolver, q *resolver.Query, denyListPath string) (*resolver.Result, error) {
+	denyList, err := command.LoadDenyList(denyListPath)
+	if err != nil {
+		return nil, fmt.Errorf("Reading deny-list is failed: %s", err)
+	}
+	q.DenyList = denyList
+
+	result, err := r.Resolve(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Excluded) > 0 {
+		var list []string
+		for _, e := range result.Excluded {
+			list = append(list, fmt.Sprintf("%d(%s)", e.Server.ID, e.Server.Name))
+		}
+		fmt.Fprintf(command.GlobalOption.Err, "Excluded servers: %s\n", strings.Join(list, ", "))
+	}
+	return result, nil
+}
+
+// chooseServers asks which of servers having the same name to be targets
+func chooseServers(name string, servers []*sacloud.Server) ([]*sacloud.Server, error) {
+	out := command.GlobalOption.Out
+	fmt.Fprintf(out, "\nMultiple servers have the same name %q\n", name)
+	outputServers(servers)
+	fmt.Fprintf(out, "Enter IDs of target servers(comma separated), 'all' or empty to cancel: ")
+
+	line, _ := bufio.NewReader(command.GlobalOption.In).ReadString('\n')
+	input := strings.TrimSpace(line)
+	switch input {
+	case "":
+		return nil, fmt.Errorf("Canceled: name %q is ambiguous", name)
+	case "all":
+		return servers, nil
+	}
+
+	var chosen []*sacloud.Server
+	for _, v := range strings.Split(input, ",") {
+		id, _ := toSakuraID(strings.TrimSpace(v))
+		var server *sacloud.Server
+		for _, s := range servers {
+			if s.ID == id {
+				server = s
+			}
+		}
+		if server == nil {
+			return nil, fmt.Errorf("Invalid server ID: %q is not one of servers named %q", v, name)
+		}
+		chosen = append(chosen, server)
+	}
+	return chosen, nil
+}
+
+// targetFlags returns flags to specify target servers
+func targetFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "exclude",
+			Usage: "Set servers to exclude from targets by ID, name(glob) or tag(tag=<value>)",
+		},
+		denyListFlag(),
+		&cli.StringFlag{
+			Name:  "inventory",
+			Usage: "Path of the inventory file(CSV or JSON) listing target servers and per-server options",
+		},
+		&cli.StringFlag{
+			Name:  "wave",
+			Usage: "Set target wave in the inventory file",
+		},
+		&cli.StringSliceFlag{
+			Name:  "selector",
+			Usage: "Set target filter by selector expression(e.g. 'tag1 || tag2', 'name=web-* && core>=2')",
+		},
+	}
+}
+
+func denyListFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "deny-list",
+		Usage:   "Path of the deny-list file listing protected servers",
+		EnvVars: []string{"CLOUD_PLAN_MIGRATE_DENY_LIST"},
+		Value:   command.DefaultDenyListPath(),
+	}
+}
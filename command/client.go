@@ -0,0 +1,20 @@
+package command
+
+import (
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/iaas"
+)
+
+// NewIaaSClient returns iaas.Client of the context, limiting the rate of API calls and logging slow calls by GlobalOption
+func NewIaaSClient(ctx Context, logger iaas.Logger) *iaas.InstrumentedClient {
+	threshold := time.Duration(GlobalOption.SlowAPICallSec) * time.Second
+	if threshold <= 0 {
+		threshold = -1 // disabled
+	}
+	return iaas.NewInstrumentedClient(iaas.NewClient(ctx.GetAPIClient()), &iaas.InstrumentOptions{
+		RequestsPerSecond: GlobalOption.APIRateLimit,
+		SlowCallThreshold: threshold,
+		Logger:            logger,
+	})
+}
@@ -0,0 +1,75 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ReadSecretFile reads the secret from the first line of the file
+//
+// The file must not be accessible by group or others(the permission is not checked on Windows).
+func ReadSecretFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if runtime.GOOS != "windows" {
+		info, err := f.Stat()
+		if err != nil {
+			return "", err
+		}
+		if perm := info.Mode().Perm(); perm&0077 != 0 {
+			return "", fmt.Errorf("permission of %q is too open(%04o): must not be accessible by group or others(e.g. chmod 600)", path, perm)
+		}
+	}
+	secret, err := ReadSecret(f)
+	if err != nil {
+		return "", fmt.Errorf("reading %q is failed: %s", path, err)
+	}
+	return secret, nil
+}
+
+// ReadSecretCommand runs the command with the shell and returns the first line of the output(e.g. a password manager CLI)
+//
+// Only stderr of the command is included in errors, stdout is never shown.
+func ReadSecretCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	stderr := new(bytes.Buffer)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("command %q is failed: %s: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	secret, err := ReadSecret(bytes.NewReader(out))
+	if err != nil {
+		return "", fmt.Errorf("command %q is failed: %s", command, err)
+	}
+	return secret, nil
+}
+
+// ReadSecret reads the secret from the first line of r
+func ReadSecret(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	secret := strings.TrimSpace(line)
+	if secret == "" {
+		return "", fmt.Errorf("secret is empty")
+	}
+	return secret, nil
+}
@@ -0,0 +1,125 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSecretFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission is not checked on Windows")
+	}
+
+	dir, err := ioutil.TempDir("", "secret")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	expects := []struct {
+		name    string
+		content string
+		perm    os.FileMode
+		expect  string
+		err     string
+	}{
+		{name: "0600", content: "secret\nignored\n", perm: 0600, expect: "secret"},
+		{name: "0400", content: "  secret  ", perm: 0400, expect: "secret"},
+		{name: "0644", content: "secret\n", perm: 0644, err: "is too open(0644)"},
+		{name: "0640", content: "secret\n", perm: 0640, err: "is too open(0640)"},
+		{name: "0604", content: "secret\n", perm: 0604, err: "is too open(0604)"},
+		{name: "empty", content: "\nsecret\n", perm: 0600, err: "secret is empty"},
+	}
+
+	for _, expect := range expects {
+		t.Run(expect.name, func(t *testing.T) {
+			path := filepath.Join(dir, expect.name)
+			assert.NoError(t, ioutil.WriteFile(path, []byte(expect.content), 0600))
+			assert.NoError(t, os.Chmod(path, expect.perm)) // not masked by umask
+
+			secret, err := ReadSecretFile(path)
+			if expect.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), expect.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, expect.expect, secret)
+		})
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := ReadSecretFile(filepath.Join(dir, "not-found"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestReadSecretCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands are run by sh")
+	}
+
+	expects := []struct {
+		name    string
+		command string
+		expect  string
+		err     string
+	}{
+		{name: "first line", command: "printf 'top-secret\\nignored\\n'", expect: "top-secret"},
+		{name: "no newline", command: "printf top-secret", expect: "top-secret"},
+		{name: "empty", command: "true", err: "secret is empty"},
+		{
+			name:    "failed",
+			command: "echo top-secret; echo permission denied >&2; exit 3",
+			err:     "exit status 3: permission denied",
+		},
+	}
+
+	for _, expect := range expects {
+		t.Run(expect.name, func(t *testing.T) {
+			secret, err := ReadSecretCommand(expect.command)
+			if expect.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), expect.err)
+				// stdout is never included, only the command itself and stderr
+				msg := strings.Replace(err.Error(), expect.command, "", 1)
+				assert.NotContains(t, msg, "top-secret")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, expect.expect, secret)
+		})
+	}
+}
+
+func TestReadSecret(t *testing.T) {
+	expects := []struct {
+		name   string
+		input  string
+		expect string
+		err    string
+	}{
+		{name: "first line", input: "secret\nsecond\n", expect: "secret"},
+		{name: "crlf", input: "secret\r\n", expect: "secret"},
+		{name: "no newline", input: "secret", expect: "secret"},
+		{name: "spaces", input: "  secret \t\n", expect: "secret"},
+		{name: "empty", input: "", err: "secret is empty"},
+		{name: "empty first line", input: "\nsecret\n", err: "secret is empty"},
+	}
+
+	for _, expect := range expects {
+		t.Run(expect.name, func(t *testing.T) {
+			secret, err := ReadSecret(strings.NewReader(expect.input))
+			if expect.err != "" {
+				assert.EqualError(t, err, expect.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, expect.expect, secret)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+// DefaultDenyListPath returns default path of the deny-list file(~/.cloud-plan-migrate/deny-list.json)
+func DefaultDenyListPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cloud-plan-migrate", "deny-list.json")
+}
+
+// LoadDenyList reads the deny-list file
+//
+// If the file of the default path does not exist, empty list is returned.
+// A missing file of other(explicitly specified) paths and unknown keys are errors, not to run unguarded by a typo.
+func LoadDenyList(path string) (*migrate.Protection, error) {
+	denyList := &migrate.Protection{}
+	if path == "" {
+		return denyList, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && path == DefaultDenyListPath() {
+			return denyList, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	// a misspelled key(e.g. "tag") must not silently disable the protection
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(denyList); err != nil {
+		return nil, err
+	}
+	return denyList, nil
+}
@@ -0,0 +1,57 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadDenyList(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "deny-list")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(dir, "deny-list.json")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(`{"tags":["db"]}`), 0600))
+
+		denyList, err := LoadDenyList(path)
+		assert.NoError(t, err)
+		assert.Equal(t, &migrate.Protection{Tags: []string{"db"}}, denyList)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		path := filepath.Join(dir, "unknown-key.json")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(`{"tag":["db"]}`), 0600))
+
+		_, err := LoadDenyList(path)
+		assert.EqualError(t, err, `json: unknown field "tag"`)
+	})
+
+	t.Run("empty path", func(t *testing.T) {
+		denyList, err := LoadDenyList("")
+		assert.NoError(t, err)
+		assert.Equal(t, &migrate.Protection{}, denyList)
+	})
+
+	t.Run("missing default path", func(t *testing.T) {
+		home := os.Getenv("HOME")
+		defer os.Setenv("HOME", home)
+		os.Setenv("HOME", dir)
+
+		denyList, err := LoadDenyList(DefaultDenyListPath())
+		assert.NoError(t, err)
+		assert.Equal(t, &migrate.Protection{}, denyList)
+	})
+
+	t.Run("missing explicit path", func(t *testing.T) {
+		_, err := LoadDenyList(filepath.Join(dir, "not-found.json"))
+		assert.Error(t, err)
+		assert.True(t, os.IsNotExist(err))
+	})
+}
@@ -0,0 +1,118 @@
+package funcs
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"github.com/sacloud/cloud-plan-migrate/iaas"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"github.com/sacloud/cloud-plan-migrate/selector"
+)
+
+func MigrateCheck(ctx command.Context, params *params.MigrateCheckParam) error {
+
+	client := command.NewIaaSClient(ctx, nil)
+
+	targetSelector, err := selector.ParseAll(params.Selector)
+	if err != nil {
+		return fmt.Errorf("Invalid selector: %s", err)
+	}
+
+	servers, err := client.Find(&iaas.FindParameter{Tags: selector.RequiredTags(targetSelector)})
+	if err != nil {
+		return fmt.Errorf("Check is failed: %s", err)
+	}
+	servers = selector.Filter(servers, targetSelector)
+
+	history, err := migrate.LoadThroughputHistory(params.History)
+	if err != nil {
+		return fmt.Errorf("Reading throughput history is failed: %s", err)
+	}
+
+	results := migrate.CheckEligibility(client, servers, history, nil)
+
+	out := command.GlobalOption.Out
+	switch params.OutputType {
+	case "json":
+		return outputEligibilityJSON(out, results)
+	case "csv":
+		return outputEligibilityCSV(out, results)
+	default:
+		outputEligibilityTable(out, results)
+		return nil
+	}
+}
+
+var eligibilityHeader = []string{
+	"ID", "Name", "Generation", "NewPlan", "Disks", "DiskSize(GB)", "DiskPlanSubstitutions", "State", "EstimatedCopy", "Blockers",
+}
+
+func eligibilityRecord(e *migrate.Eligibility) []string {
+	newPlan := "not found"
+	if e.NewPlanAvailable {
+		newPlan = "available"
+	}
+	return []string{
+		fmt.Sprintf("%d", e.ServerID),
+		e.ServerName,
+		e.Generation,
+		newPlan,
+		fmt.Sprintf("%d", e.DiskCount),
+		fmt.Sprintf("%d", e.TotalDiskSizeGB),
+		strings.Join(e.DiskPlanSubstitutions, "\n"),
+		e.State,
+		e.EstimatedCopyTime.String(),
+		strings.Join(e.Blockers, "\n"),
+	}
+}
+
+func outputEligibilityTable(out io.Writer, results []*migrate.Eligibility) {
+	table := tablewriter.NewWriter(out)
+	table.SetHeader(eligibilityHeader)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	eligible := 0
+	for _, e := range results {
+		table.Append(eligibilityRecord(e))
+		if e.Eligible() {
+			eligible++
+		}
+	}
+	table.Render()
+	fmt.Fprintf(out, "%d/%d servers are eligible for migration\n", eligible, len(results))
+}
+
+func outputEligibilityCSV(out io.Writer, results []*migrate.Eligibility) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(eligibilityHeader); err != nil {
+		return err
+	}
+	for _, e := range results {
+		record := eligibilityRecord(e)
+		for i := range record {
+			record[i] = strings.Replace(record[i], "\n", ";", -1)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func outputEligibilityJSON(out io.Writer, results []*migrate.Eligibility) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
@@ -0,0 +1,22 @@
+package funcs
+
+import (
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+// MigrateCleanup deletes the original disks of migrated servers of the run recorded in the journal
+func MigrateCleanup(ctx command.Context, params *params.MigrateCleanupParam) error {
+	return applyJournal(ctx, &journalAction{
+		path:      params.Journal,
+		runID:     params.RunID,
+		ids:       params.IDs,
+		assumeyes: params.Assumeyes,
+		verb:      "delete original disks",
+		eventType: migrate.EventDisksCleanedUp,
+		target:    command.IsCleanupTarget,
+		apply:     migrate.CleanupDisks,
+		done:      "original disks are deleted",
+	})
+}
@@ -0,0 +1,93 @@
+package funcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"github.com/sacloud/cloud-plan-migrate/iaas"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"github.com/sacloud/cloud-plan-migrate/selector"
+)
+
+func MigrateEstimate(ctx command.Context, params *params.MigrateEstimateParam) error {
+
+	client := command.NewIaaSClient(ctx, nil)
+
+	targetSelector, err := selector.ParseAll(params.Selector)
+	if err != nil {
+		return fmt.Errorf("Invalid selector: %s", err)
+	}
+
+	servers, err := client.Find(&iaas.FindParameter{Tags: selector.RequiredTags(targetSelector)})
+	if err != nil {
+		return fmt.Errorf("Estimate is failed: %s", err)
+	}
+	servers = selector.Filter(servers, targetSelector)
+
+	history, err := migrate.LoadThroughputHistory(params.History)
+	if err != nil {
+		return fmt.Errorf("Reading throughput history is failed: %s", err)
+	}
+
+	estimate := migrate.EstimateMigration(servers, history, params.Workers, params.CleanupDisk, params.PreClone, nil)
+
+	out := command.GlobalOption.Out
+	if params.OutputType == "json" {
+		data, err := json.MarshalIndent(estimate, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+	outputEstimateTable(out, estimate)
+	return nil
+}
+
+func outputEstimateTable(out io.Writer, estimate *migrate.Estimate) {
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"ID", "Name", "DiskSize(GB)", "Mode", "Copy", "Downtime", "Start"})
+	table.SetAutoFormatHeaders(false)
+	preClone := false
+	for _, s := range estimate.Servers {
+		mode := "offline"
+		if s.PreClone {
+			mode = "pre-clone"
+			preClone = true
+		}
+		table.Append([]string{
+			fmt.Sprintf("%d", s.ServerID),
+			s.ServerName,
+			fmt.Sprintf("%d", s.DiskSizeGB),
+			mode,
+			s.CopyTime.String(),
+			s.Downtime.String(),
+			fmt.Sprintf("+%s", s.Start),
+		})
+	}
+	table.Render()
+
+	var plans []string
+	for plan := range estimate.Throughputs {
+		plans = append(plans, plan)
+	}
+	sort.Strings(plans)
+
+	fmt.Fprintf(out, "Workers         : %d\n", estimate.Workers)
+	fmt.Fprintf(out, "Total time      : %s\n", estimate.TotalTime.Round(time.Second))
+	fmt.Fprintf(out, "Extra disk size : %dGB\n", estimate.ExtraDiskSizeGB)
+	for _, plan := range plans {
+		fmt.Fprintf(out, "Throughput(%s) : %.1fMB/s\n", plan, estimate.Throughputs[plan])
+	}
+	if preClone {
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "pre-clone: Downtime excludes Copy, but writes during Copy are NOT migrated")
+		fmt.Fprintln(out, "           unless the filesystem is frozen by the BeforeShutdown hook.")
+	}
+}
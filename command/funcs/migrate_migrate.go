@@ -3,9 +3,13 @@ package funcs
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/fatih/color"
@@ -13,44 +17,152 @@ import (
 	"github.com/sacloud/cloud-plan-migrate/command"
 	"github.com/sacloud/cloud-plan-migrate/command/params"
 	"github.com/sacloud/cloud-plan-migrate/iaas"
+	"github.com/sacloud/cloud-plan-migrate/logging"
+	"github.com/sacloud/cloud-plan-migrate/metrics"
 	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"github.com/sacloud/cloud-plan-migrate/notify"
 	"github.com/sacloud/libsacloud/sacloud"
 )
 
 func MigrateMigrate(ctx command.Context, params *params.MigrateMigrateParam) error {
 
-	rawClient := ctx.GetAPIClient()
-	client := iaas.NewClient(rawClient)
+	apiClient := command.NewIaaSClient(ctx, nil)
+	var client iaas.Client = apiClient
+
+	var collector *metrics.Collector
+	if params.MetricsAddr != "" {
+		collector = metrics.NewCollector()
+		client = iaas.NewObservedClient(client, collector.ObserveAPICall)
+	}
+
+	var recorder *iaas.RecordingClient
+	if params.Record != "" {
+		f, err := os.OpenFile(params.Record, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("Opening record file is failed: %s", err)
+		}
+		defer f.Close()
+		recorder = iaas.NewRecordingClient(client, f, secrets(params)...)
+		client = recorder
+	}
 
 	// validate server status
+	var errs []error
 	for _, serverID := range params.IDs {
 		server, err := client.ServerByID(serverID)
 		if err != nil {
 			return fmt.Errorf("Migrate is failed: %s", err)
 		}
-
-		if len(server.Disks) == 0 {
-			return fmt.Errorf("Server[%q] don't have any disks", serverID)
+		if opts := params.ServerOptions[serverID]; opts != nil && opts.Resume != nil && opts.Resume.DisksDisconnected {
+			// disks are disconnected by the interrupted run
+			continue
+		}
+		if err := validateServer(server); err != nil {
+			errs = append(errs, err)
 		}
+	}
+	if len(errs) > 0 {
+		return command.FlattenErrors(errs)
+	}
 
-		if server.GetServerPlan().Generation != sacloud.PlanG1 {
-			return fmt.Errorf("Server[%q] is already use plan-gen2", serverID)
+	var updaters []migrate.ReferenceUpdater
+	if params.UpdateRefs {
+		updaters = append(updaters, migrate.NewResourceReferenceUpdater(client))
+	}
+	if params.RefCommand != "" {
+		updaters = append(updaters, migrate.NewCommandReferenceUpdater(params.RefCommand))
+	}
+
+	if params.ListRefs {
+		migration, err := migrate.NewMigration(client, params.IDs, &migrate.Options{ReferenceUpdaters: updaters, Protection: params.Protection})
+		if err != nil {
+			return fmt.Errorf("Listing references is failed: %s", err)
 		}
+		refs, err := migration.References()
+		if err != nil {
+			return fmt.Errorf("Listing references is failed: %s", err)
+		}
+		outputReferences(params.IDs, refs)
+		return nil
 	}
 
 	// prepare params
-	logfile, err := openLogFile()
+	timestamp := time.Now().Format("20060102-150405")
+	logger, err := newLogger(params, timestamp)
 	if err != nil {
 		return fmt.Errorf("Migrate is failed: %s", err)
 	}
-	defer logfile.Close()
+	defer logger.Close()
+	if recorder != nil {
+		defer func() {
+			if err := recorder.Err(); err != nil {
+				logger.Warn("Recording API calls is failed", "file", params.Record, "error", err)
+			}
+		}()
+	}
+
+	history, err := migrate.LoadThroughputHistory(params.History)
+	if err != nil {
+		return fmt.Errorf("Reading throughput history is failed: %s", err)
+	}
+
+	var windows migrate.Schedule
+	for _, spec := range params.Windows {
+		w, err := migrate.ParseWindow(spec)
+		if err != nil {
+			return fmt.Errorf("Migrate is failed: %s", err)
+		}
+		windows = append(windows, w)
+	}
+
+	apiClient.SetLogger(logger)
+	defer logAPIStats(logger, apiClient)
+
+	if collector != nil {
+		server, addr, err := metrics.Serve(params.MetricsAddr, collector)
+		if err != nil {
+			return fmt.Errorf("Starting metrics endpoint is failed: %s", err)
+		}
+		defer server.Close()
+		logger.Info("Metrics endpoint started", "url", fmt.Sprintf("http://%s/metrics", addr))
+	}
+
+	notifier, err := newNotifier(params, logger)
+	if err != nil {
+		return fmt.Errorf("Migrate is failed: %s", err)
+	}
+	var listeners []migrate.Listener
+	if notifier != nil {
+		listeners = append(listeners, notifier)
+		defer notifier.Close()
+	}
+	if params.Journal != "" {
+		// the timestamp is used as the ID of the run
+		journal := migrate.NewJournal(params.Journal, timestamp)
+		listeners = append(listeners, journal)
+		defer func() {
+			if err := journal.Err(); err != nil {
+				logger.Warn("Writing journal is failed", "file", params.Journal, "error", err)
+			}
+		}()
+	}
 
-	logger := log.New(logfile, "", log.LstdFlags)
 	options := &migrate.Options{
 		DisableBoot:    params.DisableReboot,
 		DeleteDisks:    params.CleanupDisk,
-		MaxWorkerCount: 10, // TODO 設定変更可能に
+		PreClone:       params.PreClone,
+		StrictVerify:   params.StrictVerify,
+		MaxWorkerCount: params.Workers,
 		Logger:         logger,
+		DiskPlanID:     diskPlanID(params.DiskPlan),
+		Hooks:          commandHooks(params),
+
+		ReferenceUpdaters: updaters,
+		Protection:        params.Protection,
+		Servers:           params.ServerOptions,
+		History:           history,
+		Windows:           windows,
+		Listeners:         listeners,
 	}
 
 	// prepare migration
@@ -59,61 +171,251 @@ func MigrateMigrate(ctx command.Context, params *params.MigrateMigrateParam) err
 		return fmt.Errorf("Migrate is failed: %s", err)
 	}
 
+	if collector != nil {
+		collector.SetMigration(migration)
+	}
+
 	// exec migration
 	var doneC = make(chan bool)
-	tickC := time.NewTicker(time.Second).C
 
 	go func() {
 		migration.Apply()
 		doneC <- true
 	}()
 
+	stopSignals := handleSignals(migration, logger)
+	defer stopSignals()
+
 	// wait and printing
+	logProgress := newProgressLogger(logger)
+	if params.TUI {
+		if err := runMigrationTUI(migration, doneC, logProgress); err != nil {
+			return fmt.Errorf("Migrate is failed: %s", err)
+		}
+	} else {
+		waitMigration(migration, doneC, params.Compact, logProgress)
+		outputMigrationStatus(migration, params.Compact)
+	}
+
+	if notifier != nil {
+		notifier.Close()
+	}
+
+	fmt.Fprintln(command.GlobalOption.Out, "")
+
+	c := color.New(color.FgHiGreen)
+	c.Fprintln(command.GlobalOption.Out, "=== Migration finished ===")
+
+	fmt.Fprintln(command.GlobalOption.Out, "")
+	outputMigrationErrors(migration.HasErrors())
+
+	report := migration.Report()
+	redactReport(report, secrets(params))
+	outputInterfaceDiffs(report)
+	reportDir := params.LogDir
+	if reportDir == "" && params.LogFile != "" {
+		reportDir = filepath.Dir(params.LogFile)
+	}
+	if err := writeReportFile(reportDir, timestamp, report); err != nil {
+		return fmt.Errorf("Writing report is failed: %s", err)
+	}
+	if params.History != "" {
+		if err := history.Save(params.History); err != nil {
+			return fmt.Errorf("Writing throughput history is failed: %s", err)
+		}
+	}
+	if len(params.MailTo) > 0 {
+		mailer := &notify.Mailer{
+			Addr:     params.SMTPAddr,
+			Username: params.SMTPUser,
+			Password: params.SMTPPassword,
+			From:     params.MailFrom,
+			To:       params.MailTo,
+			Subject:  params.MailSubject,
+		}
+		if err := mailer.SendReport(report); err != nil {
+			return fmt.Errorf("Sending summary mail is failed: %s", err)
+		}
+	}
+	return nil
+}
+
+func waitMigration(migration *migrate.Migration, doneC <-chan bool, compact bool, logProgress func(*migrate.Progress)) {
+	tickC := time.NewTicker(time.Second).C
 	for {
 		select {
 		case <-tickC:
-			outputMigrationStatus(migration.Working())
+			outputMigrationStatus(migration, compact)
 			outputMigrationErrors(migration.HasErrors())
+			logProgress(migration.Progress())
 		case <-doneC:
-			outputMigrationStatus(migration.Working())
+			return
+		}
+	}
+}
+
+// newProgressLogger returns a func that logs the overall progress and ETA at each 10 percent
+func newProgressLogger(logger *logging.Logger) func(*migrate.Progress) {
+	logged := 0
+	return func(progress *migrate.Progress) {
+		if p := int(progress.Percentage()) / 10 * 10; p > logged {
+			logged = p
+			logger.Info("Overall progress", "migrated_mb", progress.MigratedMB, "size_mb", progress.SizeMB, "percentage", p,
+				"eta_seconds", progress.ETASeconds())
+		}
+	}
+}
 
-			fmt.Fprintln(command.GlobalOption.Out, "")
+// logAPIStats logs statistics of API calls of each method
+func logAPIStats(logger *logging.Logger, client *iaas.InstrumentedClient) {
+	for _, s := range client.Stats() {
+		logger.Info("API calls", "method", s.Method, "calls", s.Calls, "errors", s.Errors,
+			"average", s.Average().Seconds(), "max", s.Max.Seconds(), "waiting", s.Waiting.Seconds())
+	}
+}
 
-			c := color.New(color.FgHiGreen)
-			c.Fprintln(command.GlobalOption.Out, "=== Migration finished ===")
+// newNotifier returns the notifier for webhooks, or nil if no webhook is specified
+func newNotifier(params *params.MigrateMigrateParam, logger *logging.Logger) (*notify.Notifier, error) {
+	if len(params.Webhooks) == 0 && len(params.SlackWebhooks) == 0 {
+		return nil, nil
+	}
 
-			fmt.Fprintln(command.GlobalOption.Out, "")
-			outputMigrationErrors(migration.HasErrors())
-			return nil
+	var tmpl *template.Template
+	if params.WebhookTmpl != "" {
+		data, err := ioutil.ReadFile(params.WebhookTmpl)
+		if err != nil {
+			return nil, fmt.Errorf("reading webhook template is failed: %s", err)
 		}
+		tmpl, err = notify.ParseTemplate(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("parsing webhook template is failed: %s", err)
+		}
+	}
+
+	var endpoints []*notify.Endpoint
+	for _, u := range params.Webhooks {
+		endpoints = append(endpoints, notify.NewWebhookEndpoint(u, tmpl))
+	}
+	for _, u := range params.SlackWebhooks {
+		endpoints = append(endpoints, notify.NewSlackEndpoint(u))
+	}
+	notifier := notify.NewNotifier(endpoints, logger)
+	notifier.Secrets = secrets(params)
+	return notifier, nil
+}
+
+func validateServer(server *sacloud.Server) error {
+	if len(server.Disks) == 0 {
+		return fmt.Errorf("Server[%d] don't have any disks", server.ID)
+	}
+
+	if server.GetServerPlan().Generation != sacloud.PlanG1 {
+		return fmt.Errorf("Server[%d] is already use plan-gen2", server.ID)
+	}
+	return nil
+}
+
+// newLogger returns the logger writing to the log file and optional stderr/syslog
+//
+// The log file is --log-file, or migrate-<timestamp>.log in --log-dir if not specified.
+func newLogger(params *params.MigrateMigrateParam, timestamp string) (*logging.Logger, error) {
+	level, err := logging.ParseLevel(params.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+	format, err := logging.ParseFormat(params.LogFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	name := params.LogFile
+	if name == "" {
+		name = filepath.Join(params.LogDir, fmt.Sprintf("migrate-%s.log", timestamp))
+	}
+	file, err := logging.NewFileSink(name)
+	if err != nil {
+		return nil, err
+	}
+	sinks := []logging.Sink{file}
+
+	if params.LogStderr {
+		sinks = append(sinks, &logging.WriterSink{Writer: command.GlobalOption.Err})
+	}
+	if params.LogSyslog {
+		s, err := logging.NewSyslogSink("", "", "cloud-plan-migrate")
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	logger := logging.New(level, format, sinks...)
+	logger.Secrets = secrets(params)
+	return logger, nil
+}
+
+// secrets returns API keys and passwords which must not be written to logs, reports and recordings
+func secrets(params *params.MigrateMigrateParam) []string {
+	secrets := command.GlobalOption.Secrets()
+	if params.SMTPPassword != "" {
+		secrets = append(secrets, params.SMTPPassword)
 	}
+	return secrets
 }
 
-func openLogFile() (*os.File, error) {
-	name := fmt.Sprintf("migrate-%s.log", time.Now().Format("20060102-150405"))
-	return os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0666)
+// redactReport replaces secrets in errors of the report, which may contain messages of the API or commands
+func redactReport(report *migrate.Report, secrets []string) {
+	for _, s := range report.Servers {
+		for _, secret := range secrets {
+			s.Error = strings.Replace(s.Error, secret, logging.Redacted, -1)
+		}
+	}
+}
+
+// writeReportFile writes the report readable only by the owner like the log file(it contains IP addresses)
+func writeReportFile(dir, timestamp string, report *migrate.Report) error {
+	name := filepath.Join(dir, fmt.Sprintf("migrate-%s-report.json", timestamp))
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, data, 0600)
 }
 
 var out = bufio.NewWriter(command.GlobalOption.Out)
 var screen = new(bytes.Buffer)
 
-func outputMigrationStatus(status []*migrate.ServerStatus) {
+// outputMigrationStatus prints status of all servers
+//
+// In compact mode, queued and done servers are collapsed into totals.
+func outputMigrationStatus(migration *migrate.Migration, compact bool) {
 
 	out.WriteString("\033[1;1H") // position(line3-1)
 	out.WriteString("\033[0J")   // clear after cursor
 	screen.Reset()
 
+	var status []*migrate.ServerStatus
+	for _, s := range migration.Status() {
+		state := s.State()
+		if compact && (state == migrate.ServerStateQueued || state == migrate.ServerStateDone) {
+			continue
+		}
+		status = append(status, s)
+	}
+
 	if len(status) > 0 {
 		table := tablewriter.NewWriter(screen)
-		table.SetHeader([]string{"Server", "Shutdown", "Disk", "PlanChange", "Boot", "Cleanup"})
+		table.SetHeader([]string{"Server", "State", "Shutdown", "Disk", "PlanChange", "Verify", "Boot", "Cleanup"})
 		//table.SetAutoMergeCells(true)
 		//table.SetRowLine(true)
 		table.SetAutoFormatHeaders(false)
-		table.SetColMinWidth(1, 12)
-		table.SetColMinWidth(2, 24)
-		table.SetColMinWidth(3, 10)
-		table.SetColMinWidth(4, 12)
+		table.SetColMinWidth(1, 8)
+		table.SetColMinWidth(2, 12)
+		table.SetColMinWidth(3, 24)
+		table.SetColMinWidth(4, 10)
 		table.SetColMinWidth(5, 10)
+		table.SetColMinWidth(6, 12)
+		table.SetColMinWidth(7, 10)
 
 		for _, s := range status {
 			data := buildOutputDataFromStatus(s)
@@ -124,19 +426,55 @@ func outputMigrationStatus(status []*migrate.ServerStatus) {
 		fmt.Fprintln(screen, "")
 	}
 
+	fmt.Fprintf(screen, "%s%s\n", totalsSummary(migration.Totals()), controlState(migration))
+	progress := migration.Progress()
+	fmt.Fprintf(screen, "Overall %s %s\n\n", progressBar(progress.Percentage(), 40), progress)
+
 	out.WriteString(screen.String())
 	out.Flush()
 }
 
+func serverState(s *migrate.ServerStatus) string {
+	if w := s.WindowStatus(); w != "" {
+		return fmt.Sprintf("%s\n%s", s.State(), w)
+	}
+	return string(s.State())
+}
+
+func totalsSummary(totals *migrate.Totals) string {
+	return fmt.Sprintf("Total: %d  Queued: %d  Waiting: %d  Running: %d  Done: %d  Failed: %d  Skipped: %d  RolledBack: %d",
+		totals.Total(), totals.Queued, totals.Waiting, totals.Running, totals.Done, totals.Failed, totals.Skipped, totals.RolledBack)
+}
+
+func controlState(migration *migrate.Migration) string {
+	switch {
+	case migration.Draining():
+		return "  [DRAINING]"
+	case migration.Paused():
+		return "  [PAUSED]"
+	}
+	return ""
+}
+
+func progressBar(percentage float64, width int) string {
+	filled := int(percentage / 100 * float64(width))
+	if filled > width {
+		filled = width
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
+
 func buildOutputDataFromStatus(s *migrate.ServerStatus) [][]string {
 	var data [][]string
 
 	for _, d := range s.Disks {
 		data = append(data, []string{
 			s.ServerID(),
+			serverState(s),
 			s.ShutdownStatus(),
 			d.CloneStatus(),
 			s.MigrationStatus(),
+			s.VerifyStatus(),
 			s.BootStatus(),
 			d.DeleteStatus(),
 		})
@@ -156,9 +494,73 @@ func outputMigrationErrors(errs []*migrate.ServerStatus) {
 	cBody := color.New(color.FgRed)
 	cTitle.Fprintln(screen, "*** Errors ***")
 	for _, e := range errs {
-		cBody.Fprintf(screen, "  Server[%s:%s] Error: %s\n", e.ServerID(), e.ServerName(), e.Err)
+		cBody.Fprintf(screen, "  Server[%s:%s] Error: %s\n", e.ServerID(), e.ServerName(), e.GetErr())
+	}
+
+	out.WriteString(screen.String())
+	out.Flush()
+}
+
+func outputInterfaceDiffs(report *migrate.Report) {
+	screen.Reset()
+
+	cTitle := color.New(color.BgYellow)
+	cBody := color.New(color.FgYellow)
+	titled := false
+	for _, s := range report.Servers {
+		if len(s.InterfaceDiffs) == 0 {
+			continue
+		}
+		if !titled {
+			cTitle.Fprintln(screen, "*** Network interface differences ***")
+			titled = true
+		}
+		for _, diff := range s.InterfaceDiffs {
+			cBody.Fprintf(screen, "  Server[%d=>%d:%s] %s\n", s.ServerID, s.MigratedServerID, s.ServerName, diff)
+		}
 	}
 
 	out.WriteString(screen.String())
 	out.Flush()
 }
+
+func outputReferences(ids []int64, refs map[int64][]*migrate.Reference) {
+	table := tablewriter.NewWriter(command.GlobalOption.Out)
+	table.SetHeader([]string{"Server", "Updater", "Reference"})
+	table.SetAutoFormatHeaders(false)
+	for _, id := range ids {
+		for _, ref := range refs[id] {
+			table.Append([]string{fmt.Sprintf("%d", id), ref.Updater, ref.String()})
+		}
+	}
+	table.Render()
+}
+
+// diskPlanID returns the plan ID of --disk-plan, or 0(the plan of original disks) if empty
+func diskPlanID(plan string) int64 {
+	switch plan {
+	case "ssd":
+		return int64(sacloud.DiskPlanSSDID)
+	case "hdd":
+		return int64(sacloud.DiskPlanHDDID)
+	}
+	return 0
+}
+
+// commandHooks returns hooks executing commands of --xxx-command, or nil if no command is set
+func commandHooks(params *params.MigrateMigrateParam) *migrate.Hooks {
+	if params.BeforeShutdown == "" && params.AfterMigrate == "" && params.AfterBoot == "" {
+		return nil
+	}
+	hooks := &migrate.Hooks{}
+	if params.BeforeShutdown != "" {
+		hooks.BeforeShutdown = migrate.NewCommandHook(params.BeforeShutdown)
+	}
+	if params.AfterMigrate != "" {
+		hooks.AfterMigrate = migrate.NewCommandHook(params.AfterMigrate)
+	}
+	if params.AfterBoot != "" {
+		hooks.AfterBoot = migrate.NewCommandHook(params.AfterBoot)
+	}
+	return hooks
+}
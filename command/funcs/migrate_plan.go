@@ -0,0 +1,47 @@
+package funcs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"github.com/sacloud/cloud-plan-migrate/iaas"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+// MigratePlan shows eligibility and the estimate of target servers without migration
+func MigratePlan(ctx command.Context, params *params.MigratePlanParam) error {
+
+	client := command.NewIaaSClient(ctx, nil)
+
+	servers, err := client.Find(&iaas.FindParameter{Ids: params.IDs})
+	if err != nil {
+		return fmt.Errorf("Plan is failed: %s", err)
+	}
+
+	history, err := migrate.LoadThroughputHistory(params.History)
+	if err != nil {
+		return fmt.Errorf("Reading throughput history is failed: %s", err)
+	}
+
+	results := migrate.CheckEligibility(client, servers, history, params.ServerOptions)
+	estimate := migrate.EstimateMigration(servers, history, params.Workers, params.CleanupDisk, params.PreClone, params.ServerOptions)
+
+	out := command.GlobalOption.Out
+	if params.OutputType == "json" {
+		data, err := json.MarshalIndent(&struct {
+			Servers  []*migrate.Eligibility `json:"servers"`
+			Estimate *migrate.Estimate      `json:"estimate"`
+		}{results, estimate}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+	outputEligibilityTable(out, results)
+	fmt.Fprintln(out, "")
+	outputEstimateTable(out, estimate)
+	return nil
+}
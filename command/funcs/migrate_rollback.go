@@ -0,0 +1,96 @@
+package funcs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"github.com/sacloud/cloud-plan-migrate/iaas"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+// MigrateRollback restores failed servers of the run recorded in the journal
+func MigrateRollback(ctx command.Context, params *params.MigrateRollbackParam) error {
+	return applyJournal(ctx, &journalAction{
+		path:      params.Journal,
+		runID:     params.RunID,
+		ids:       params.IDs,
+		assumeyes: params.Assumeyes,
+		verb:      "roll back",
+		eventType: migrate.EventServerRolledBack,
+		target:    command.IsRollbackTarget,
+		apply:     migrate.RollbackServer,
+		done:      "is rolled back",
+	})
+}
+
+// journalAction is an action applied to servers recorded in the journal, such as rollback and cleanup
+type journalAction struct {
+	path      string
+	runID     string
+	ids       []int64 // all servers matching target if empty
+	assumeyes bool
+	verb      string
+	eventType migrate.EventType
+	target    func(r *migrate.ServerRecord) bool
+	apply     func(client iaas.Client, r *migrate.ServerRecord) error
+	done      string
+}
+
+// applyJournal applies the action to servers of the run after the confirmation
+//
+// The record of each server is appended to the journal even if the action is failed,
+// so that the progress is kept and the action can be retried.
+func applyJournal(ctx command.Context, action *journalAction) error {
+	run, err := command.FindJournalRun(action.path, action.runID)
+	if err != nil {
+		return fmt.Errorf("Reading journal is failed: %s", err)
+	}
+
+	records, err := command.SelectJournalServers(run, action.ids, action.target)
+	if err != nil {
+		return err
+	}
+
+	out := command.GlobalOption.Out
+	if len(records) == 0 {
+		fmt.Fprintf(out, "No servers to %s in run %s\n", action.verb, run.RunID)
+		return nil
+	}
+
+	// confirm
+	if !action.assumeyes {
+		var ids []int64
+		for _, r := range records {
+			ids = append(ids, r.ServerID)
+		}
+		if !command.ConfirmContinue(action.verb, ids...) {
+			return nil
+		}
+	}
+
+	client := command.NewIaaSClient(ctx, nil)
+	var errs []error
+	for _, r := range records {
+		applyErr := action.apply(client, r)
+		entry := &migrate.JournalEntry{
+			RunID:   run.RunID,
+			Type:    action.eventType,
+			Time:    time.Now(),
+			Servers: []*migrate.ServerRecord{r},
+		}
+		if err := migrate.AppendJournal(action.path, entry); err != nil {
+			errs = append(errs, fmt.Errorf("Writing journal is failed: %s", err))
+		}
+		if applyErr != nil {
+			errs = append(errs, fmt.Errorf("Server[%d](%s): %s", r.ServerID, r.ServerName, applyErr))
+			continue
+		}
+		fmt.Fprintf(out, "Server[%d](%s) %s\n", r.ServerID, r.ServerName, action.done)
+	}
+	if len(errs) > 0 {
+		return command.FlattenErrors(errs)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package funcs
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/logging"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+var drainSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// handleSignals controls the migration by signals
+//
+// SIGINT/SIGTERM drains the migration, and the second one terminates the process.
+// SIGUSR1 pauses or resumes the migration(except on Windows).
+// The default handler terminating the process is suspended until stop is called.
+func handleSignals(migration *migrate.Migration, logger *logging.Logger) (stop func()) {
+	resumeInterrupt := command.SuspendInterrupt()
+
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, drainSignals...)
+	if len(pauseSignals) > 0 {
+		signal.Notify(sigC, pauseSignals...)
+	}
+
+	doneC := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-sigC:
+				if isPauseSignal(sig) {
+					if migration.Paused() {
+						migration.Resume()
+						logger.Info("Resumed by signal", "signal", sig)
+					} else {
+						migration.Pause()
+						logger.Info("Paused by signal", "signal", sig)
+					}
+					continue
+				}
+				migration.Drain()
+				logger.Warn("Draining by signal, waiting for servers in progress", "signal", sig)
+				// the next signal terminates the process
+				signal.Reset(drainSignals...)
+			case <-doneC:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigC)
+		close(doneC)
+		resumeInterrupt()
+	}
+}
+
+func isPauseSignal(sig os.Signal) bool {
+	for _, s := range pauseSignals {
+		if s == sig {
+			return true
+		}
+	}
+	return false
+}
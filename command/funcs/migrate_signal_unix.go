@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package funcs
+
+import (
+	"os"
+	"syscall"
+)
+
+var pauseSignals = []os.Signal{syscall.SIGUSR1}
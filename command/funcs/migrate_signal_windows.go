@@ -0,0 +1,8 @@
+//go:build windows
+// +build windows
+
+package funcs
+
+import "os"
+
+var pauseSignals []os.Signal
@@ -0,0 +1,76 @@
+package funcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/sacloud/cloud-plan-migrate/command"
+	"github.com/sacloud/cloud-plan-migrate/command/params"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+// MigrateStatus shows the state of servers of the run recorded in the journal
+func MigrateStatus(ctx command.Context, params *params.MigrateStatusParam) error {
+
+	run, err := command.FindJournalRun(params.Journal, params.RunID)
+	if err != nil {
+		return fmt.Errorf("Reading journal is failed: %s", err)
+	}
+
+	out := command.GlobalOption.Out
+	if params.OutputType == "json" {
+		data, err := json.MarshalIndent(run, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+	outputJournalRun(out, run)
+	return nil
+}
+
+func outputJournalRun(out io.Writer, run *migrate.JournalRun) {
+	finished := "not finished(interrupted or running)"
+	if !run.FinishedAt.IsZero() {
+		finished = run.FinishedAt.Format(time.RFC3339)
+	}
+	fmt.Fprintf(out, "Run      : %s\n", run.RunID)
+	fmt.Fprintf(out, "Started  : %s\n", run.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(out, "Finished : %s\n", finished)
+
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"ID", "Name", "State", "MigratedID", "OriginalDisks", "ClonedDisks", "Error"})
+	table.SetAutoFormatHeaders(false)
+	for _, s := range run.Servers {
+		migrated := ""
+		if s.MigratedServerID != 0 {
+			migrated = fmt.Sprintf("%d", s.MigratedServerID)
+		}
+		originals := strings.Join(command.StringIDs(s.OriginalDiskIDs), ",")
+		if s.DisksDeleted {
+			originals += "(deleted)"
+		}
+		var cloned []int64
+		for _, id := range s.ClonedDiskIDs {
+			if id != 0 {
+				cloned = append(cloned, id)
+			}
+		}
+		table.Append([]string{
+			fmt.Sprintf("%d", s.ServerID),
+			s.ServerName,
+			string(s.State),
+			migrated,
+			originals,
+			strings.Join(command.StringIDs(cloned), ","),
+			s.Error,
+		})
+	}
+	table.Render()
+	fmt.Fprintln(out, totalsSummary(run.Totals()))
+}
@@ -0,0 +1,287 @@
+package funcs
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/mattn/go-tty"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+const (
+	keyUp       = "up"
+	keyDown     = "down"
+	keyPageUp   = "pgup"
+	keyPageDown = "pgdown"
+	keyEnter    = "enter"
+)
+
+const tuiHelp = "↑/k ↓/j:move  PgUp/PgDn:scroll  Enter/l:logs  p:pause/resume  d:drain  s:skip  r:rollback  q:quit"
+
+// migrationTUI is an interactive screen of the migration
+type migrationTUI struct {
+	migration *migrate.Migration
+	tty       *tty.TTY
+
+	cursor   int
+	offset   int
+	detail   bool
+	finished bool
+	message  string
+}
+
+// runMigrationTUI shows the interactive screen until the migration is finished and the operator quits
+func runMigrationTUI(migration *migrate.Migration, doneC <-chan bool, logProgress func(*migrate.Progress)) error {
+	t, err := tty.Open()
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	ui := &migrationTUI{migration: migration, tty: t}
+	ui.write("\033[?1049h\033[?25l") // alternate screen, hide cursor
+	defer ui.write("\033[?25h\033[?1049l")
+
+	keyC := make(chan string)
+	go ui.readKeys(keyC)
+	messageC := make(chan string, 1)
+	tickC := time.NewTicker(time.Second).C
+
+	ui.render()
+	for {
+		select {
+		case <-tickC:
+			logProgress(migration.Progress())
+		case <-doneC:
+			ui.finished = true
+			ui.message = "Migration finished. Press q to exit"
+		case msg := <-messageC:
+			ui.message = msg
+		case key := <-keyC:
+			if key == "q" && ui.finished {
+				return nil
+			}
+			ui.handleKey(key, messageC)
+		}
+		ui.render()
+	}
+}
+
+func (ui *migrationTUI) readKeys(keyC chan<- string) {
+	for {
+		r, err := ui.tty.ReadRune()
+		if err != nil {
+			return
+		}
+		switch r {
+		case 0x1b: // escape sequence
+			if r, err = ui.tty.ReadRune(); err != nil || r != '[' {
+				continue
+			}
+			if r, err = ui.tty.ReadRune(); err != nil {
+				return
+			}
+			switch r {
+			case 'A':
+				keyC <- keyUp
+			case 'B':
+				keyC <- keyDown
+			case '5', '6':
+				ui.tty.ReadRune() // trailing '~'
+				if r == '5' {
+					keyC <- keyPageUp
+				} else {
+					keyC <- keyPageDown
+				}
+			}
+		case '\r', '\n':
+			keyC <- keyEnter
+		default:
+			keyC <- string(r)
+		}
+	}
+}
+
+func (ui *migrationTUI) handleKey(key string, messageC chan<- string) {
+	status := ui.migration.Status()
+	if len(status) == 0 {
+		return
+	}
+	selected := status[ui.cursor]
+	serverID := selected.ID()
+
+	switch key {
+	case keyUp, "k":
+		ui.moveCursor(-1)
+	case keyDown, "j":
+		ui.moveCursor(1)
+	case keyPageUp:
+		ui.moveCursor(-ui.listHeight())
+	case keyPageDown:
+		ui.moveCursor(ui.listHeight())
+	case keyEnter, "l":
+		ui.detail = !ui.detail
+	case "p":
+		if ui.migration.Paused() {
+			ui.migration.Resume()
+			ui.message = "Resumed"
+		} else {
+			ui.migration.Pause()
+			ui.message = "Paused: servers in progress continue, queued servers wait for resume"
+		}
+	case "d":
+		ui.migration.Drain()
+		ui.message = "Draining: waiting for servers in progress, queued servers are not started"
+	case "s":
+		if err := ui.migration.Skip(serverID); err != nil {
+			ui.message = fmt.Sprintf("Skip is failed: %s", err)
+		} else {
+			ui.message = fmt.Sprintf("Server[%d] is skipped", serverID)
+		}
+	case "r":
+		ui.message = fmt.Sprintf("Rolling back Server[%d]...", serverID)
+		go func() {
+			if err := ui.migration.Rollback(serverID); err != nil {
+				messageC <- err.Error()
+				return
+			}
+			messageC <- fmt.Sprintf("Server[%d] is rolled back", serverID)
+		}()
+	case "q":
+		ui.message = "Migration is running. Press p to pause starting new servers"
+	}
+}
+
+func (ui *migrationTUI) moveCursor(delta int) {
+	count := len(ui.migration.Status())
+	ui.cursor += delta
+	if ui.cursor >= count {
+		ui.cursor = count - 1
+	}
+	if ui.cursor < 0 {
+		ui.cursor = 0
+	}
+
+	height := ui.listHeight()
+	if ui.cursor < ui.offset {
+		ui.offset = ui.cursor
+	}
+	if ui.cursor >= ui.offset+height {
+		ui.offset = ui.cursor - height + 1
+	}
+}
+
+func (ui *migrationTUI) size() (int, int) {
+	w, h, err := ui.tty.Size()
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// listHeight returns the number of rows of the server list
+func (ui *migrationTUI) listHeight() int {
+	_, h := ui.size()
+	height := h - 5 // header(3) + footer(2)
+	if ui.detail {
+		height = height / 2
+	}
+	if height < 1 {
+		height = 1
+	}
+	return height
+}
+
+func (ui *migrationTUI) render() {
+	w, h := ui.size()
+	status := ui.migration.Status()
+	height := ui.listHeight()
+
+	var lines []string
+	header := totalsSummary(ui.migration.Totals()) + controlState(ui.migration)
+	progress := ui.migration.Progress()
+	lines = append(lines,
+		header,
+		fmt.Sprintf("Overall %s %s", progressBar(progress.Percentage(), 40), progress),
+		fmt.Sprintf("  %-14s %-20s %-12s %s", "ID", "Name", "State", "Progress"),
+	)
+
+	for i := ui.offset; i < ui.offset+height && i < len(status); i++ {
+		s := status[i]
+		cursor := " "
+		if i == ui.cursor {
+			cursor = ">"
+		}
+		p := s.Progress()
+		progress := ""
+		if p.MigratedMB > 0 {
+			progress = p.String()
+		} else if w := s.WindowStatus(); w != "" {
+			progress = w
+		}
+		lines = append(lines, fmt.Sprintf("%s %-14s %-20s %-12s %s",
+			cursor, s.ServerID(), runewidth.Truncate(s.ServerName(), 20, "…"), s.State(), progress))
+	}
+	for len(lines) < height+3 {
+		lines = append(lines, "")
+	}
+
+	if ui.detail && len(status) > 0 {
+		lines = append(lines, ui.detailLines(status[ui.cursor], h-len(lines)-2)...)
+	}
+
+	for len(lines) < h-2 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, ui.message, tuiHelp)
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("\033[H\033[2J")
+	for i, line := range lines {
+		if i >= h {
+			break
+		}
+		if i > 0 {
+			buf.WriteString("\r\n")
+		}
+		buf.WriteString(runewidth.Truncate(line, w, ""))
+	}
+	ui.write(buf.String())
+}
+
+// detailLines returns steps, error and recent logs of the server
+func (ui *migrationTUI) detailLines(s *migrate.ServerStatus, height int) []string {
+	lines := []string{
+		strings.Repeat("-", 40),
+		fmt.Sprintf("Server[%s:%s] %s", s.ServerID(), s.ServerName(), s.State()),
+		fmt.Sprintf("  Shutdown:%s  PlanChange:%s  Verify:%s  Boot:%s",
+			s.ShutdownStatus(), s.MigrationStatus(), s.VerifyStatus(), s.BootStatus()),
+	}
+	for _, d := range s.Disks {
+		lines = append(lines, fmt.Sprintf("  Disk %s  Cleanup:%s",
+			strings.Replace(d.CloneStatus(), "\n", " ", -1), d.DeleteStatus()))
+	}
+	if err := s.GetErr(); err != nil {
+		lines = append(lines, fmt.Sprintf("  Error: %s", err))
+	}
+
+	logs := s.Logs()
+	rest := height - len(lines)
+	if rest < 0 {
+		rest = 0
+	}
+	if len(logs) > rest {
+		logs = logs[len(logs)-rest:]
+	}
+	for _, l := range logs {
+		lines = append(lines, "  "+l)
+	}
+	return lines
+}
+
+func (ui *migrationTUI) write(s string) {
+	ui.tty.Output().WriteString(s)
+}
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -17,6 +18,8 @@ type Option struct {
 	AcceptLanguage    string
 	RetryMax          int
 	RetryIntervalSec  int64
+	APIRateLimit      float64
+	SlowAPICallSec    int
 	Zones             []string
 	APIRootURL        string
 	TraceMode         bool
@@ -50,6 +53,17 @@ func init() {
 	}
 }
 
+// Secrets returns API keys which must not be written to logs or reports
+func (o *Option) Secrets() []string {
+	var secrets []string
+	for _, s := range []string{o.AccessToken, o.AccessTokenSecret} {
+		if s != "" {
+			secrets = append(secrets, s)
+		}
+	}
+	return secrets
+}
+
 func (o *Option) Validate(skipAuth bool) []error {
 	var errs []error
 
@@ -62,6 +76,10 @@ func (o *Option) Validate(skipAuth bool) []error {
 		errs = append(errs, ValidateInStrValues("zone", o.Zone, "is1a")...)
 	}
 
+	if o.APIRateLimit < 0 {
+		errs = append(errs, fmt.Errorf("%q: must be 0 or greater", "api-rate-limit"))
+	}
+
 	o.Validated = true
 	o.Valid = len(errs) == 0
 	o.ValidationResults = errs
@@ -0,0 +1,15 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DefaultThroughputHistoryPath returns default path of the clone throughput history file(~/.cloud-plan-migrate/throughput-history.json)
+func DefaultThroughputHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cloud-plan-migrate", "throughput-history.json")
+}
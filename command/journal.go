@@ -0,0 +1,85 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+// DefaultJournalPath returns default path of the journal file(~/.cloud-plan-migrate/journal.jsonl)
+func DefaultJournalPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cloud-plan-migrate", "journal.jsonl")
+}
+
+// FindJournalRun reads the journal and returns the run, or the last run if runID is empty
+func FindJournalRun(path, runID string) (*migrate.JournalRun, error) {
+	runs, err := migrate.ReadJournal(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("journal %q is not found", path)
+		}
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("journal %q has no runs", path)
+	}
+	if runID == "" {
+		return runs[len(runs)-1], nil
+	}
+	for _, run := range runs {
+		if run.RunID == runID {
+			return run, nil
+		}
+	}
+	return nil, fmt.Errorf("run %q is not found in journal %q", runID, path)
+}
+
+// SelectJournalServers returns records of servers of the run specified by ids,
+// or all servers matching target if ids is empty
+func SelectJournalServers(run *migrate.JournalRun, ids []int64, target func(r *migrate.ServerRecord) bool) ([]*migrate.ServerRecord, error) {
+	var records []*migrate.ServerRecord
+	if len(ids) == 0 {
+		for _, r := range run.Servers {
+			if target(r) {
+				records = append(records, r)
+			}
+		}
+		return records, nil
+	}
+	for _, id := range ids {
+		r := run.Server(id)
+		if r == nil {
+			return nil, fmt.Errorf("Server[%d] is not found in run %s", id, run.RunID)
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// IsRollbackTarget reports whether the server is rolled back by default(failed)
+func IsRollbackTarget(r *migrate.ServerRecord) bool {
+	return r.State == migrate.ServerStateFailed
+}
+
+// IsCleanupTarget reports whether original disks of the server are deleted by default(migrated and not deleted yet)
+func IsCleanupTarget(r *migrate.ServerRecord) bool {
+	return r.State == migrate.ServerStateDone && !r.DisksDeleted
+}
+
+// IsResumeTarget reports whether the server is resumed
+// (not migrated: queued, waiting or rolled back, or interrupted while running before the plan change)
+func IsResumeTarget(r *migrate.ServerRecord) bool {
+	switch r.State {
+	case migrate.ServerStateQueued, migrate.ServerStateWaiting, migrate.ServerStateRolledBack:
+		return true
+	case migrate.ServerStateRunning:
+		return r.MigratedServerID == 0
+	}
+	return false
+}
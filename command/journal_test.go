@@ -0,0 +1,152 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestJournal(t *testing.T, path string) {
+	now := time.Now()
+	record := func(id int64, state migrate.ServerState) *migrate.ServerRecord {
+		return &migrate.ServerRecord{ServerID: id, State: state}
+	}
+	entries := []*migrate.JournalEntry{
+		{RunID: "run1", Type: migrate.EventRunStarted, Time: now, Servers: []*migrate.ServerRecord{
+			record(1, migrate.ServerStateQueued),
+		}},
+		{RunID: "run1", Type: migrate.EventServerFailed, Time: now, Servers: []*migrate.ServerRecord{
+			record(1, migrate.ServerStateFailed),
+		}},
+		{RunID: "run2", Type: migrate.EventRunStarted, Time: now, Servers: []*migrate.ServerRecord{
+			record(11, migrate.ServerStateQueued),
+			record(12, migrate.ServerStateQueued),
+			record(13, migrate.ServerStateQueued),
+			record(14, migrate.ServerStateQueued),
+			record(15, migrate.ServerStateQueued),
+			record(16, migrate.ServerStateQueued),
+			record(17, migrate.ServerStateQueued),
+			record(18, migrate.ServerStateQueued),
+		}},
+		{RunID: "run2", Type: migrate.EventServerCompleted, Time: now, Servers: []*migrate.ServerRecord{
+			record(11, migrate.ServerStateDone),
+		}},
+		{RunID: "run2", Type: migrate.EventServerCompleted, Time: now, Servers: []*migrate.ServerRecord{
+			{ServerID: 12, State: migrate.ServerStateDone, DisksDeleted: true},
+		}},
+		{RunID: "run2", Type: migrate.EventServerFailed, Time: now, Servers: []*migrate.ServerRecord{
+			record(13, migrate.ServerStateFailed),
+		}},
+		{RunID: "run2", Type: migrate.EventServerFailed, Time: now, Servers: []*migrate.ServerRecord{
+			record(14, migrate.ServerStateFailed),
+		}},
+		{RunID: "run2", Type: migrate.EventServerRolledBack, Time: now, Servers: []*migrate.ServerRecord{
+			record(14, migrate.ServerStateRolledBack),
+		}},
+		{RunID: "run2", Type: migrate.EventRunFinished, Time: now, Servers: []*migrate.ServerRecord{
+			record(15, migrate.ServerStateRunning),
+			record(16, migrate.ServerStateWaiting),
+			{ServerID: 18, State: migrate.ServerStateRunning, MigratedServerID: 28}, // interrupted after the plan change
+		}},
+	}
+	for _, e := range entries {
+		assert.NoError(t, migrate.AppendJournal(path, e))
+	}
+}
+
+func TestFindJournalRun(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "journal")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "journal.jsonl")
+	_, err = FindJournalRun(path, "")
+	assert.EqualError(t, err, `journal "`+path+`" is not found`)
+
+	writeTestJournal(t, path)
+
+	run, err := FindJournalRun(path, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "run2", run.RunID)
+
+	run, err = FindJournalRun(path, "run1")
+	assert.NoError(t, err)
+	assert.Equal(t, "run1", run.RunID)
+
+	_, err = FindJournalRun(path, "run3")
+	assert.EqualError(t, err, `run "run3" is not found in journal "`+path+`"`)
+}
+
+func TestSelectJournalServers(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "journal")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "journal.jsonl")
+	writeTestJournal(t, path)
+	run, err := FindJournalRun(path, "")
+	assert.NoError(t, err)
+
+	ids := func(records []*migrate.ServerRecord) []int64 {
+		var ids []int64
+		for _, r := range records {
+			ids = append(ids, r.ServerID)
+		}
+		return ids
+	}
+
+	expects := []struct {
+		name   string
+		ids    []int64
+		target func(r *migrate.ServerRecord) bool
+		expect []int64
+		err    string
+	}{
+		{
+			name:   "rollback",
+			target: IsRollbackTarget,
+			expect: []int64{13},
+		},
+		{
+			name:   "cleanup",
+			target: IsCleanupTarget,
+			expect: []int64{11},
+		},
+		{
+			name:   "resume",
+			target: IsResumeTarget,
+			expect: []int64{14, 15, 16, 17},
+		},
+		{
+			name:   "ids regardless of target",
+			ids:    []int64{12, 15},
+			target: IsCleanupTarget,
+			expect: []int64{12, 15},
+		},
+		{
+			name:   "id not in the run",
+			ids:    []int64{11, 1},
+			target: IsRollbackTarget,
+			err:    "Server[1] is not found in run run2",
+		},
+	}
+
+	for _, expect := range expects {
+		t.Run(expect.name, func(t *testing.T) {
+			records, err := SelectJournalServers(run, expect.ids, expect.target)
+			if expect.err != "" {
+				assert.EqualError(t, err, expect.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, expect.expect, ids(records))
+		})
+	}
+}
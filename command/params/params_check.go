@@ -0,0 +1,51 @@
+package params
+
+// MigrateCheckParam is input parameters for the check command
+type MigrateCheckParam struct {
+	Selector   []string `json:"selector"`
+	OutputType string   `json:"output-type"`
+	History    string   `json:"throughput-history"`
+}
+
+// NewMigrateCheckParam return new MigrateCheckParam
+func NewMigrateCheckParam() *MigrateCheckParam {
+	return &MigrateCheckParam{
+		OutputType: "table",
+	}
+}
+
+// Validate checks current values in model
+func (p *MigrateCheckParam) Validate() []error {
+	errors := []error{}
+	{
+		validator := validateInStrValues
+		errs := validator("--output-type", p.OutputType, "table", "csv", "json")
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+	}
+	return errors
+}
+
+func (p *MigrateCheckParam) SetSelector(v []string) {
+	p.Selector = v
+}
+
+func (p *MigrateCheckParam) GetSelector() []string {
+	return p.Selector
+}
+func (p *MigrateCheckParam) SetOutputType(v string) {
+	p.OutputType = v
+}
+
+func (p *MigrateCheckParam) GetOutputType() string {
+	return p.OutputType
+}
+
+func (p *MigrateCheckParam) SetHistory(v string) {
+	p.History = v
+}
+
+func (p *MigrateCheckParam) GetHistory() string {
+	return p.History
+}
@@ -0,0 +1,56 @@
+package params
+
+// MigrateCleanupParam is input parameters for the cleanup command
+type MigrateCleanupParam struct {
+	Journal   string  `json:"journal"`
+	RunID     string  `json:"run"`
+	Assumeyes bool    `json:"assumeyes"`
+	IDs       []int64 `json:"ids"`
+}
+
+// NewMigrateCleanupParam return new MigrateCleanupParam
+func NewMigrateCleanupParam() *MigrateCleanupParam {
+	return &MigrateCleanupParam{}
+}
+
+// Validate checks current values in model
+func (p *MigrateCleanupParam) Validate() []error {
+	errors := []error{}
+	{
+		validator := validateRequired
+		errs := validator("--journal", p.Journal)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+	}
+	return errors
+}
+
+func (p *MigrateCleanupParam) SetJournal(v string) {
+	p.Journal = v
+}
+
+func (p *MigrateCleanupParam) GetJournal() string {
+	return p.Journal
+}
+func (p *MigrateCleanupParam) SetRunID(v string) {
+	p.RunID = v
+}
+
+func (p *MigrateCleanupParam) GetRunID() string {
+	return p.RunID
+}
+func (p *MigrateCleanupParam) SetAssumeyes(v bool) {
+	p.Assumeyes = v
+}
+
+func (p *MigrateCleanupParam) GetAssumeyes() bool {
+	return p.Assumeyes
+}
+func (p *MigrateCleanupParam) SetIDs(v []int64) {
+	p.IDs = v
+}
+
+func (p *MigrateCleanupParam) GetIDs() []int64 {
+	return p.IDs
+}
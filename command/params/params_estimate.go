@@ -0,0 +1,87 @@
+package params
+
+import (
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+// MigrateEstimateParam is input parameters for the estimate command
+type MigrateEstimateParam struct {
+	Selector    []string `json:"selector"`
+	Workers     int      `json:"workers"`
+	CleanupDisk bool     `json:"cleanup-disk"`
+	PreClone    bool     `json:"pre-clone"`
+	History     string   `json:"throughput-history"`
+	OutputType  string   `json:"output-type"`
+}
+
+// NewMigrateEstimateParam return new MigrateEstimateParam
+func NewMigrateEstimateParam() *MigrateEstimateParam {
+	return &MigrateEstimateParam{
+		Workers:    migrate.DefaultMaxWorkerCount,
+		OutputType: "table",
+	}
+}
+
+// Validate checks current values in model
+func (p *MigrateEstimateParam) Validate() []error {
+	errors := []error{}
+	{
+		validator := validateIntRange
+		errs := validator("--workers", p.Workers, 1, 100)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+	}
+	{
+		validator := validateInStrValues
+		errs := validator("--output-type", p.OutputType, "table", "json")
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+	}
+	return errors
+}
+
+func (p *MigrateEstimateParam) SetSelector(v []string) {
+	p.Selector = v
+}
+
+func (p *MigrateEstimateParam) GetSelector() []string {
+	return p.Selector
+}
+func (p *MigrateEstimateParam) SetWorkers(v int) {
+	p.Workers = v
+}
+
+func (p *MigrateEstimateParam) GetWorkers() int {
+	return p.Workers
+}
+func (p *MigrateEstimateParam) SetCleanupDisk(v bool) {
+	p.CleanupDisk = v
+}
+
+func (p *MigrateEstimateParam) GetCleanupDisk() bool {
+	return p.CleanupDisk
+}
+func (p *MigrateEstimateParam) SetHistory(v string) {
+	p.History = v
+}
+
+func (p *MigrateEstimateParam) GetHistory() string {
+	return p.History
+}
+func (p *MigrateEstimateParam) SetOutputType(v string) {
+	p.OutputType = v
+}
+
+func (p *MigrateEstimateParam) GetOutputType() string {
+	return p.OutputType
+}
+
+func (p *MigrateEstimateParam) SetPreClone(v bool) {
+	p.PreClone = v
+}
+
+func (p *MigrateEstimateParam) GetPreClone() bool {
+	return p.PreClone
+}
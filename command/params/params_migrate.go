@@ -1,18 +1,68 @@
 package params
 
+import (
+	"fmt"
+
+	"github.com/sacloud/cloud-plan-migrate/logging"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
 // MigrateMigrateParam is input parameters for the sacloud API
 type MigrateMigrateParam struct {
-	Selector      []string `json:"selector"`
-	Assumeyes     bool     `json:"assumeyes"`
-	CleanupDisk   bool     `json:"cleanup-disk"`
-	DisableReboot bool     `json:"disable-reboot"`
-	ID            int64    `json:"id"`
-	IDs           []int64
+	Selector       []string `json:"selector"`
+	Assumeyes      bool     `json:"assumeyes"`
+	Workers        int      `json:"workers"`
+	CleanupDisk    bool     `json:"cleanup-disk"`
+	PreClone       bool     `json:"pre-clone"`
+	AcceptDataLoss bool     `json:"accept-pre-clone-data-loss"`
+	DisableReboot  bool     `json:"disable-reboot"`
+	StrictVerify   bool     `json:"strict-verify"`
+	UpdateRefs     bool     `json:"update-references"`
+	RefCommand     string   `json:"reference-command"`
+	DiskPlan       string   `json:"disk-plan"`
+	BeforeShutdown string   `json:"before-shutdown-command"`
+	AfterMigrate   string   `json:"after-migrate-command"`
+	AfterBoot      string   `json:"after-boot-command"`
+	ListRefs       bool     `json:"list-references"`
+	Exclude        []string `json:"exclude"`
+	DenyList       string   `json:"deny-list"`
+	Inventory      string   `json:"inventory"`
+	Wave           string   `json:"wave"`
+	History        string   `json:"throughput-history"`
+	Journal        string   `json:"journal"`
+	Compact        bool     `json:"compact"`
+	TUI            bool     `json:"tui"`
+	Windows        []string `json:"maintenance-window"`
+	Webhooks       []string `json:"webhook"`
+	SlackWebhooks  []string `json:"slack-webhook"`
+	WebhookTmpl    string   `json:"webhook-template"`
+	MailTo         []string `json:"mail-to"`
+	MailFrom       string   `json:"mail-from"`
+	MailSubject    string   `json:"mail-subject"`
+	SMTPAddr       string   `json:"smtp-addr"`
+	SMTPUser       string   `json:"smtp-user"`
+	SMTPPassword   string   `json:"-"`
+	LogLevel       string   `json:"log-level"`
+	LogFormat      string   `json:"log-format"`
+	LogFile        string   `json:"log-file"`
+	LogDir         string   `json:"log-dir"`
+	LogStderr      bool     `json:"log-stderr"`
+	LogSyslog      bool     `json:"log-syslog"`
+	MetricsAddr    string   `json:"metrics-addr"`
+	Record         string   `json:"record"`
+	ID             int64    `json:"id"`
+	IDs            []int64
+	Protection     *migrate.Protection
+	ServerOptions  map[int64]*migrate.ServerOptions
 }
 
 // NewMigrateMigrateParam return new MigrateMigrateParam
 func NewMigrateMigrateParam() *MigrateMigrateParam {
-	return &MigrateMigrateParam{}
+	return &MigrateMigrateParam{
+		Workers:   migrate.DefaultMaxWorkerCount,
+		LogLevel:  "info",
+		LogFormat: string(logging.FormatText),
+	}
 }
 
 // Validate checks current values in model
@@ -25,9 +75,70 @@ func (p *MigrateMigrateParam) Validate() []error {
 			errors = append(errors, errs...)
 		}
 	}
+	{
+		validator := validateIntRange
+		errs := validator("--workers", p.Workers, 1, 100)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+	}
+	{
+		validator := validateInStrValues
+		errs := validator("--disk-plan", p.DiskPlan, "ssd", "hdd")
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+	}
+	for _, w := range p.Windows {
+		if _, err := migrate.ParseWindow(w); err != nil {
+			errors = append(errors, fmt.Errorf("%q: %s", "--maintenance-window", err))
+		}
+	}
+	if err := p.ValidatePreClone(); err != nil {
+		errors = append(errors, err)
+	}
+	if p.WebhookTmpl != "" && len(p.Webhooks) == 0 {
+		errors = append(errors, fmt.Errorf("%q: requires --webhook", "--webhook-template"))
+	}
+	if _, err := logging.ParseLevel(p.LogLevel); err != nil {
+		errors = append(errors, fmt.Errorf("%q: %s", "--log-level", err))
+	}
+	if _, err := logging.ParseFormat(p.LogFormat); err != nil {
+		errors = append(errors, fmt.Errorf("%q: %s", "--log-format", err))
+	}
+	if p.LogStderr && p.TUI {
+		errors = append(errors, fmt.Errorf("%q: can't be used with --tui", "--log-stderr"))
+	}
+	if len(p.MailTo) > 0 {
+		if p.MailFrom == "" {
+			errors = append(errors, fmt.Errorf("%q: required with --mail-to", "--mail-from"))
+		}
+		if p.SMTPAddr == "" {
+			errors = append(errors, fmt.Errorf("%q: required with --mail-to", "--smtp-addr"))
+		}
+	}
 	return errors
 }
 
+// ValidatePreClone checks that the data loss of pre-clone is accepted
+//
+// Pre-clone is enabled by --pre-clone or pre_clone of the inventory(ServerOptions).
+func (p *MigrateMigrateParam) ValidatePreClone() error {
+	if p.AcceptDataLoss {
+		return nil
+	}
+	preClone := p.PreClone
+	for _, opts := range p.ServerOptions {
+		if opts.PreClone != nil && *opts.PreClone {
+			preClone = true
+		}
+	}
+	if preClone {
+		return fmt.Errorf("%q: requires --accept-pre-clone-data-loss, writes during the copy are not migrated", "--pre-clone")
+	}
+	return nil
+}
+
 func (p *MigrateMigrateParam) SetSelector(v []string) {
 	p.Selector = v
 }
@@ -42,6 +153,13 @@ func (p *MigrateMigrateParam) SetAssumeyes(v bool) {
 func (p *MigrateMigrateParam) GetAssumeyes() bool {
 	return p.Assumeyes
 }
+func (p *MigrateMigrateParam) SetWorkers(v int) {
+	p.Workers = v
+}
+
+func (p *MigrateMigrateParam) GetWorkers() int {
+	return p.Workers
+}
 func (p *MigrateMigrateParam) SetCleanupDisk(v bool) {
 	p.CleanupDisk = v
 }
@@ -56,6 +174,90 @@ func (p *MigrateMigrateParam) SetDisableReboot(v bool) {
 func (p *MigrateMigrateParam) GetDisableReboot() bool {
 	return p.DisableReboot
 }
+func (p *MigrateMigrateParam) SetStrictVerify(v bool) {
+	p.StrictVerify = v
+}
+
+func (p *MigrateMigrateParam) GetStrictVerify() bool {
+	return p.StrictVerify
+}
+func (p *MigrateMigrateParam) SetUpdateRefs(v bool) {
+	p.UpdateRefs = v
+}
+
+func (p *MigrateMigrateParam) GetUpdateRefs() bool {
+	return p.UpdateRefs
+}
+func (p *MigrateMigrateParam) SetRefCommand(v string) {
+	p.RefCommand = v
+}
+
+func (p *MigrateMigrateParam) GetRefCommand() string {
+	return p.RefCommand
+}
+func (p *MigrateMigrateParam) SetDiskPlan(v string) {
+	p.DiskPlan = v
+}
+
+func (p *MigrateMigrateParam) GetDiskPlan() string {
+	return p.DiskPlan
+}
+func (p *MigrateMigrateParam) SetBeforeShutdown(v string) {
+	p.BeforeShutdown = v
+}
+
+func (p *MigrateMigrateParam) GetBeforeShutdown() string {
+	return p.BeforeShutdown
+}
+func (p *MigrateMigrateParam) SetAfterMigrate(v string) {
+	p.AfterMigrate = v
+}
+
+func (p *MigrateMigrateParam) GetAfterMigrate() string {
+	return p.AfterMigrate
+}
+func (p *MigrateMigrateParam) SetAfterBoot(v string) {
+	p.AfterBoot = v
+}
+
+func (p *MigrateMigrateParam) GetAfterBoot() string {
+	return p.AfterBoot
+}
+func (p *MigrateMigrateParam) SetListRefs(v bool) {
+	p.ListRefs = v
+}
+
+func (p *MigrateMigrateParam) GetListRefs() bool {
+	return p.ListRefs
+}
+func (p *MigrateMigrateParam) SetExclude(v []string) {
+	p.Exclude = v
+}
+
+func (p *MigrateMigrateParam) GetExclude() []string {
+	return p.Exclude
+}
+func (p *MigrateMigrateParam) SetDenyList(v string) {
+	p.DenyList = v
+}
+
+func (p *MigrateMigrateParam) GetDenyList() string {
+	return p.DenyList
+}
+func (p *MigrateMigrateParam) SetInventory(v string) {
+	p.Inventory = v
+}
+
+func (p *MigrateMigrateParam) GetInventory() string {
+	return p.Inventory
+}
+func (p *MigrateMigrateParam) SetWave(v string) {
+	p.Wave = v
+}
+
+func (p *MigrateMigrateParam) GetWave() string {
+	return p.Wave
+}
 func (p *MigrateMigrateParam) SetID(v int64) {
 	p.ID = v
 }
@@ -63,3 +265,194 @@ func (p *MigrateMigrateParam) SetID(v int64) {
 func (p *MigrateMigrateParam) GetID() int64 {
 	return p.ID
 }
+
+func (p *MigrateMigrateParam) SetHistory(v string) {
+	p.History = v
+}
+
+func (p *MigrateMigrateParam) GetHistory() string {
+	return p.History
+}
+
+func (p *MigrateMigrateParam) SetCompact(v bool) {
+	p.Compact = v
+}
+
+func (p *MigrateMigrateParam) GetCompact() bool {
+	return p.Compact
+}
+
+func (p *MigrateMigrateParam) SetTUI(v bool) {
+	p.TUI = v
+}
+
+func (p *MigrateMigrateParam) GetTUI() bool {
+	return p.TUI
+}
+
+func (p *MigrateMigrateParam) SetWindows(v []string) {
+	p.Windows = v
+}
+
+func (p *MigrateMigrateParam) GetWindows() []string {
+	return p.Windows
+}
+
+func (p *MigrateMigrateParam) SetPreClone(v bool) {
+	p.PreClone = v
+}
+
+func (p *MigrateMigrateParam) GetPreClone() bool {
+	return p.PreClone
+}
+func (p *MigrateMigrateParam) SetAcceptDataLoss(v bool) {
+	p.AcceptDataLoss = v
+}
+
+func (p *MigrateMigrateParam) GetAcceptDataLoss() bool {
+	return p.AcceptDataLoss
+}
+
+func (p *MigrateMigrateParam) SetWebhooks(v []string) {
+	p.Webhooks = v
+}
+
+func (p *MigrateMigrateParam) GetWebhooks() []string {
+	return p.Webhooks
+}
+
+func (p *MigrateMigrateParam) SetSlackWebhooks(v []string) {
+	p.SlackWebhooks = v
+}
+
+func (p *MigrateMigrateParam) GetSlackWebhooks() []string {
+	return p.SlackWebhooks
+}
+
+func (p *MigrateMigrateParam) SetWebhookTmpl(v string) {
+	p.WebhookTmpl = v
+}
+
+func (p *MigrateMigrateParam) GetWebhookTmpl() string {
+	return p.WebhookTmpl
+}
+
+func (p *MigrateMigrateParam) SetMailTo(v []string) {
+	p.MailTo = v
+}
+
+func (p *MigrateMigrateParam) GetMailTo() []string {
+	return p.MailTo
+}
+
+func (p *MigrateMigrateParam) SetMailFrom(v string) {
+	p.MailFrom = v
+}
+
+func (p *MigrateMigrateParam) GetMailFrom() string {
+	return p.MailFrom
+}
+
+func (p *MigrateMigrateParam) SetMailSubject(v string) {
+	p.MailSubject = v
+}
+
+func (p *MigrateMigrateParam) GetMailSubject() string {
+	return p.MailSubject
+}
+
+func (p *MigrateMigrateParam) SetSMTPAddr(v string) {
+	p.SMTPAddr = v
+}
+
+func (p *MigrateMigrateParam) GetSMTPAddr() string {
+	return p.SMTPAddr
+}
+
+func (p *MigrateMigrateParam) SetSMTPUser(v string) {
+	p.SMTPUser = v
+}
+
+func (p *MigrateMigrateParam) GetSMTPUser() string {
+	return p.SMTPUser
+}
+
+func (p *MigrateMigrateParam) SetSMTPPassword(v string) {
+	p.SMTPPassword = v
+}
+
+func (p *MigrateMigrateParam) GetSMTPPassword() string {
+	return p.SMTPPassword
+}
+
+func (p *MigrateMigrateParam) SetLogLevel(v string) {
+	p.LogLevel = v
+}
+
+func (p *MigrateMigrateParam) GetLogLevel() string {
+	return p.LogLevel
+}
+
+func (p *MigrateMigrateParam) SetLogFormat(v string) {
+	p.LogFormat = v
+}
+
+func (p *MigrateMigrateParam) GetLogFormat() string {
+	return p.LogFormat
+}
+
+func (p *MigrateMigrateParam) SetLogFile(v string) {
+	p.LogFile = v
+}
+
+func (p *MigrateMigrateParam) GetLogFile() string {
+	return p.LogFile
+}
+
+func (p *MigrateMigrateParam) SetLogDir(v string) {
+	p.LogDir = v
+}
+
+func (p *MigrateMigrateParam) GetLogDir() string {
+	return p.LogDir
+}
+
+func (p *MigrateMigrateParam) SetLogStderr(v bool) {
+	p.LogStderr = v
+}
+
+func (p *MigrateMigrateParam) GetLogStderr() bool {
+	return p.LogStderr
+}
+
+func (p *MigrateMigrateParam) SetLogSyslog(v bool) {
+	p.LogSyslog = v
+}
+
+func (p *MigrateMigrateParam) GetLogSyslog() bool {
+	return p.LogSyslog
+}
+
+func (p *MigrateMigrateParam) SetJournal(v string) {
+	p.Journal = v
+}
+
+func (p *MigrateMigrateParam) GetJournal() string {
+	return p.Journal
+}
+
+func (p *MigrateMigrateParam) SetMetricsAddr(v string) {
+	p.MetricsAddr = v
+}
+
+func (p *MigrateMigrateParam) GetMetricsAddr() string {
+	return p.MetricsAddr
+}
+
+func (p *MigrateMigrateParam) SetRecord(v string) {
+	p.Record = v
+}
+
+func (p *MigrateMigrateParam) GetRecord() string {
+	return p.Record
+}
@@ -0,0 +1,121 @@
+package params
+
+import (
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+// MigratePlanParam is input parameters for the plan command
+type MigratePlanParam struct {
+	Selector    []string `json:"selector"`
+	Exclude     []string `json:"exclude"`
+	DenyList    string   `json:"deny-list"`
+	Inventory   string   `json:"inventory"`
+	Wave        string   `json:"wave"`
+	Workers     int      `json:"workers"`
+	CleanupDisk bool     `json:"cleanup-disk"`
+	PreClone    bool     `json:"pre-clone"`
+	History     string   `json:"throughput-history"`
+	OutputType  string   `json:"output-type"`
+
+	IDs           []int64                          `json:"-"` // resolved target servers
+	ServerOptions map[int64]*migrate.ServerOptions `json:"-"` // options of target servers in the inventory
+}
+
+// NewMigratePlanParam return new MigratePlanParam
+func NewMigratePlanParam() *MigratePlanParam {
+	return &MigratePlanParam{
+		Workers:    migrate.DefaultMaxWorkerCount,
+		OutputType: "table",
+	}
+}
+
+// Validate checks current values in model
+func (p *MigratePlanParam) Validate() []error {
+	errors := []error{}
+	{
+		validator := validateIntRange
+		errs := validator("--workers", p.Workers, 1, 100)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+	}
+	{
+		validator := validateInStrValues
+		errs := validator("--output-type", p.OutputType, "table", "json")
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+	}
+	return errors
+}
+
+func (p *MigratePlanParam) SetSelector(v []string) {
+	p.Selector = v
+}
+
+func (p *MigratePlanParam) GetSelector() []string {
+	return p.Selector
+}
+func (p *MigratePlanParam) SetExclude(v []string) {
+	p.Exclude = v
+}
+
+func (p *MigratePlanParam) GetExclude() []string {
+	return p.Exclude
+}
+func (p *MigratePlanParam) SetDenyList(v string) {
+	p.DenyList = v
+}
+
+func (p *MigratePlanParam) GetDenyList() string {
+	return p.DenyList
+}
+func (p *MigratePlanParam) SetInventory(v string) {
+	p.Inventory = v
+}
+
+func (p *MigratePlanParam) GetInventory() string {
+	return p.Inventory
+}
+func (p *MigratePlanParam) SetWave(v string) {
+	p.Wave = v
+}
+
+func (p *MigratePlanParam) GetWave() string {
+	return p.Wave
+}
+func (p *MigratePlanParam) SetWorkers(v int) {
+	p.Workers = v
+}
+
+func (p *MigratePlanParam) GetWorkers() int {
+	return p.Workers
+}
+func (p *MigratePlanParam) SetCleanupDisk(v bool) {
+	p.CleanupDisk = v
+}
+
+func (p *MigratePlanParam) GetCleanupDisk() bool {
+	return p.CleanupDisk
+}
+func (p *MigratePlanParam) SetPreClone(v bool) {
+	p.PreClone = v
+}
+
+func (p *MigratePlanParam) GetPreClone() bool {
+	return p.PreClone
+}
+func (p *MigratePlanParam) SetHistory(v string) {
+	p.History = v
+}
+
+func (p *MigratePlanParam) GetHistory() string {
+	return p.History
+}
+func (p *MigratePlanParam) SetOutputType(v string) {
+	p.OutputType = v
+}
+
+func (p *MigratePlanParam) GetOutputType() string {
+	return p.OutputType
+}
@@ -0,0 +1,56 @@
+package params
+
+// MigrateRollbackParam is input parameters for the rollback command
+type MigrateRollbackParam struct {
+	Journal   string  `json:"journal"`
+	RunID     string  `json:"run"`
+	Assumeyes bool    `json:"assumeyes"`
+	IDs       []int64 `json:"ids"`
+}
+
+// NewMigrateRollbackParam return new MigrateRollbackParam
+func NewMigrateRollbackParam() *MigrateRollbackParam {
+	return &MigrateRollbackParam{}
+}
+
+// Validate checks current values in model
+func (p *MigrateRollbackParam) Validate() []error {
+	errors := []error{}
+	{
+		validator := validateRequired
+		errs := validator("--journal", p.Journal)
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+	}
+	return errors
+}
+
+func (p *MigrateRollbackParam) SetJournal(v string) {
+	p.Journal = v
+}
+
+func (p *MigrateRollbackParam) GetJournal() string {
+	return p.Journal
+}
+func (p *MigrateRollbackParam) SetRunID(v string) {
+	p.RunID = v
+}
+
+func (p *MigrateRollbackParam) GetRunID() string {
+	return p.RunID
+}
+func (p *MigrateRollbackParam) SetAssumeyes(v bool) {
+	p.Assumeyes = v
+}
+
+func (p *MigrateRollbackParam) GetAssumeyes() bool {
+	return p.Assumeyes
+}
+func (p *MigrateRollbackParam) SetIDs(v []int64) {
+	p.IDs = v
+}
+
+func (p *MigrateRollbackParam) GetIDs() []int64 {
+	return p.IDs
+}
@@ -0,0 +1,50 @@
+package params
+
+// MigrateStatusParam is input parameters for the status command
+type MigrateStatusParam struct {
+	Journal    string `json:"journal"`
+	RunID      string `json:"run"`
+	OutputType string `json:"output-type"`
+}
+
+// NewMigrateStatusParam return new MigrateStatusParam
+func NewMigrateStatusParam() *MigrateStatusParam {
+	return &MigrateStatusParam{
+		OutputType: "table",
+	}
+}
+
+// Validate checks current values in model
+func (p *MigrateStatusParam) Validate() []error {
+	errors := []error{}
+	{
+		validator := validateInStrValues
+		errs := validator("--output-type", p.OutputType, "table", "json")
+		if errs != nil {
+			errors = append(errors, errs...)
+		}
+	}
+	return errors
+}
+
+func (p *MigrateStatusParam) SetJournal(v string) {
+	p.Journal = v
+}
+
+func (p *MigrateStatusParam) GetJournal() string {
+	return p.Journal
+}
+func (p *MigrateStatusParam) SetRunID(v string) {
+	p.RunID = v
+}
+
+func (p *MigrateStatusParam) GetRunID() string {
+	return p.RunID
+}
+func (p *MigrateStatusParam) SetOutputType(v string) {
+	p.OutputType = v
+}
+
+func (p *MigrateStatusParam) GetOutputType() string {
+	return p.OutputType
+}
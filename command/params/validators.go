@@ -4,6 +4,18 @@ import (
 	"github.com/sacloud/cloud-plan-migrate/command"
 )
 
+func validateRequired(fieldName string, object interface{}) []error {
+	return command.ValidateRequired(fieldName, object)
+}
+
 func validateSakuraID(fieldName string, object interface{}) []error {
 	return command.ValidateSakuraID(fieldName, object)
 }
+
+func validateInStrValues(fieldName string, object interface{}, allowValues ...string) []error {
+	return command.ValidateInStrValues(fieldName, object, allowValues...)
+}
+
+func validateIntRange(fieldName string, object interface{}, min int, max int) []error {
+	return command.ValidateIntRange(fieldName, object, min, max)
+}
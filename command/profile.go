@@ -0,0 +1,181 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultProfileName is the profile used when no profile is selected
+	DefaultProfileName = "default"
+
+	// ProfileDirEnv is the environment variable to change the base directory of profiles(compatible with usacloud)
+	ProfileDirEnv = "USACLOUD_PROFILE_DIR"
+
+	profileDirName      = ".usacloud"
+	profileFileName     = "config.json"
+	currentProfileFile  = "current"
+	profileOptionsField = "CloudPlanMigrate"
+)
+
+// Profile is the config file of the named profile, compatible with usacloud(~/.usacloud/<name>/config.json)
+//
+// Options of cloud-plan-migrate are stored in CloudPlanMigrate keyed by flag name(e.g. "workers", "webhook"),
+// usacloud ignores them.
+type Profile struct {
+	AccessToken       string
+	AccessTokenSecret string
+	Zone              string
+	Zones             []string
+	AcceptLanguage    string
+	RetryMax          int
+	RetryIntervalSec  int64
+	NoColor           bool
+	APIRootURL        string
+	DefaultOutputType string
+	Timeout           int
+
+	CloudPlanMigrate map[string]interface{} `json:",omitempty"`
+}
+
+// ProfileBaseDir returns the directory containing profiles(~/.usacloud, or $USACLOUD_PROFILE_DIR/.usacloud)
+func ProfileBaseDir() (string, error) {
+	base := os.Getenv(ProfileDirEnv)
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = home
+	}
+	return filepath.Join(base, profileDirName), nil
+}
+
+// ProfilePath returns the path of the config file of the profile
+func ProfilePath(name string) (string, error) {
+	if err := validateProfileName(name); err != nil {
+		return "", err
+	}
+	dir, err := ProfileBaseDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name, profileFileName), nil
+}
+
+// CurrentProfileName returns the profile selected by `usacloud config use`, or DefaultProfileName
+func CurrentProfileName() string {
+	dir, err := ProfileBaseDir()
+	if err != nil {
+		return DefaultProfileName
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, currentProfileFile))
+	if err != nil {
+		return DefaultProfileName
+	}
+	name := strings.TrimSpace(string(data))
+	if validateProfileName(name) != nil {
+		return DefaultProfileName
+	}
+	return name
+}
+
+// LoadProfile reads the profile, returns nil without error if the profile doesn't exist
+func LoadProfile(name string) (*Profile, error) {
+	path, err := ProfilePath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	profile := &Profile{}
+	if err := json.Unmarshal(data, profile); err != nil {
+		return nil, fmt.Errorf("parsing %q is failed: %s", path, err)
+	}
+	return profile, nil
+}
+
+// FlagValues returns values of the profile keyed by flag name
+//
+// Empty values of usacloud fields are omitted, values of CloudPlanMigrate are converted to strings
+// (arrays to multiple values) to be set as flags.
+func (p *Profile) FlagValues() (map[string][]string, error) {
+	values := map[string][]string{}
+	setString := func(name, v string) {
+		if v != "" {
+			values[name] = []string{v}
+		}
+	}
+	setString("token", p.AccessToken)
+	setString("secret", p.AccessTokenSecret)
+	setString("zone", p.Zone)
+	if len(p.Zones) > 0 {
+		values["zones"] = p.Zones
+	}
+	setString("accept-language", p.AcceptLanguage)
+	if p.RetryMax > 0 {
+		values["retry-max"] = []string{fmt.Sprintf("%d", p.RetryMax)}
+	}
+	if p.RetryIntervalSec > 0 {
+		values["retry-interval"] = []string{fmt.Sprintf("%d", p.RetryIntervalSec)}
+	}
+	if p.NoColor {
+		values["no-color"] = []string{"true"}
+	}
+	setString("api-root-url", p.APIRootURL)
+	setString("output-type", p.DefaultOutputType)
+	if p.Timeout > 0 {
+		values["timeout"] = []string{fmt.Sprintf("%d", p.Timeout)}
+	}
+
+	for name, v := range p.CloudPlanMigrate {
+		var strs []string
+		switch v := v.(type) {
+		case []interface{}:
+			for _, e := range v {
+				s, err := profileValueString(e)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %s", profileOptionsField, name, err)
+				}
+				strs = append(strs, s)
+			}
+		default:
+			s, err := profileValueString(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %s", profileOptionsField, name, err)
+			}
+			strs = []string{s}
+		}
+		values[name] = strs
+	}
+	return values, nil
+}
+
+func profileValueString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+func validateProfileName(name string) error {
+	if name == "" || name == currentProfileFile || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setTestProfileDir sets USACLOUD_PROFILE_DIR to a temporary directory and returns the directory of profiles
+func setTestProfileDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "profile")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	t.Setenv(ProfileDirEnv, dir)
+	return filepath.Join(dir, profileDirName)
+}
+
+func writeTestProfile(t *testing.T, base, name, content string) {
+	assert.NoError(t, os.MkdirAll(filepath.Join(base, name), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(base, name, profileFileName), []byte(content), 0600))
+}
+
+func TestProfilePath(t *testing.T) {
+	base := setTestProfileDir(t)
+
+	expects := []struct {
+		name string
+		path string
+		err  string
+	}{
+		{name: "default", path: filepath.Join(base, "default", "config.json")},
+		{name: "prod-1", path: filepath.Join(base, "prod-1", "config.json")},
+		{name: "", err: `invalid profile name ""`},
+		{name: "current", err: `invalid profile name "current"`},
+		{name: ".", err: `invalid profile name "."`},
+		{name: "..", err: `invalid profile name ".."`},
+		{name: "../prod", err: `invalid profile name "../prod"`},
+		{name: `prod\1`, err: `invalid profile name "prod\\1"`},
+	}
+
+	for _, expect := range expects {
+		t.Run(expect.name, func(t *testing.T) {
+			path, err := ProfilePath(expect.name)
+			if expect.err != "" {
+				assert.EqualError(t, err, expect.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, expect.path, path)
+		})
+	}
+}
+
+func TestProfileBaseDir(t *testing.T) {
+	t.Setenv(ProfileDirEnv, "")
+	home, err := os.UserHomeDir()
+	assert.NoError(t, err)
+	dir, err := ProfileBaseDir()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".usacloud"), dir)
+
+	base := setTestProfileDir(t)
+	dir, err = ProfileBaseDir()
+	assert.NoError(t, err)
+	assert.Equal(t, base, dir)
+}
+
+func TestCurrentProfileName(t *testing.T) {
+	expects := []struct {
+		name    string
+		current *string // nil if the current file doesn't exist
+		expect  string
+	}{
+		{name: "no current file", expect: DefaultProfileName},
+		{name: "current", current: strPtr("prod\n"), expect: "prod"},
+		{name: "empty", current: strPtr(""), expect: DefaultProfileName},
+		{name: "invalid", current: strPtr("../prod"), expect: DefaultProfileName},
+	}
+
+	for _, expect := range expects {
+		t.Run(expect.name, func(t *testing.T) {
+			base := setTestProfileDir(t)
+			if expect.current != nil {
+				assert.NoError(t, os.MkdirAll(base, 0700))
+				assert.NoError(t, ioutil.WriteFile(filepath.Join(base, "current"), []byte(*expect.current), 0600))
+			}
+			assert.Equal(t, expect.expect, CurrentProfileName())
+		})
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	base := setTestProfileDir(t)
+	writeTestProfile(t, base, "prod", `{
+		"AccessToken": "token",
+		"AccessTokenSecret": "secret",
+		"Zone": "is1a",
+		"RetryMax": 20,
+		"CloudPlanMigrate": {
+			"workers": 5,
+			"cleanup-disk": true,
+			"maintenance-window": ["01:00-05:00", "22:00-23:00"],
+			"reference-command": "/usr/local/bin/update-cmdb"
+		}
+	}`)
+	writeTestProfile(t, base, "broken", `{"AccessToken":`)
+	writeTestProfile(t, base, "unsupported", `{"CloudPlanMigrate": {"workers": {"value": 5}}}`)
+
+	t.Run("flag values", func(t *testing.T) {
+		profile, err := LoadProfile("prod")
+		assert.NoError(t, err)
+
+		values, err := profile.FlagValues()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]string{
+			"token":              {"token"},
+			"secret":             {"secret"},
+			"zone":               {"is1a"},
+			"retry-max":          {"20"},
+			"workers":            {"5"},
+			"cleanup-disk":       {"true"},
+			"maintenance-window": {"01:00-05:00", "22:00-23:00"},
+			"reference-command":  {"/usr/local/bin/update-cmdb"},
+		}, values)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		profile, err := LoadProfile("staging")
+		assert.NoError(t, err)
+		assert.Nil(t, profile)
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		_, err := LoadProfile("../prod")
+		assert.EqualError(t, err, `invalid profile name "../prod"`)
+	})
+
+	t.Run("broken", func(t *testing.T) {
+		_, err := LoadProfile("broken")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "parsing")
+	})
+
+	t.Run("unsupported value", func(t *testing.T) {
+		profile, err := LoadProfile("unsupported")
+		assert.NoError(t, err)
+		_, err = profile.FlagValues()
+		assert.EqualError(t, err, "CloudPlanMigrate.workers: unsupported value map[value:5]")
+	})
+}
+
+func strPtr(s string) *string {
+	return &s
+}
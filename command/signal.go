@@ -0,0 +1,40 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var (
+	interruptSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, os.Interrupt}
+	interruptC       = make(chan os.Signal, 1)
+)
+
+// HandleInterrupt terminates the process when SIGINT/SIGTERM is received
+//
+// Commands handling signals by themselves(e.g. draining the migration) suspend it by SuspendInterrupt.
+func HandleInterrupt() {
+	signal.Notify(interruptC, interruptSignals...)
+	go func() {
+		<-interruptC
+		time.Sleep(500 * time.Millisecond)
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(color.Error, color.HiBlueString("signal received; shutting down"))
+		fmt.Fprintln(os.Stderr, "")
+		os.Exit(1)
+	}()
+}
+
+// SuspendInterrupt stops HandleInterrupt from receiving signals until resume is called
+func SuspendInterrupt() (resume func()) {
+	signal.Stop(interruptC)
+	return func() {
+		signal.Notify(interruptC, interruptSignals...)
+	}
+}
@@ -63,3 +63,19 @@ func ValidateSakuraID(fieldName string, object interface{}) []error {
 
 	return res
 }
+
+func ValidateIntRange(fieldName string, object interface{}, min int, max int) []error {
+	res := []error{}
+
+	// if target is nil , return OK(Use required attr if necessary)
+	if object == nil {
+		return res
+	}
+
+	if v, ok := object.(int); ok {
+		if v < min || max < v {
+			res = append(res, fmt.Errorf("%q: must be between %d and %d", fieldName, min, max))
+		}
+	}
+	return res
+}
@@ -1,14 +1,27 @@
 module github.com/sacloud/cloud-plan-migrate
 
+go 1.27.1
+
 require (
-	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fatih/color v1.7.0
 	github.com/mattn/go-colorable v0.0.9
 	github.com/mattn/go-isatty v0.0.4
-	github.com/mattn/go-runewidth v0.0.3 // indirect
+	github.com/mattn/go-runewidth v0.0.3
+	github.com/mattn/go-tty v0.0.0-20180219170247-931426f7535a
 	github.com/olekukonko/tablewriter v0.0.0-20180506121414-d4647c9c7a84
 	github.com/sacloud/libsacloud v1.27.1
 	github.com/stretchr/testify v1.2.2
-	golang.org/x/sys v0.0.0-20181022134430-8a28ead16f52 // indirect
 	gopkg.in/urfave/cli.v2 v2.0.0-20170215051800-04b2f4ff79cf
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/mitchellh/go-vnc v0.0.0-20150629162542-723ed9867aed // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/skratchdot/open-golang v0.0.0-20160302144031-75fb7ed4208c // indirect
+	github.com/uber-go/atomic v1.3.2 // indirect
+	go.uber.org/atomic v1.3.2 // indirect
+	go.uber.org/ratelimit v0.0.0-20180316092928-c15da0234277 // indirect
+	golang.org/x/crypto v0.0.0-20180621125126-a49355c7e3f8 // indirect
+	golang.org/x/sys v0.0.0-20181022134430-8a28ead16f52 // indirect
+)
@@ -7,18 +7,22 @@ import (
 
 type Client interface {
 	FindAll() ([]*sacloud.Server, error)
-	// Find(param *FindParameter) ([]*sacloud.Server, error)
+	Find(param *FindParameter) ([]*sacloud.Server, error)
 	ServerByID(id int64) (*sacloud.Server, error)
 	DiskByID(id int64) (*sacloud.Disk, error)
 	FindServerPlan(core int, memoryGB int) (*sacloud.ProductServer, error)
 
 	Shutdown(id int64) (err error)
 	DisconnectDisks(serverID int64) error
-	CloneDisk(id int64) (progress <-chan interface{}, err error)
+	CloneDisk(id int64, planID int64) (progress <-chan interface{}, err error)
 	ChangePlan(serverID int64, plan *sacloud.ProductServer) (*sacloud.Server, error)
 	ConnectDisks(serverID int64, diskIDs []int64) error
 	Boot(id int64) (err error)
 	DeleteDisk(id int64) error
+
+	FindTaggedResources() ([]*TaggedResource, error)
+	TaggedResourceByID(resourceType string, id int64) (*TaggedResource, error)
+	UpdateTaggedResource(resource *TaggedResource) error
 }
 
 type FindParameter struct {
@@ -36,22 +40,93 @@ type client struct {
 }
 
 func (c *client) FindAll() ([]*sacloud.Server, error) {
-	res, err := c.apiClient.Server.Reset().Limit(1000).Find()
+	res, err := c.findServers(nil)
 	if err != nil {
 		return nil, err
 	}
 	var servers []*sacloud.Server
-	for _, s := range res.Servers {
+	for _, s := range res {
 		if s.ServerPlan.Generation != sacloud.PlanG2 {
-			servers = append(servers, &s)
+			servers = append(servers, s)
 		}
 	}
 	return servers, nil
 }
 
-//func (c *client) Find(param *FindParameter) ([]*sacloud.Server, error) {
+// Find returns servers matched with any of Names or Ids, and having all of Tags
 //
-//}
+// If both Names and Ids are empty, all servers having Tags are returned.
+func (c *client) Find(param *FindParameter) ([]*sacloud.Server, error) {
+	if param == nil {
+		param = &FindParameter{}
+	}
+
+	withTags := func(serverAPI *api.ServerAPI) {
+		if len(param.Tags) > 0 {
+			serverAPI.SetTags(param.Tags)
+		}
+	}
+
+	var conditions []func(*api.ServerAPI)
+	if len(param.Ids) > 0 {
+		conditions = append(conditions, func(serverAPI *api.ServerAPI) {
+			for _, id := range param.Ids {
+				serverAPI.SetFilterMultiBy("ID", id)
+			}
+			withTags(serverAPI)
+		})
+	}
+	for _, name := range param.Names {
+		name := name
+		conditions = append(conditions, func(serverAPI *api.ServerAPI) {
+			serverAPI.SetNameLike(name)
+			withTags(serverAPI)
+		})
+	}
+	if len(conditions) == 0 {
+		conditions = append(conditions, withTags)
+	}
+
+	var servers []*sacloud.Server
+	found := map[int64]bool{}
+	for _, condition := range conditions {
+		res, err := c.findServers(condition)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range res {
+			if !found[s.ID] {
+				found[s.ID] = true
+				servers = append(servers, s)
+			}
+		}
+	}
+	return servers, nil
+}
+
+func (c *client) findServers(condition func(*api.ServerAPI)) ([]*sacloud.Server, error) {
+	serverAPI := c.apiClient.Clone().Server
+	var servers []*sacloud.Server
+
+	err := findAllPages(func(offset int) (int, int, error) {
+		serverAPI.Reset().Offset(offset).Limit(findPageSize)
+		if condition != nil {
+			condition(serverAPI)
+		}
+		res, err := serverAPI.Find()
+		if err != nil {
+			return 0, 0, err
+		}
+		for i := range res.Servers {
+			servers = append(servers, &res.Servers[i])
+		}
+		return len(res.Servers), res.Total, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
 
 func (c *client) ServerByID(id int64) (*sacloud.Server, error) {
 	return c.apiClient.Server.Read(id)
@@ -85,16 +160,18 @@ func (c *client) DisconnectDisks(serverID int64) error {
 	return nil
 }
 
-func (c *client) CloneDisk(id int64) (<-chan interface{}, error) {
+// CloneDisk creates new disk from the disk
+//
+// If planID is zero, the plan of the source disk is used.
+func (c *client) CloneDisk(id int64, planID int64) (<-chan interface{}, error) {
 	sourceDisk, err := c.DiskByID(id)
 	if err != nil {
 		return nil, err
 	}
-	// [HACK]: プランが標準プラン:20GBの場合、対応する標準プランが新プランに存在しないためSSDプランへ変更する。
-	planID := sourceDisk.GetPlanID()
-	if planID == int64(sacloud.DiskPlanHDDID) && sourceDisk.GetSizeGB() == 20 {
-		planID = int64(sacloud.DiskPlanSSDID)
+	if planID == 0 {
+		planID = sourceDisk.GetPlanID()
 	}
+	planID = ClonedDiskPlanID(planID, sourceDisk.GetSizeGB())
 
 	params := c.apiClient.Disk.New()
 	params.SetDescription(sourceDisk.Description)
@@ -140,6 +217,15 @@ func (c *client) CloneDisk(id int64) (<-chan interface{}, error) {
 	return progress, err
 }
 
+// ClonedDiskPlanID returns the plan ID of the disk created by CloneDisk
+func ClonedDiskPlanID(planID int64, sizeGB int) int64 {
+	// [HACK]: プランが標準プラン:20GBの場合、対応する標準プランが新プランに存在しないためSSDプランへ変更する。
+	if planID == int64(sacloud.DiskPlanHDDID) && sizeGB == 20 {
+		return int64(sacloud.DiskPlanSSDID)
+	}
+	return planID
+}
+
 func (c *client) ChangePlan(serverID int64, plan *sacloud.ProductServer) (*sacloud.Server, error) {
 	return c.apiClient.Server.ChangePlan(serverID, plan)
 }
@@ -0,0 +1,191 @@
+package iaas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sacloud/libsacloud/api"
+	"github.com/sacloud/libsacloud/sacloud"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeServerAPI serves the server search API paging by From/Count, and filtering by ID or Name
+type fakeServerAPI struct {
+	servers  []sacloud.Server
+	requests []*sacloud.Request
+	lock     sync.Mutex
+}
+
+func (f *fakeServerAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query, err := url.QueryUnescape(r.URL.RawQuery)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &sacloud.Request{}
+	if err := json.Unmarshal([]byte(query), req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.lock.Lock()
+	f.requests = append(f.requests, req)
+	f.lock.Unlock()
+
+	var matched []sacloud.Server
+	for _, s := range f.servers {
+		if f.match(&s, req.Filter) {
+			matched = append(matched, s)
+		}
+	}
+
+	// the API returns Servers even if empty
+	res := struct {
+		Total   int
+		From    int
+		Count   int
+		Servers []sacloud.Server
+	}{Total: len(matched), From: req.From, Servers: []sacloud.Server{}}
+	if req.From < len(matched) {
+		to := req.From + req.Count
+		if to > len(matched) {
+			to = len(matched)
+		}
+		res.Servers = matched[req.From:to]
+	}
+	res.Count = len(res.Servers)
+	data, _ := json.Marshal(res)
+	w.Write(data)
+}
+
+func (f *fakeServerAPI) match(server *sacloud.Server, filter map[string]interface{}) bool {
+	if ids, ok := filter["ID"].([]interface{}); ok {
+		for _, id := range ids {
+			if fmt.Sprintf("%v", id) == server.GetStrID() {
+				return true
+			}
+		}
+		return false
+	}
+	if name, ok := filter["Name"].(string); ok {
+		return strings.Contains(server.Name, name)
+	}
+	return true
+}
+
+func newFakeServerAPI(t *testing.T, count int) (*fakeServerAPI, *api.Client) {
+	fake := &fakeServerAPI{}
+	for i := 1; i <= count; i++ {
+		server := sacloud.Server{Resource: sacloud.NewResource(int64(i))}
+		server.Name = fmt.Sprintf("server%03d", i)
+		fake.servers = append(fake.servers, server)
+	}
+	httpServer := httptest.NewServer(fake)
+
+	apiRoot := api.SakuraCloudAPIRoot
+	api.SakuraCloudAPIRoot = httpServer.URL
+	t.Cleanup(func() {
+		api.SakuraCloudAPIRoot = apiRoot
+		httpServer.Close()
+	})
+	return fake, api.NewClient("token", "secret", "is1a")
+}
+
+func TestClient_Find(t *testing.T) {
+
+	ids := func(servers []*sacloud.Server) []int64 {
+		var ids []int64
+		for _, s := range servers {
+			ids = append(ids, s.ID)
+		}
+		return ids
+	}
+	froms := func(requests []*sacloud.Request) []int {
+		var froms []int
+		for _, r := range requests {
+			froms = append(froms, r.From)
+		}
+		return froms
+	}
+
+	t.Run("all pages", func(t *testing.T) {
+		fake, apiClient := newFakeServerAPI(t, 2*findPageSize+50)
+
+		servers, err := NewClient(apiClient).Find(nil)
+		assert.NoError(t, err)
+		assert.Len(t, servers, 2*findPageSize+50)
+		assert.Equal(t, int64(1), servers[0].ID)
+		assert.Equal(t, int64(2*findPageSize+50), servers[len(servers)-1].ID)
+
+		assert.Equal(t, []int{0, findPageSize, 2 * findPageSize}, froms(fake.requests))
+		for _, r := range fake.requests {
+			assert.Equal(t, findPageSize, r.Count)
+		}
+	})
+
+	t.Run("exact pages", func(t *testing.T) {
+		fake, apiClient := newFakeServerAPI(t, 2*findPageSize)
+
+		servers, err := NewClient(apiClient).Find(nil)
+		assert.NoError(t, err)
+		assert.Len(t, servers, 2*findPageSize)
+		assert.Equal(t, []int{0, findPageSize}, froms(fake.requests))
+	})
+
+	t.Run("conditions over pages", func(t *testing.T) {
+		fake, apiClient := newFakeServerAPI(t, 2*findPageSize+50)
+
+		// "server" matches all servers, each server is returned once
+		servers, err := NewClient(apiClient).Find(&FindParameter{Names: []string{"server10", "server"}, Ids: []int64{250, 1}})
+		assert.NoError(t, err)
+		assert.Len(t, servers, 2*findPageSize+50)
+		assert.Equal(t, []int64{1, 250, 100, 101}, ids(servers[:4]))
+
+		// ids: 1 page, server10: 1 page, server: 3 pages
+		assert.Equal(t, []int{0, 0, 0, findPageSize, 2 * findPageSize}, froms(fake.requests))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		fake, apiClient := newFakeServerAPI(t, 10)
+
+		servers, err := NewClient(apiClient).Find(&FindParameter{Names: []string{"unknown"}})
+		assert.NoError(t, err)
+		assert.Empty(t, servers)
+		assert.Len(t, fake.requests, 1)
+	})
+}
+
+func TestFindAllPages(t *testing.T) {
+
+	t.Run("stop when a page is empty", func(t *testing.T) {
+		var offsets []int
+		err := findAllPages(func(offset int) (int, int, error) {
+			offsets = append(offsets, offset)
+			if offset >= 150 {
+				return 0, 300, nil // total is changed during paging
+			}
+			return 50, 300, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{0, 50, 100, 150}, offsets)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var offsets []int
+		err := findAllPages(func(offset int) (int, int, error) {
+			offsets = append(offsets, offset)
+			if offset > 0 {
+				return 0, 0, fmt.Errorf("find failed")
+			}
+			return 100, 200, nil
+		})
+		assert.EqualError(t, err, "find failed")
+		assert.Equal(t, []int{0, 100}, offsets)
+	})
+}
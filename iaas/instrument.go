@@ -0,0 +1,187 @@
+package iaas
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/logging"
+)
+
+// DefaultSlowCallThreshold is the threshold of the slow call log
+const DefaultSlowCallThreshold = 10 * time.Second
+
+// Logger is the logger of slow calls
+//
+// If it also has Log(logging.Level, string, ...interface{}), slow calls are logged with key/value fields.
+type Logger interface {
+	Printf(string, ...interface{})
+}
+
+type structuredLogger interface {
+	Log(level logging.Level, msg string, keyvals ...interface{})
+}
+
+// InstrumentOptions is options of NewInstrumentedClient
+type InstrumentOptions struct {
+	// RequestsPerSecond limits method calls shared across all goroutines using the client, zero means unlimited
+	//
+	// Only calls of Client methods are limited, polling inside a method(e.g. waiting for the copy of CloneDisk) is not.
+	RequestsPerSecond float64
+
+	// SlowCallThreshold is the elapsed time to log the call as slow, zero means DefaultSlowCallThreshold and negative disables the log
+	SlowCallThreshold time.Duration
+
+	Logger Logger
+}
+
+// CallStats is the statistics of calls of a method
+type CallStats struct {
+	Method  string
+	Calls   int
+	Errors  int
+	Total   time.Duration
+	Max     time.Duration
+	Waiting time.Duration // total time waiting for the rate limit
+}
+
+// Average returns the average elapsed time of calls
+func (s *CallStats) Average() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+// InstrumentedClient is Client counting and timing method calls, limiting the rate of calls and logging slow calls
+type InstrumentedClient struct {
+	Client
+
+	options *InstrumentOptions
+	limiter *rateLimiter
+	stats   map[string]*CallStats
+	lock    sync.Mutex
+}
+
+// NewInstrumentedClient returns InstrumentedClient wrapping client
+func NewInstrumentedClient(client Client, options *InstrumentOptions) *InstrumentedClient {
+	if options == nil {
+		options = &InstrumentOptions{}
+	}
+	c := &InstrumentedClient{
+		options: options,
+		stats:   map[string]*CallStats{},
+	}
+	if options.RequestsPerSecond > 0 {
+		c.limiter = newRateLimiter(options.RequestsPerSecond)
+	}
+	c.Client = &observedClient{
+		client:    client,
+		observers: []Observer{c.observe},
+		before:    c.wait,
+	}
+	return c
+}
+
+// SetLogger sets the logger of slow calls
+func (c *InstrumentedClient) SetLogger(logger Logger) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.options.Logger = logger
+}
+
+// Stats returns statistics of calls sorted by method name
+func (c *InstrumentedClient) Stats() []*CallStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var stats []*CallStats
+	for _, s := range c.stats {
+		copied := *s
+		stats = append(stats, &copied)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Method < stats[j].Method })
+	return stats
+}
+
+func (c *InstrumentedClient) wait(method string) {
+	if c.limiter == nil {
+		return
+	}
+	if waited := c.limiter.wait(); waited > 0 {
+		c.lock.Lock()
+		c.methodStats(method).Waiting += waited
+		c.lock.Unlock()
+	}
+}
+
+func (c *InstrumentedClient) observe(method string, elapsed time.Duration, err error) {
+	c.lock.Lock()
+	s := c.methodStats(method)
+	s.Calls++
+	if err != nil {
+		s.Errors++
+	}
+	s.Total += elapsed
+	if elapsed > s.Max {
+		s.Max = elapsed
+	}
+	logger := c.options.Logger
+	c.lock.Unlock()
+
+	threshold := c.options.SlowCallThreshold
+	if threshold == 0 {
+		threshold = DefaultSlowCallThreshold
+	}
+	if threshold < 0 || elapsed < threshold || logger == nil {
+		return
+	}
+	switch logger := logger.(type) {
+	case structuredLogger:
+		keyvals := []interface{}{"method", method, "elapsed", elapsed.Seconds()}
+		if err != nil {
+			keyvals = append(keyvals, "error", err)
+		}
+		logger.Log(logging.LevelWarn, "Slow API call", keyvals...)
+	default:
+		logger.Printf(": Slow API call: %s (elapsed:%s)", method, elapsed)
+	}
+}
+
+// methodStats returns the stats of the method, the caller must hold the lock
+func (c *InstrumentedClient) methodStats(method string) *CallStats {
+	s, ok := c.stats[method]
+	if !ok {
+		s = &CallStats{Method: method}
+		c.stats[method] = s
+	}
+	return s
+}
+
+// rateLimiter spaces calls at the constant interval
+type rateLimiter struct {
+	interval time.Duration
+	next     time.Time
+	lock     sync.Mutex
+}
+
+func newRateLimiter(rps float64) *rateLimiter {
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / rps)}
+}
+
+// wait blocks until the next call is allowed and returns the waited time
+func (l *rateLimiter) wait() time.Duration {
+	l.lock.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	waiting := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.lock.Unlock()
+
+	if waiting > 0 {
+		time.Sleep(waiting)
+	}
+	return waiting
+}
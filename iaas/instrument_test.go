@@ -0,0 +1,132 @@
+package iaas
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/logging"
+	"github.com/sacloud/libsacloud/sacloud"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClient struct {
+	Client
+	delay time.Duration
+}
+
+func (f *fakeClient) ServerByID(id int64) (*sacloud.Server, error) {
+	time.Sleep(f.delay)
+	return &sacloud.Server{Resource: sacloud.NewResource(id)}, nil
+}
+
+func (f *fakeClient) Boot(id int64) error {
+	time.Sleep(f.delay)
+	return fmt.Errorf("boot failed")
+}
+
+type recordLogger struct {
+	entries []string
+	lock    sync.Mutex
+}
+
+func (l *recordLogger) Printf(format string, v ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.entries = append(l.entries, fmt.Sprintf(format, v...))
+}
+
+func (l *recordLogger) Log(level logging.Level, msg string, keyvals ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.entries = append(l.entries, fmt.Sprintf("%s %s %s", level, msg, logging.FormatFields(keyvals...)))
+}
+
+func TestInstrumentedClient_Stats(t *testing.T) {
+	client := NewInstrumentedClient(&fakeClient{}, nil)
+
+	server, err := client.ServerByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), server.ID)
+	client.ServerByID(2)
+	assert.EqualError(t, client.Boot(1), "boot failed")
+
+	stats := client.Stats()
+	assert.Len(t, stats, 2)
+	assert.Equal(t, "Boot", stats[0].Method)
+	assert.Equal(t, 1, stats[0].Calls)
+	assert.Equal(t, 1, stats[0].Errors)
+	assert.Equal(t, "ServerByID", stats[1].Method)
+	assert.Equal(t, 2, stats[1].Calls)
+	assert.Equal(t, 0, stats[1].Errors)
+	assert.True(t, stats[1].Max <= stats[1].Total)
+}
+
+func TestInstrumentedClient_RateLimit(t *testing.T) {
+	client := NewInstrumentedClient(&fakeClient{}, &InstrumentOptions{RequestsPerSecond: 50})
+
+	// 10 calls from 5 goroutines at 50rps take at least 9 intervals(180ms)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.ServerByID(1)
+			client.ServerByID(2)
+		}()
+	}
+	wg.Wait()
+
+	assert.True(t, time.Since(start) >= 180*time.Millisecond, "elapsed: %s", time.Since(start))
+	stats := client.Stats()
+	assert.Equal(t, 10, stats[0].Calls)
+	assert.True(t, stats[0].Waiting > 0)
+}
+
+func TestInstrumentedClient_SlowCall(t *testing.T) {
+	t.Run("structured", func(t *testing.T) {
+		logger := &recordLogger{}
+		client := NewInstrumentedClient(&fakeClient{delay: 20 * time.Millisecond}, &InstrumentOptions{
+			SlowCallThreshold: 10 * time.Millisecond,
+			Logger:            logger,
+		})
+		client.Boot(1)
+
+		assert.Len(t, logger.entries, 1)
+		assert.Contains(t, logger.entries[0], "warn Slow API call method=Boot elapsed=")
+		assert.Contains(t, logger.entries[0], `error="boot failed"`)
+	})
+
+	t.Run("printf", func(t *testing.T) {
+		logger := &printfLogger{}
+		client := NewInstrumentedClient(&fakeClient{delay: 20 * time.Millisecond}, &InstrumentOptions{
+			SlowCallThreshold: 10 * time.Millisecond,
+			Logger:            logger,
+		})
+		client.ServerByID(1)
+
+		assert.Len(t, logger.lines, 1)
+		assert.Contains(t, logger.lines[0], ": Slow API call: ServerByID")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		logger := &recordLogger{}
+		client := NewInstrumentedClient(&fakeClient{delay: 20 * time.Millisecond}, &InstrumentOptions{
+			SlowCallThreshold: -1,
+			Logger:            logger,
+		})
+		client.ServerByID(1)
+
+		assert.Empty(t, logger.entries)
+	})
+}
+
+type printfLogger struct {
+	lines []string
+}
+
+func (l *printfLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
@@ -0,0 +1,145 @@
+package iaas
+
+import (
+	"time"
+
+	"github.com/sacloud/libsacloud/sacloud"
+)
+
+// Observer is called after each method call of Client with the method name, elapsed time and error
+type Observer func(method string, elapsed time.Duration, err error)
+
+// NewObservedClient returns Client calling observers after each method call of client
+//
+// For CloneDisk, only the request to start cloning is observed, not the copy itself.
+func NewObservedClient(client Client, observers ...Observer) Client {
+	return &observedClient{client: client, observers: observers}
+}
+
+type observedClient struct {
+	client    Client
+	observers []Observer
+
+	// before is called before each method call, e.g. for rate limiting
+	before func(method string)
+}
+
+// begin calls before hook and returns the start time of the call
+func (c *observedClient) begin(method string) time.Time {
+	if c.before != nil {
+		c.before(method)
+	}
+	return time.Now()
+}
+
+func (c *observedClient) observe(method string, start time.Time, err error) {
+	elapsed := time.Since(start)
+	for _, o := range c.observers {
+		o(method, elapsed, err)
+	}
+}
+
+func (c *observedClient) FindAll() ([]*sacloud.Server, error) {
+	start := c.begin("FindAll")
+	servers, err := c.client.FindAll()
+	c.observe("FindAll", start, err)
+	return servers, err
+}
+
+func (c *observedClient) Find(param *FindParameter) ([]*sacloud.Server, error) {
+	start := c.begin("Find")
+	servers, err := c.client.Find(param)
+	c.observe("Find", start, err)
+	return servers, err
+}
+
+func (c *observedClient) ServerByID(id int64) (*sacloud.Server, error) {
+	start := c.begin("ServerByID")
+	server, err := c.client.ServerByID(id)
+	c.observe("ServerByID", start, err)
+	return server, err
+}
+
+func (c *observedClient) DiskByID(id int64) (*sacloud.Disk, error) {
+	start := c.begin("DiskByID")
+	disk, err := c.client.DiskByID(id)
+	c.observe("DiskByID", start, err)
+	return disk, err
+}
+
+func (c *observedClient) FindServerPlan(core int, memoryGB int) (*sacloud.ProductServer, error) {
+	start := c.begin("FindServerPlan")
+	plan, err := c.client.FindServerPlan(core, memoryGB)
+	c.observe("FindServerPlan", start, err)
+	return plan, err
+}
+
+func (c *observedClient) Shutdown(id int64) error {
+	start := c.begin("Shutdown")
+	err := c.client.Shutdown(id)
+	c.observe("Shutdown", start, err)
+	return err
+}
+
+func (c *observedClient) DisconnectDisks(serverID int64) error {
+	start := c.begin("DisconnectDisks")
+	err := c.client.DisconnectDisks(serverID)
+	c.observe("DisconnectDisks", start, err)
+	return err
+}
+
+func (c *observedClient) CloneDisk(id int64, planID int64) (<-chan interface{}, error) {
+	start := c.begin("CloneDisk")
+	progress, err := c.client.CloneDisk(id, planID)
+	c.observe("CloneDisk", start, err)
+	return progress, err
+}
+
+func (c *observedClient) ChangePlan(serverID int64, plan *sacloud.ProductServer) (*sacloud.Server, error) {
+	start := c.begin("ChangePlan")
+	server, err := c.client.ChangePlan(serverID, plan)
+	c.observe("ChangePlan", start, err)
+	return server, err
+}
+
+func (c *observedClient) ConnectDisks(serverID int64, diskIDs []int64) error {
+	start := c.begin("ConnectDisks")
+	err := c.client.ConnectDisks(serverID, diskIDs)
+	c.observe("ConnectDisks", start, err)
+	return err
+}
+
+func (c *observedClient) Boot(id int64) error {
+	start := c.begin("Boot")
+	err := c.client.Boot(id)
+	c.observe("Boot", start, err)
+	return err
+}
+
+func (c *observedClient) DeleteDisk(id int64) error {
+	start := c.begin("DeleteDisk")
+	err := c.client.DeleteDisk(id)
+	c.observe("DeleteDisk", start, err)
+	return err
+}
+
+func (c *observedClient) FindTaggedResources() ([]*TaggedResource, error) {
+	start := c.begin("FindTaggedResources")
+	resources, err := c.client.FindTaggedResources()
+	c.observe("FindTaggedResources", start, err)
+	return resources, err
+}
+
+func (c *observedClient) TaggedResourceByID(resourceType string, id int64) (*TaggedResource, error) {
+	start := c.begin("TaggedResourceByID")
+	resource, err := c.client.TaggedResourceByID(resourceType, id)
+	c.observe("TaggedResourceByID", start, err)
+	return resource, err
+}
+
+func (c *observedClient) UpdateTaggedResource(resource *TaggedResource) error {
+	start := c.begin("UpdateTaggedResource")
+	err := c.client.UpdateTaggedResource(resource)
+	c.observe("UpdateTaggedResource", start, err)
+	return err
+}
@@ -0,0 +1,265 @@
+package iaas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sacloud/libsacloud/sacloud"
+)
+
+// Redacted replaces secrets in recordings
+const Redacted = "[REDACTED]"
+
+// secretKeyPattern matches JSON keys whose values are redacted in recordings
+var secretKeyPattern = regexp.MustCompile(`(?i)(password|secret|token|private.?key|ssh.?key|api.?key)`)
+
+// Interaction is a recorded method call of Client
+type Interaction struct {
+	Seq    int             `json:"seq"`
+	Method string          `json:"method"`
+	Args   json.RawMessage `json:"args"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+
+	// Progress is the values sent to the progress channel of CloneDisk
+	Progress []*ProgressRecord `json:"progress,omitempty"`
+}
+
+// ProgressRecord is a recorded value of the progress channel of CloneDisk
+type ProgressRecord struct {
+	Disk  *sacloud.Disk `json:"disk,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+// RecordingClient is Client writing every method call to w as a JSON line
+//
+// Values of keys like password/secret/token and the literal secrets are replaced with Redacted.
+// The interaction of CloneDisk is written when the progress channel is closed.
+type RecordingClient struct {
+	client  Client
+	w       io.Writer
+	secrets []string
+	seq     int
+	lock    sync.Mutex
+	err     error
+}
+
+// NewRecordingClient returns RecordingClient wrapping client, secrets are the literal values to redact(e.g. API keys)
+func NewRecordingClient(client Client, w io.Writer, secrets ...string) *RecordingClient {
+	var nonEmpty []string
+	for _, s := range secrets {
+		if s != "" {
+			nonEmpty = append(nonEmpty, s)
+		}
+	}
+	return &RecordingClient{client: client, w: w, secrets: nonEmpty}
+}
+
+// Err returns the first error of writing recordings
+func (c *RecordingClient) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.err
+}
+
+func (c *RecordingClient) begin(method string, args ...interface{}) *Interaction {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.seq++
+	return &Interaction{Seq: c.seq, Method: method, Args: c.marshal(args)}
+}
+
+func (c *RecordingClient) finish(i *Interaction, result interface{}, err error) {
+	if result != nil {
+		i.Result = c.marshal(result)
+	}
+	if err != nil {
+		i.Error = c.redactString(err.Error())
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	data, e := json.Marshal(i)
+	if e == nil {
+		_, e = c.w.Write(append(data, '\n'))
+	}
+	if e != nil && c.err == nil {
+		c.err = e
+	}
+}
+
+func (c *RecordingClient) marshal(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprintf("(unmarshalable: %s)", err))
+	}
+	return redactJSON(json.RawMessage(c.redactString(string(data))))
+}
+
+func (c *RecordingClient) redactString(s string) string {
+	for _, secret := range c.secrets {
+		s = strings.Replace(s, secret, Redacted, -1)
+	}
+	return s
+}
+
+// redactJSON replaces values of secret keys
+func redactJSON(data json.RawMessage) json.RawMessage {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return data
+	}
+	redacted, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return data
+	}
+	return redacted
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if secretKeyPattern.MatchString(key) && value != nil && value != "" {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = redactValue(value)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return v
+}
+
+func (c *RecordingClient) FindAll() ([]*sacloud.Server, error) {
+	i := c.begin("FindAll")
+	servers, err := c.client.FindAll()
+	c.finish(i, servers, err)
+	return servers, err
+}
+
+func (c *RecordingClient) Find(param *FindParameter) ([]*sacloud.Server, error) {
+	i := c.begin("Find", param)
+	servers, err := c.client.Find(param)
+	c.finish(i, servers, err)
+	return servers, err
+}
+
+func (c *RecordingClient) ServerByID(id int64) (*sacloud.Server, error) {
+	i := c.begin("ServerByID", id)
+	server, err := c.client.ServerByID(id)
+	c.finish(i, server, err)
+	return server, err
+}
+
+func (c *RecordingClient) DiskByID(id int64) (*sacloud.Disk, error) {
+	i := c.begin("DiskByID", id)
+	disk, err := c.client.DiskByID(id)
+	c.finish(i, disk, err)
+	return disk, err
+}
+
+func (c *RecordingClient) FindServerPlan(core int, memoryGB int) (*sacloud.ProductServer, error) {
+	i := c.begin("FindServerPlan", core, memoryGB)
+	plan, err := c.client.FindServerPlan(core, memoryGB)
+	c.finish(i, plan, err)
+	return plan, err
+}
+
+func (c *RecordingClient) Shutdown(id int64) error {
+	i := c.begin("Shutdown", id)
+	err := c.client.Shutdown(id)
+	c.finish(i, nil, err)
+	return err
+}
+
+func (c *RecordingClient) DisconnectDisks(serverID int64) error {
+	i := c.begin("DisconnectDisks", serverID)
+	err := c.client.DisconnectDisks(serverID)
+	c.finish(i, nil, err)
+	return err
+}
+
+func (c *RecordingClient) CloneDisk(id int64, planID int64) (<-chan interface{}, error) {
+	i := c.begin("CloneDisk", id, planID)
+	progress, err := c.client.CloneDisk(id, planID)
+	if err != nil {
+		c.finish(i, nil, err)
+		return progress, err
+	}
+
+	recorded := make(chan interface{})
+	go func() {
+		defer close(recorded)
+		for p := range progress {
+			switch v := p.(type) {
+			case *sacloud.Disk:
+				i.Progress = append(i.Progress, &ProgressRecord{Disk: v})
+			case error:
+				i.Progress = append(i.Progress, &ProgressRecord{Error: c.redactString(v.Error())})
+			}
+			recorded <- p
+		}
+		c.finish(i, nil, nil)
+	}()
+	return recorded, nil
+}
+
+func (c *RecordingClient) ChangePlan(serverID int64, plan *sacloud.ProductServer) (*sacloud.Server, error) {
+	i := c.begin("ChangePlan", serverID, plan)
+	server, err := c.client.ChangePlan(serverID, plan)
+	c.finish(i, server, err)
+	return server, err
+}
+
+func (c *RecordingClient) ConnectDisks(serverID int64, diskIDs []int64) error {
+	i := c.begin("ConnectDisks", serverID, diskIDs)
+	err := c.client.ConnectDisks(serverID, diskIDs)
+	c.finish(i, nil, err)
+	return err
+}
+
+func (c *RecordingClient) Boot(id int64) error {
+	i := c.begin("Boot", id)
+	err := c.client.Boot(id)
+	c.finish(i, nil, err)
+	return err
+}
+
+func (c *RecordingClient) DeleteDisk(id int64) error {
+	i := c.begin("DeleteDisk", id)
+	err := c.client.DeleteDisk(id)
+	c.finish(i, nil, err)
+	return err
+}
+
+func (c *RecordingClient) FindTaggedResources() ([]*TaggedResource, error) {
+	i := c.begin("FindTaggedResources")
+	resources, err := c.client.FindTaggedResources()
+	c.finish(i, resources, err)
+	return resources, err
+}
+
+func (c *RecordingClient) TaggedResourceByID(resourceType string, id int64) (*TaggedResource, error) {
+	i := c.begin("TaggedResourceByID", resourceType, id)
+	resource, err := c.client.TaggedResourceByID(resourceType, id)
+	c.finish(i, resource, err)
+	return resource, err
+}
+
+func (c *RecordingClient) UpdateTaggedResource(resource *TaggedResource) error {
+	i := c.begin("UpdateTaggedResource", resource)
+	err := c.client.UpdateTaggedResource(resource)
+	c.finish(i, nil, err)
+	return err
+}
@@ -0,0 +1,127 @@
+package iaas
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sacloud/libsacloud/sacloud"
+	"github.com/stretchr/testify/assert"
+)
+
+type taggedResourceClient struct {
+	Client
+	resource *TaggedResource
+}
+
+func (c *taggedResourceClient) TaggedResourceByID(resourceType string, id int64) (*TaggedResource, error) {
+	return c.resource, nil
+}
+
+func (c *taggedResourceClient) UpdateTaggedResource(resource *TaggedResource) error {
+	return nil
+}
+
+func TestRecordingClient(t *testing.T) {
+	buf := new(bytes.Buffer)
+	client := NewRecordingClient(&fakeClient{}, buf, "my-api-token")
+
+	server, err := client.ServerByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), server.ID)
+	assert.EqualError(t, client.Boot(1), "boot failed")
+	assert.NoError(t, client.Err())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var interactions []*Interaction
+	for _, l := range lines {
+		i := &Interaction{}
+		assert.NoError(t, json.Unmarshal([]byte(l), i))
+		interactions = append(interactions, i)
+	}
+	assert.Equal(t, 1, interactions[0].Seq)
+	assert.Equal(t, "ServerByID", interactions[0].Method)
+	assert.JSONEq(t, `[1]`, string(interactions[0].Args))
+	assert.Equal(t, "Boot", interactions[1].Method)
+	assert.Equal(t, "boot failed", interactions[1].Error)
+}
+
+func TestRecordingClient_Redact(t *testing.T) {
+	buf := new(bytes.Buffer)
+	resource := &TaggedResource{
+		Type:        ResourceTypeServer,
+		ID:          1,
+		Description: "token=my-api-token",
+	}
+	client := NewRecordingClient(&taggedResourceClient{resource: resource}, buf, "my-api-token", "")
+	client.UpdateTaggedResource(resource)
+
+	assert.NotContains(t, buf.String(), "my-api-token")
+	assert.Contains(t, buf.String(), "token="+Redacted)
+
+	data := redactJSON([]byte(`{"Password":"p@ss","Nested":[{"SSHKey":"ssh-rsa AAA","Name":"web"}],"Token":""}`))
+	assert.JSONEq(t, `{"Password":"[REDACTED]","Nested":[{"SSHKey":"[REDACTED]","Name":"web"}],"Token":""}`, string(data))
+}
+
+func TestReplayClient(t *testing.T) {
+	buf := new(bytes.Buffer)
+	recorder := NewRecordingClient(&fakeClient{}, buf)
+	recorder.ServerByID(1)
+	recorder.ServerByID(2)
+	recorder.Boot(1)
+
+	replay, err := NewReplayClient(buf)
+	assert.NoError(t, err)
+
+	// the order of calls with different arguments can be changed
+	server, err := replay.ServerByID(2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), server.ID)
+	assert.EqualError(t, replay.Boot(1), "boot failed")
+
+	_, err = replay.ServerByID(3)
+	assert.EqualError(t, err, "no recorded interaction for ServerByID[3]")
+
+	unused := replay.Unused()
+	assert.Len(t, unused, 1)
+	assert.JSONEq(t, `[1]`, string(unused[0].Args))
+
+	server, err = replay.ServerByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), server.ID)
+
+	// each interaction is replayed once
+	_, err = replay.ServerByID(1)
+	assert.Error(t, err)
+}
+
+func TestReplayClient_CloneDisk(t *testing.T) {
+	disk := &sacloud.Disk{Resource: sacloud.NewResource(11)}
+	disk.MigratedMB = 1024
+	data, err := json.Marshal(&Interaction{
+		Seq:      1,
+		Method:   "CloneDisk",
+		Args:     json.RawMessage(`[10,4]`),
+		Progress: []*ProgressRecord{{Disk: disk}, {Error: "copy failed"}},
+	})
+	assert.NoError(t, err)
+
+	replay, err := NewReplayClient(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	progress, err := replay.CloneDisk(10, 4)
+	assert.NoError(t, err)
+
+	var values []interface{}
+	for p := range progress {
+		values = append(values, p)
+	}
+	assert.Len(t, values, 2)
+	replayed := values[0].(*sacloud.Disk)
+	assert.Equal(t, int64(11), replayed.ID)
+	assert.Equal(t, 1024, replayed.MigratedMB)
+	assert.EqualError(t, values[1].(error), "copy failed")
+}
@@ -0,0 +1,192 @@
+package iaas
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/sacloud/libsacloud/sacloud"
+)
+
+// ReplayClient is Client serving interactions recorded by RecordingClient
+//
+// Each call returns the first unused interaction with the same method and arguments,
+// so the calls can be reordered by goroutines but must be the same set as recorded.
+type ReplayClient struct {
+	interactions []*Interaction
+	used         []bool
+	lock         sync.Mutex
+}
+
+// NewReplayClient returns ReplayClient serving interactions read from r
+func NewReplayClient(r io.Reader) (*ReplayClient, error) {
+	var interactions []*Interaction
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		i := &Interaction{}
+		if err := json.Unmarshal(scanner.Bytes(), i); err != nil {
+			return nil, fmt.Errorf("invalid recording at line %d: %s", line, err)
+		}
+		interactions = append(interactions, i)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(interactions, func(i, j int) bool { return interactions[i].Seq < interactions[j].Seq })
+	return &ReplayClient{interactions: interactions, used: make([]bool, len(interactions))}, nil
+}
+
+// Unused returns interactions not replayed yet
+func (c *ReplayClient) Unused() []*Interaction {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var unused []*Interaction
+	for i, interaction := range c.interactions {
+		if !c.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+// replay finds the interaction, decodes the result into result and returns the recorded error
+func (c *ReplayClient) replay(result interface{}, method string, args ...interface{}) (*Interaction, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	key := redactJSON(data)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i, interaction := range c.interactions {
+		if c.used[i] || interaction.Method != method || !jsonEqual(interaction.Args, key) {
+			continue
+		}
+		c.used[i] = true
+		if result != nil && len(interaction.Result) > 0 {
+			if err := json.Unmarshal(interaction.Result, result); err != nil {
+				return nil, fmt.Errorf("invalid recorded result of %s%s: %s", method, key, err)
+			}
+		}
+		if interaction.Error != "" {
+			return interaction, errors.New(interaction.Error)
+		}
+		return interaction, nil
+	}
+	return nil, fmt.Errorf("no recorded interaction for %s%s", method, key)
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	ca, _ := json.Marshal(va)
+	cb, _ := json.Marshal(vb)
+	return bytes.Equal(ca, cb)
+}
+
+func (c *ReplayClient) FindAll() ([]*sacloud.Server, error) {
+	var servers []*sacloud.Server
+	_, err := c.replay(&servers, "FindAll")
+	return servers, err
+}
+
+func (c *ReplayClient) Find(param *FindParameter) ([]*sacloud.Server, error) {
+	var servers []*sacloud.Server
+	_, err := c.replay(&servers, "Find", param)
+	return servers, err
+}
+
+func (c *ReplayClient) ServerByID(id int64) (*sacloud.Server, error) {
+	var server *sacloud.Server
+	_, err := c.replay(&server, "ServerByID", id)
+	return server, err
+}
+
+func (c *ReplayClient) DiskByID(id int64) (*sacloud.Disk, error) {
+	var disk *sacloud.Disk
+	_, err := c.replay(&disk, "DiskByID", id)
+	return disk, err
+}
+
+func (c *ReplayClient) FindServerPlan(core int, memoryGB int) (*sacloud.ProductServer, error) {
+	var plan *sacloud.ProductServer
+	_, err := c.replay(&plan, "FindServerPlan", core, memoryGB)
+	return plan, err
+}
+
+func (c *ReplayClient) Shutdown(id int64) error {
+	_, err := c.replay(nil, "Shutdown", id)
+	return err
+}
+
+func (c *ReplayClient) DisconnectDisks(serverID int64) error {
+	_, err := c.replay(nil, "DisconnectDisks", serverID)
+	return err
+}
+
+func (c *ReplayClient) CloneDisk(id int64, planID int64) (<-chan interface{}, error) {
+	interaction, err := c.replay(nil, "CloneDisk", id, planID)
+	if err != nil {
+		return nil, err
+	}
+	progress := make(chan interface{}, len(interaction.Progress))
+	for _, p := range interaction.Progress {
+		if p.Error != "" {
+			progress <- errors.New(p.Error)
+		} else {
+			progress <- p.Disk
+		}
+	}
+	close(progress)
+	return progress, nil
+}
+
+func (c *ReplayClient) ChangePlan(serverID int64, plan *sacloud.ProductServer) (*sacloud.Server, error) {
+	var server *sacloud.Server
+	_, err := c.replay(&server, "ChangePlan", serverID, plan)
+	return server, err
+}
+
+func (c *ReplayClient) ConnectDisks(serverID int64, diskIDs []int64) error {
+	_, err := c.replay(nil, "ConnectDisks", serverID, diskIDs)
+	return err
+}
+
+func (c *ReplayClient) Boot(id int64) error {
+	_, err := c.replay(nil, "Boot", id)
+	return err
+}
+
+func (c *ReplayClient) DeleteDisk(id int64) error {
+	_, err := c.replay(nil, "DeleteDisk", id)
+	return err
+}
+
+func (c *ReplayClient) FindTaggedResources() ([]*TaggedResource, error) {
+	var resources []*TaggedResource
+	_, err := c.replay(&resources, "FindTaggedResources")
+	return resources, err
+}
+
+func (c *ReplayClient) TaggedResourceByID(resourceType string, id int64) (*TaggedResource, error) {
+	var resource *TaggedResource
+	_, err := c.replay(&resource, "TaggedResourceByID", resourceType, id)
+	return resource, err
+}
+
+func (c *ReplayClient) UpdateTaggedResource(resource *TaggedResource) error {
+	_, err := c.replay(nil, "UpdateTaggedResource", resource)
+	return err
+}
@@ -0,0 +1,178 @@
+package iaas
+
+import (
+	"fmt"
+)
+
+const (
+	ResourceTypeServer        = "server"
+	ResourceTypeDisk          = "disk"
+	ResourceTypeSwitch        = "switch"
+	ResourceTypeSimpleMonitor = "simple-monitor"
+)
+
+const findPageSize = 100
+
+// TaggedResource is a resource that has description and tags
+type TaggedResource struct {
+	Type        string
+	ID          int64
+	Name        string
+	Description string
+	Tags        []string
+}
+
+func (c *client) FindTaggedResources() ([]*TaggedResource, error) {
+	apiClient := c.apiClient.Clone()
+	var resources []*TaggedResource
+
+	servers, err := c.findServers(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range servers {
+		resources = append(resources, &TaggedResource{
+			Type: ResourceTypeServer, ID: s.ID, Name: s.Name, Description: s.Description, Tags: s.Tags,
+		})
+	}
+
+	err = findAllPages(func(offset int) (int, int, error) {
+		res, err := apiClient.Disk.Reset().Offset(offset).Limit(findPageSize).Find()
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, d := range res.Disks {
+			resources = append(resources, &TaggedResource{
+				Type: ResourceTypeDisk, ID: d.ID, Name: d.Name, Description: d.Description, Tags: d.Tags,
+			})
+		}
+		return len(res.Disks), res.Total, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = findAllPages(func(offset int) (int, int, error) {
+		res, err := apiClient.Switch.Reset().Offset(offset).Limit(findPageSize).Find()
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, s := range res.Switches {
+			resources = append(resources, &TaggedResource{
+				Type: ResourceTypeSwitch, ID: s.ID, Name: s.Name, Description: s.Description, Tags: s.Tags,
+			})
+		}
+		return len(res.Switches), res.Total, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = findAllPages(func(offset int) (int, int, error) {
+		res, err := apiClient.SimpleMonitor.Reset().Offset(offset).Limit(findPageSize).Find()
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, s := range res.SimpleMonitors {
+			resources = append(resources, &TaggedResource{
+				Type: ResourceTypeSimpleMonitor, ID: s.ID, Name: s.Name, Description: s.Description, Tags: s.Tags,
+			})
+		}
+		return len(res.SimpleMonitors), res.Total, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+func (c *client) TaggedResourceByID(resourceType string, id int64) (*TaggedResource, error) {
+	r := &TaggedResource{Type: resourceType, ID: id}
+	switch resourceType {
+	case ResourceTypeServer:
+		s, err := c.apiClient.Server.Read(id)
+		if err != nil {
+			return nil, err
+		}
+		r.Name, r.Description, r.Tags = s.Name, s.Description, s.Tags
+	case ResourceTypeDisk:
+		d, err := c.apiClient.Disk.Read(id)
+		if err != nil {
+			return nil, err
+		}
+		r.Name, r.Description, r.Tags = d.Name, d.Description, d.Tags
+	case ResourceTypeSwitch:
+		s, err := c.apiClient.Switch.Read(id)
+		if err != nil {
+			return nil, err
+		}
+		r.Name, r.Description, r.Tags = s.Name, s.Description, s.Tags
+	case ResourceTypeSimpleMonitor:
+		s, err := c.apiClient.SimpleMonitor.Read(id)
+		if err != nil {
+			return nil, err
+		}
+		r.Name, r.Description, r.Tags = s.Name, s.Description, s.Tags
+	default:
+		return nil, fmt.Errorf("unsupported resource type: %q", resourceType)
+	}
+	return r, nil
+}
+
+func (c *client) UpdateTaggedResource(resource *TaggedResource) error {
+	switch resource.Type {
+	case ResourceTypeServer:
+		s, err := c.apiClient.Server.Read(resource.ID)
+		if err != nil {
+			return err
+		}
+		s.SetDescription(resource.Description)
+		s.SetTags(resource.Tags)
+		_, err = c.apiClient.Server.Update(resource.ID, s)
+		return err
+	case ResourceTypeDisk:
+		d, err := c.apiClient.Disk.Read(resource.ID)
+		if err != nil {
+			return err
+		}
+		d.SetDescription(resource.Description)
+		d.SetTags(resource.Tags)
+		_, err = c.apiClient.Disk.Update(resource.ID, d)
+		return err
+	case ResourceTypeSwitch:
+		s, err := c.apiClient.Switch.Read(resource.ID)
+		if err != nil {
+			return err
+		}
+		s.SetDescription(resource.Description)
+		s.SetTags(resource.Tags)
+		_, err = c.apiClient.Switch.Update(resource.ID, s)
+		return err
+	case ResourceTypeSimpleMonitor:
+		s, err := c.apiClient.SimpleMonitor.Read(resource.ID)
+		if err != nil {
+			return err
+		}
+		s.SetDescription(resource.Description)
+		s.SetTags(resource.Tags)
+		_, err = c.apiClient.SimpleMonitor.Update(resource.ID, s)
+		return err
+	}
+	return fmt.Errorf("unsupported resource type: %q", resource.Type)
+}
+
+// findAllPages calls find with increasing offset until all pages are fetched
+func findAllPages(find func(offset int) (count int, total int, err error)) error {
+	offset := 0
+	for {
+		count, total, err := find(offset)
+		if err != nil {
+			return err
+		}
+		offset += count
+		if count == 0 || offset >= total {
+			return nil
+		}
+	}
+}
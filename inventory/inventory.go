@@ -0,0 +1,218 @@
+package inventory
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Entry is a row of the inventory file
+//
+// Zero values or nil mean that the global option is used.
+type Entry struct {
+	Line        int    `json:"-"` // line number in the CSV file
+	Index       int    `json:"-"` // 1-based index of the entry in the JSON array
+	ServerID    int64  `json:"server_id"`
+	Wave        string `json:"wave"`
+	Core        int    `json:"core"`
+	MemoryGB    int    `json:"memory"`
+	CleanupDisk *bool  `json:"cleanup_disk"`
+	Boot        *bool  `json:"boot"`
+	PreClone    *bool  `json:"pre_clone"`
+}
+
+// Position returns the position of the entry in the file for messages, "line N" for CSV or "entry N" for JSON
+func (e *Entry) Position() string {
+	if e.Index > 0 {
+		return fmt.Sprintf("entry %d", e.Index)
+	}
+	return fmt.Sprintf("line %d", e.Line)
+}
+
+var csvColumns = []string{"server_id", "wave", "core", "memory", "cleanup_disk", "boot", "pre_clone"}
+
+// ReadFile reads the inventory file, format is detected from the file extension
+func ReadFile(path string) ([]*Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	return Read(f, format)
+}
+
+// Read reads the inventory in CSV or JSON format
+func Read(r io.Reader, format string) ([]*Entry, error) {
+	var entries []*Entry
+	var err error
+	switch format {
+	case FormatCSV:
+		entries, err = readCSV(r)
+	case FormatJSON:
+		entries, err = readJSON(r)
+	default:
+		return nil, fmt.Errorf("unsupported inventory format: %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validate(entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// FilterByWave returns entries of the wave
+func FilterByWave(entries []*Entry, wave string) []*Entry {
+	var res []*Entry
+	for _, e := range entries {
+		if e.Wave == wave {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func readCSV(r io.Reader) ([]*Entry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header is failed: %s", err)
+	}
+	index := map[string]int{}
+	for i, h := range header {
+		index[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := index["server_id"]; !ok {
+		return nil, fmt.Errorf("%q column is required", "server_id")
+	}
+	for key := range index {
+		if !inStrings(key, csvColumns) {
+			return nil, fmt.Errorf("unknown column: %q", key)
+		}
+	}
+
+	var entries []*Entry
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		value := func(column string) string {
+			if i, ok := index[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		e := &Entry{Line: line, Wave: value("wave")}
+		if e.ServerID, err = parseInt64(value("server_id")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid server_id: %s", line, err)
+		}
+		if e.Core, err = parseInt(value("core")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid core: %s", line, err)
+		}
+		if e.MemoryGB, err = parseInt(value("memory")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid memory: %s", line, err)
+		}
+		if e.CleanupDisk, err = parseBool(value("cleanup_disk")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid cleanup_disk: %s", line, err)
+		}
+		if e.Boot, err = parseBool(value("boot")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid boot: %s", line, err)
+		}
+		if e.PreClone, err = parseBool(value("pre_clone")); err != nil {
+			return nil, fmt.Errorf("line %d: invalid pre_clone: %s", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func readJSON(r io.Reader) ([]*Entry, error) {
+	var entries []*Entry
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&entries); err != nil {
+		return nil, err
+	}
+	for i, e := range entries {
+		e.Index = i + 1
+	}
+	return entries, nil
+}
+
+func validate(entries []*Entry) error {
+	if len(entries) == 0 {
+		return fmt.Errorf("inventory is empty")
+	}
+
+	positions := map[int64]string{}
+	for _, e := range entries {
+		if e.ServerID <= 0 {
+			return fmt.Errorf("%s: server_id is required", e.Position())
+		}
+		if position, ok := positions[e.ServerID]; ok {
+			return fmt.Errorf("%s: server_id %d is duplicated with %s", e.Position(), e.ServerID, position)
+		}
+		positions[e.ServerID] = e.Position()
+
+		if (e.Core == 0) != (e.MemoryGB == 0) {
+			return fmt.Errorf("%s: both core and memory are required to override the plan", e.Position())
+		}
+	}
+	return nil
+}
+
+func parseInt64(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+func parseInt(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
+
+func parseBool(v string) (*bool, error) {
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func inStrings(v string, list []string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
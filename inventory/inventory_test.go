@@ -0,0 +1,62 @@
+package inventory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRead(t *testing.T) {
+
+	yes, no := true, false
+
+	t.Run("csv", func(t *testing.T) {
+		src := `server_id,wave,core,memory,cleanup_disk,boot,pre_clone
+113000000001,1,,,true,,true
+113000000002,1,2,4,,false
+113000000003,2
+`
+		entries, err := Read(strings.NewReader(src), FormatCSV)
+		assert.NoError(t, err)
+		assert.Equal(t, []*Entry{
+			{Line: 2, ServerID: 113000000001, Wave: "1", CleanupDisk: &yes, PreClone: &yes},
+			{Line: 3, ServerID: 113000000002, Wave: "1", Core: 2, MemoryGB: 4, Boot: &no},
+			{Line: 4, ServerID: 113000000003, Wave: "2"},
+		}, entries)
+
+		assert.Len(t, FilterByWave(entries, "1"), 2)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		src := `[
+  {"server_id": 113000000001, "wave": "1", "cleanup_disk": true},
+  {"server_id": 113000000002, "core": 2, "memory": 4, "boot": false}
+]`
+		entries, err := Read(strings.NewReader(src), FormatJSON)
+		assert.NoError(t, err)
+		assert.Equal(t, []*Entry{
+			{Index: 1, ServerID: 113000000001, Wave: "1", CleanupDisk: &yes},
+			{Index: 2, ServerID: 113000000002, Core: 2, MemoryGB: 4, Boot: &no},
+		}, entries)
+		assert.Equal(t, "entry 2", entries[1].Position())
+
+		_, err = Read(strings.NewReader(`[{"server_id": 1}, {"server_id": 1}]`), FormatJSON)
+		assert.EqualError(t, err, "entry 2: server_id 1 is duplicated with entry 1")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		sources := []string{
+			"wave\n1\n",
+			"server_id,unknown\n113000000001,1\n",
+			"server_id,boot\n113000000001,maybe\n",
+			"server_id,core\n113000000001,2\n",
+			"server_id\n113000000001\n113000000001\n",
+			"server_id\n",
+		}
+		for _, src := range sources {
+			_, err := Read(strings.NewReader(src), FormatCSV)
+			assert.Error(t, err, src)
+		}
+	})
+}
@@ -0,0 +1,235 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of the log
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses the level name(debug/info/warn/error)
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("invalid log level %q: must be one of %s", s, strings.Join(levelNames, "/"))
+}
+
+// Format is the output format of the log
+type Format string
+
+const (
+	FormatText   Format = "text"
+	FormatLogfmt Format = "logfmt"
+	FormatJSON   Format = "json"
+)
+
+// Formats is the list of supported formats
+var Formats = []Format{FormatText, FormatLogfmt, FormatJSON}
+
+// ParseFormat parses the format name(text/logfmt/json)
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	return FormatText, fmt.Errorf("invalid log format %q: must be one of text/logfmt/json", s)
+}
+
+// Redacted replaces secrets in logs
+const Redacted = "[REDACTED]"
+
+// Logger writes leveled logs with key/value fields to sinks
+//
+// Logger implements migrate.Logger and migrate.StructuredLogger.
+type Logger struct {
+	Level  Level
+	Format Format
+	Sinks  []Sink
+
+	// Secrets are replaced with Redacted in every line(e.g. API keys)
+	Secrets []string
+
+	// now is replaced in tests
+	now  func() time.Time
+	lock sync.Mutex
+}
+
+// New returns Logger writing to sinks
+func New(level Level, format Format, sinks ...Sink) *Logger {
+	return &Logger{Level: level, Format: format, Sinks: sinks, now: time.Now}
+}
+
+// Log writes the message with key/value pairs(key1, value1, key2, value2, ...)
+func (l *Logger) Log(level Level, msg string, keyvals ...interface{}) {
+	if l == nil || level < l.Level {
+		return
+	}
+	now := time.Now
+	if l.now != nil {
+		now = l.now
+	}
+	line := l.format(now(), level, msg, keyvals)
+	for _, s := range l.Secrets {
+		if s != "" {
+			line = bytes.Replace(line, []byte(s), []byte(Redacted), -1)
+		}
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, s := range l.Sinks {
+		s.Write(level, line) // errors of sinks are ignored so as not to stop the migration
+	}
+}
+
+// Printf writes the message at info level, for compatibility with log.Logger
+func (l *Logger) Printf(format string, v ...interface{}) {
+	l.Log(LevelInfo, strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) { l.Log(LevelDebug, msg, keyvals...) }
+func (l *Logger) Info(msg string, keyvals ...interface{})  { l.Log(LevelInfo, msg, keyvals...) }
+func (l *Logger) Warn(msg string, keyvals ...interface{})  { l.Log(LevelWarn, msg, keyvals...) }
+func (l *Logger) Error(msg string, keyvals ...interface{}) { l.Log(LevelError, msg, keyvals...) }
+
+// Close closes sinks
+func (l *Logger) Close() error {
+	var errs []string
+	for _, s := range l.Sinks {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("closing log is failed: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (l *Logger) format(t time.Time, level Level, msg string, keyvals []interface{}) []byte {
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, "(missing)")
+	}
+	buf := new(bytes.Buffer)
+
+	switch l.Format {
+	case FormatJSON:
+		buf.WriteString("{")
+		writeJSONPair(buf, "time", t.Format(time.RFC3339))
+		buf.WriteString(",")
+		writeJSONPair(buf, "level", level.String())
+		buf.WriteString(",")
+		writeJSONPair(buf, "msg", msg)
+		for i := 0; i < len(keyvals); i += 2 {
+			buf.WriteString(",")
+			writeJSONPair(buf, fmt.Sprint(keyvals[i]), jsonValue(keyvals[i+1]))
+		}
+		buf.WriteString("}")
+	case FormatLogfmt:
+		writeLogfmtPair(buf, "time", t.Format(time.RFC3339))
+		buf.WriteString(" ")
+		writeLogfmtPair(buf, "level", level.String())
+		buf.WriteString(" ")
+		writeLogfmtPair(buf, "msg", msg)
+		for i := 0; i < len(keyvals); i += 2 {
+			buf.WriteString(" ")
+			writeLogfmtPair(buf, fmt.Sprint(keyvals[i]), keyvals[i+1])
+		}
+	default:
+		fmt.Fprintf(buf, "%s %-5s %s", t.Format("2006/01/02 15:04:05"), strings.ToUpper(level.String()), msg)
+		for i := 0; i < len(keyvals); i += 2 {
+			buf.WriteString(" ")
+			writeLogfmtPair(buf, fmt.Sprint(keyvals[i]), keyvals[i+1])
+		}
+	}
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+// FormatFields returns key/value pairs in logfmt
+func FormatFields(keyvals ...interface{}) string {
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, "(missing)")
+	}
+	buf := new(bytes.Buffer)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteString(" ")
+		}
+		writeLogfmtPair(buf, fmt.Sprint(keyvals[i]), keyvals[i+1])
+	}
+	return buf.String()
+}
+
+func writeJSONPair(buf *bytes.Buffer, key string, value interface{}) {
+	k, _ := json.Marshal(key)
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(k)
+	buf.WriteString(":")
+	buf.Write(v)
+}
+
+// jsonValue converts values which are not meaningful in JSON(error, Duration, Stringer) to strings
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.Seconds()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return v
+}
+
+func writeLogfmtPair(buf *bytes.Buffer, key string, value interface{}) {
+	buf.WriteString(key)
+	buf.WriteString("=")
+
+	var s string
+	switch v := value.(type) {
+	case nil:
+		s = "null"
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	case time.Duration:
+		s = strconv.FormatFloat(v.Seconds(), 'f', -1, 64)
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		s = strconv.Quote(s)
+	}
+	buf.WriteString(s)
+}
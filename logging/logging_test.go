@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testLogger(level Level, format Format) (*Logger, *bytes.Buffer) {
+	buf := new(bytes.Buffer)
+	l := New(level, format, &WriterSink{Writer: buf})
+	l.now = func() time.Time {
+		return time.Date(2026, 10, 20, 1, 2, 3, 0, time.UTC)
+	}
+	return l, buf
+}
+
+func TestLogger_Log(t *testing.T) {
+	cases := []struct {
+		format Format
+		expect string
+	}{
+		{
+			format: FormatText,
+			expect: `2026/10/20 01:02:03 ERROR Clone Disk error server_id=1 step=clone_disk elapsed=12 error="disk is not found"`,
+		},
+		{
+			format: FormatLogfmt,
+			expect: `time=2026-10-20T01:02:03Z level=error msg="Clone Disk error" server_id=1 step=clone_disk elapsed=12 error="disk is not found"`,
+		},
+		{
+			format: FormatJSON,
+			expect: `{"time":"2026-10-20T01:02:03Z","level":"error","msg":"Clone Disk error","server_id":1,"step":"clone_disk","elapsed":12,"error":"disk is not found"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(string(tc.format), func(t *testing.T) {
+			l, buf := testLogger(LevelInfo, tc.format)
+			l.Error("Clone Disk error", "server_id", 1, "step", "clone_disk", "elapsed", 12, "error", fmt.Errorf("disk is not found"))
+			assert.Equal(t, tc.expect+"\n", buf.String())
+		})
+	}
+}
+
+func TestLogger_Level(t *testing.T) {
+	l, buf := testLogger(LevelWarn, FormatLogfmt)
+
+	l.Debug("debug")
+	l.Info("info")
+	l.Printf(": printf%s", "\n")
+	l.Warn("warn")
+	l.Error("error")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "level=warn msg=warn")
+	assert.Contains(t, lines[1], "level=error msg=error")
+}
+
+func TestLogger_Secrets(t *testing.T) {
+	l, buf := testLogger(LevelInfo, FormatText)
+	l.Secrets = []string{"my-secret", ""}
+
+	l.Error("API call is failed", "error", fmt.Errorf("invalid key: my-secret"))
+	l.Printf(": token is my-secret")
+
+	assert.NotContains(t, buf.String(), "my-secret")
+	assert.Contains(t, buf.String(), `error="invalid key: [REDACTED]"`)
+	assert.Contains(t, buf.String(), "token is [REDACTED]")
+}
+
+func TestFormatFields(t *testing.T) {
+	assert.Equal(t, "", FormatFields())
+	assert.Equal(t, `a=1 b="x y" c="" d=null e=1.5 f="a=b"`,
+		FormatFields("a", 1, "b", "x y", "c", "", "d", nil, "e", 1500*time.Millisecond, "f", "a=b"))
+	assert.Equal(t, `a=(missing)`, FormatFields("a"))
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("WARN")
+	assert.NoError(t, err)
+	assert.Equal(t, LevelWarn, level)
+
+	_, err = ParseLevel("trace")
+	assert.Error(t, err)
+
+	format, err := ParseFormat("json")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
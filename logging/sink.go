@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Sink is a destination of the log
+type Sink interface {
+	Write(level Level, line []byte) error
+	Close() error
+}
+
+// WriterSink writes logs to io.Writer
+type WriterSink struct {
+	io.Writer
+}
+
+func (s *WriterSink) Write(level Level, line []byte) error {
+	_, err := s.Writer.Write(line)
+	return err
+}
+
+// Close closes the writer if it is io.Closer other than stdout/stderr
+func (s *WriterSink) Close() error {
+	if s.Writer == os.Stdout || s.Writer == os.Stderr {
+		return nil
+	}
+	if c, ok := s.Writer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// NewFileSink returns Sink appending to the file, parent directories are created if not exist
+func NewFileSink(path string) (*WriterSink, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterSink{Writer: f}, nil
+}
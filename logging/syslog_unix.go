@@ -0,0 +1,41 @@
+//go:build !windows
+// +build !windows
+
+package logging
+
+import (
+	"log/syslog"
+	"strings"
+)
+
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogSink returns Sink writing to the syslog with tag
+//
+// network and addr are passed to syslog.Dial, empty means the local syslog.
+func NewSyslogSink(network, addr, tag string) (Sink, error) {
+	w, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{writer: w}, nil
+}
+
+func (s *syslogSink) Write(level Level, line []byte) error {
+	msg := strings.TrimRight(string(line), "\n")
+	switch level {
+	case LevelDebug:
+		return s.writer.Debug(msg)
+	case LevelWarn:
+		return s.writer.Warning(msg)
+	case LevelError:
+		return s.writer.Err(msg)
+	}
+	return s.writer.Info(msg)
+}
+
+func (s *syslogSink) Close() error {
+	return s.writer.Close()
+}
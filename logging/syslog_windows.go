@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package logging
+
+import "fmt"
+
+// NewSyslogSink returns error because syslog is not supported on windows
+func NewSyslogSink(network, addr, tag string) (Sink, error) {
+	return nil, fmt.Errorf("syslog is not supported on windows")
+}
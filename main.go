@@ -3,11 +3,8 @@ package main
 import (
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
-	"github.com/fatih/color"
+	"github.com/sacloud/cloud-plan-migrate/command"
 	migrateCLI "github.com/sacloud/cloud-plan-migrate/command/cli"
 	"github.com/sacloud/cloud-plan-migrate/version"
 	"gopkg.in/urfave/cli.v2"
@@ -21,18 +18,8 @@ var (
 
 func main() {
 
-	// Signal handling
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
-	go func() {
-		<-sigChan
-		time.Sleep(500 * time.Millisecond)
-		fmt.Fprintln(os.Stderr, "")
-		fmt.Fprintln(os.Stderr, "")
-		fmt.Fprintln(color.Error, color.HiBlueString("signal received; shutting down"))
-		fmt.Fprintln(os.Stderr, "")
-		os.Exit(1)
-	}()
+	// Signal handling, the migration suspends it to drain servers in progress
+	command.HandleInterrupt()
 
 	app := &cli.App{
 		Name:      appName,
@@ -41,7 +28,17 @@ func main() {
 		Copyright: appCopyright,
 		Version:   version.FullVersion(),
 		Flags:     migrateCLI.MigrateCommand.Flags,
-		Action:    migrateCLI.MigrateCommand.Action,
+		Action:    migrateCLI.DeprecatedRootAction, // the deprecated alias of apply
+		Commands: []*cli.Command{
+			migrateCLI.CheckCommand,
+			migrateCLI.EstimateCommand,
+			migrateCLI.PlanCommand,
+			migrateCLI.MigrateCommand,
+			migrateCLI.StatusCommand,
+			migrateCLI.ResumeCommand,
+			migrateCLI.RollbackCommand,
+			migrateCLI.CleanupCommand,
+		},
 	}
 
 	cli.AppHelpTemplate = helpTemplate
@@ -65,7 +62,10 @@ DESCRIPTION:
 
 AUTHOR{{with $length := len .Authors}}{{if ne 1 $length}}S{{end}}{{end}}:
    {{range $index, $author := .Authors}}{{if $index}}
-   {{end}}{{$author}}{{end}}{{end}}
+   {{end}}{{$author}}{{end}}{{end}}{{if .VisibleCommands}}
+
+COMMANDS:{{range .VisibleCommands}}
+   {{join .Names ", "}}{{"\t"}}{{.Usage}}{{end}}{{end}}
 
 OPTIONS:
    {{range $index, $option := .VisibleFlags}}{{if $index}}
@@ -0,0 +1,232 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+const namespace = "cloud_plan_migrate"
+
+// stepDurationBuckets is the upper bounds(seconds) of the step duration histogram
+var stepDurationBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200}
+
+var serverStates = []migrate.ServerState{
+	migrate.ServerStateQueued,
+	migrate.ServerStateWaiting,
+	migrate.ServerStateRunning,
+	migrate.ServerStateDone,
+	migrate.ServerStateFailed,
+	migrate.ServerStateSkipped,
+	migrate.ServerStateRolledBack,
+}
+
+type apiStat struct {
+	calls  int
+	errors int
+}
+
+// Collector exposes metrics of the migration in the Prometheus text format
+//
+// Metrics of servers and steps are built from the status of the migration at each scrape,
+// and API calls are counted by ObserveAPICall which can be passed to iaas.NewObservedClient.
+type Collector struct {
+	migration *migrate.Migration
+	api       map[string]*apiStat
+	lock      sync.Mutex
+}
+
+// NewCollector returns Collector
+func NewCollector() *Collector {
+	return &Collector{api: map[string]*apiStat{}}
+}
+
+// SetMigration sets the migration to expose, metrics other than API calls are empty until it is set
+func (c *Collector) SetMigration(migration *migrate.Migration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.migration = migration
+}
+
+// ObserveAPICall counts the API call, it implements iaas.Observer
+func (c *Collector) ObserveAPICall(method string, elapsed time.Duration, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	stat, ok := c.api[method]
+	if !ok {
+		stat = &apiStat{}
+		c.api[method] = stat
+	}
+	stat.calls++
+	if err != nil {
+		stat.errors++
+	}
+}
+
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Write(w)
+}
+
+// Write writes all metrics in the Prometheus text format
+func (c *Collector) Write(out io.Writer) error {
+	c.lock.Lock()
+	migration := c.migration
+	methods := make([]string, 0, len(c.api))
+	api := map[string]apiStat{}
+	for method, stat := range c.api {
+		methods = append(methods, method)
+		api[method] = *stat
+	}
+	c.lock.Unlock()
+	sort.Strings(methods)
+
+	w := bufio.NewWriter(out)
+
+	var status []*migrate.ServerStatus
+	totals := &migrate.Totals{}
+	progress := &migrate.Progress{}
+	if migration != nil {
+		status = migration.Status()
+		totals = migration.Totals()
+		progress = migration.Progress()
+	}
+
+	// servers
+	writeHeader(w, "servers", "gauge", "Number of servers in each state")
+	counts := map[migrate.ServerState]int{
+		migrate.ServerStateQueued:     totals.Queued,
+		migrate.ServerStateWaiting:    totals.Waiting,
+		migrate.ServerStateRunning:    totals.Running,
+		migrate.ServerStateDone:       totals.Done,
+		migrate.ServerStateFailed:     totals.Failed,
+		migrate.ServerStateSkipped:    totals.Skipped,
+		migrate.ServerStateRolledBack: totals.RolledBack,
+	}
+	for _, state := range serverStates {
+		writeSample(w, "servers", labels("state", string(state)), float64(counts[state]))
+	}
+
+	// steps
+	inProgress := map[string]int{}
+	durations := map[string][]float64{}
+	var stepNames []string
+	for _, s := range status {
+		for _, st := range s.Steps() {
+			if _, ok := durations[st.Step]; !ok {
+				durations[st.Step] = nil
+				stepNames = append(stepNames, st.Step)
+			}
+			if st.Started && !st.Done {
+				inProgress[st.Step]++
+			}
+			if st.Done && !st.Failed {
+				durations[st.Step] = append(durations[st.Step], st.Elapsed.Seconds())
+			}
+		}
+	}
+
+	writeHeader(w, "steps_in_progress", "gauge", "Number of steps in progress")
+	for _, name := range stepNames {
+		writeSample(w, "steps_in_progress", labels("step", name), float64(inProgress[name]))
+	}
+
+	writeHeader(w, "step_duration_seconds", "histogram", "Duration of finished steps")
+	for _, name := range stepNames {
+		writeHistogram(w, "step_duration_seconds", "step", name, durations[name])
+	}
+
+	// clone
+	writeHeader(w, "clone_copied_bytes", "gauge", "Bytes copied by cloning disks")
+	writeSample(w, "clone_copied_bytes", "", float64(progress.MigratedMB)*1024*1024)
+	writeHeader(w, "clone_total_bytes", "gauge", "Total bytes of disks to clone")
+	writeSample(w, "clone_total_bytes", "", float64(progress.SizeMB)*1024*1024)
+
+	// downtime
+	writeHeader(w, "server_downtime_seconds", "gauge", "Time since the server was shut down, for running or failed servers not booted yet")
+	for _, s := range status {
+		state := s.State()
+		if state != migrate.ServerStateRunning && state != migrate.ServerStateFailed {
+			continue
+		}
+		if d := s.CurrentDowntime(); d > 0 {
+			writeSample(w, "server_downtime_seconds", labels("server_id", s.ServerID(), "server_name", s.ServerName()), d.Seconds())
+		}
+	}
+
+	// api
+	writeHeader(w, "api_calls_total", "counter", "Number of API calls by method")
+	for _, method := range methods {
+		writeSample(w, "api_calls_total", labels("method", method), float64(api[method].calls))
+	}
+	writeHeader(w, "api_errors_total", "counter", "Number of API errors by method")
+	for _, method := range methods {
+		writeSample(w, "api_errors_total", labels("method", method), float64(api[method].errors))
+	}
+
+	return w.Flush()
+}
+
+// Serve starts the HTTP server exposing metrics at /metrics on addr, and returns the listener address
+//
+// The server is stopped by Close of the returned server.
+func Serve(addr string, c *Collector) (*http.Server, net.Addr, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", c)
+	server := &http.Server{Handler: mux}
+	go server.Serve(l)
+	return server, l.Addr(), nil
+}
+
+func writeHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s_%s %s\n", namespace, name, help)
+	fmt.Fprintf(w, "# TYPE %s_%s %s\n", namespace, name, metricType)
+}
+
+func writeSample(w io.Writer, name, labels string, value float64) {
+	fmt.Fprintf(w, "%s_%s%s %s\n", namespace, name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func writeHistogram(w io.Writer, name, labelName, labelValue string, values []float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	for _, bound := range stepDurationBuckets {
+		count := 0
+		for _, v := range values {
+			if v <= bound {
+				count++
+			}
+		}
+		writeSample(w, name+"_bucket", labels(labelName, labelValue, "le", strconv.FormatFloat(bound, 'g', -1, 64)), float64(count))
+	}
+	writeSample(w, name+"_bucket", labels(labelName, labelValue, "le", "+Inf"), float64(len(values)))
+	writeSample(w, name+"_sum", labels(labelName, labelValue), sum)
+	writeSample(w, name+"_count", labels(labelName, labelValue), float64(len(values)))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels returns the label set from pairs of name and value
+func labels(pairs ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, pairs[i], labelEscaper.Replace(pairs[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/sacloud/cloud-plan-migrate/iaas"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"github.com/sacloud/libsacloud/sacloud"
+	"github.com/stretchr/testify/assert"
+)
+
+const failedServerID = int64(2)
+
+// fakeClient implements methods used by the migration, ChangePlan of failedServerID fails
+type fakeClient struct {
+	iaas.Client
+}
+
+func (f *fakeClient) ServerByID(id int64) (*sacloud.Server, error) {
+	server := &sacloud.Server{Resource: sacloud.NewResource(id)}
+	server.Name = fmt.Sprintf("server%d", id)
+	disk := sacloud.Disk{Resource: sacloud.NewResource(id * 10)}
+	disk.SizeMB = 20 * 1024
+	server.Disks = []sacloud.Disk{disk}
+	server.Instance = &sacloud.Instance{
+		EServerInstanceStatus: &sacloud.EServerInstanceStatus{Status: "up"},
+	}
+	return server, nil
+}
+func (f *fakeClient) FindServerPlan(core int, memoryGB int) (*sacloud.ProductServer, error) {
+	return nil, nil
+}
+func (f *fakeClient) Shutdown(id int64) error {
+	return nil
+}
+func (f *fakeClient) CloneDisk(id int64, planID int64) (<-chan interface{}, error) {
+	progress := make(chan interface{}, 1)
+	disk := &sacloud.Disk{Resource: sacloud.NewResource(id + 1)}
+	disk.SizeMB = 20 * 1024
+	disk.MigratedMB = disk.SizeMB
+	progress <- disk
+	close(progress)
+	return progress, nil
+}
+func (f *fakeClient) DisconnectDisks(serverID int64) error {
+	return nil
+}
+func (f *fakeClient) ChangePlan(serverID int64, plan *sacloud.ProductServer) (*sacloud.Server, error) {
+	if serverID == failedServerID {
+		return nil, fmt.Errorf("plan change failed")
+	}
+	return &sacloud.Server{Resource: sacloud.NewResource(serverID + 100)}, nil
+}
+func (f *fakeClient) ConnectDisks(serverID int64, diskIDs []int64) error {
+	return nil
+}
+func (f *fakeClient) Boot(id int64) error {
+	return nil
+}
+
+func TestCollector_Write(t *testing.T) {
+	collector := NewCollector()
+	client := iaas.NewObservedClient(&fakeClient{}, collector.ObserveAPICall)
+
+	migration, err := migrate.NewMigration(client, []int64{1, failedServerID}, &migrate.Options{MaxWorkerCount: 1})
+	assert.NoError(t, err)
+	collector.SetMigration(migration)
+	migration.Apply()
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, collector.Write(buf))
+	out := buf.String()
+
+	for _, expect := range []string{
+		"# TYPE cloud_plan_migrate_servers gauge\n",
+		`cloud_plan_migrate_servers{state="done"} 1` + "\n",
+		`cloud_plan_migrate_servers{state="failed"} 1` + "\n",
+		`cloud_plan_migrate_servers{state="queued"} 0` + "\n",
+		`cloud_plan_migrate_steps_in_progress{step="clone_disk"} 0` + "\n",
+		"# TYPE cloud_plan_migrate_step_duration_seconds histogram\n",
+		`cloud_plan_migrate_step_duration_seconds_bucket{step="clone_disk",le="1"} 2` + "\n",
+		`cloud_plan_migrate_step_duration_seconds_bucket{step="clone_disk",le="+Inf"} 2` + "\n",
+		`cloud_plan_migrate_step_duration_seconds_count{step="boot_server"} 1` + "\n",
+		`cloud_plan_migrate_step_duration_seconds_count{step="migrate_server_plan"} 1` + "\n",
+		"cloud_plan_migrate_clone_copied_bytes 4.294967296e+10\n",
+		"cloud_plan_migrate_clone_total_bytes 4.294967296e+10\n",
+		`cloud_plan_migrate_server_downtime_seconds{server_id="2",server_name="server2"} `,
+		`cloud_plan_migrate_api_calls_total{method="ChangePlan"} 2` + "\n",
+		`cloud_plan_migrate_api_errors_total{method="ChangePlan"} 1` + "\n",
+		`cloud_plan_migrate_api_errors_total{method="Boot"} 0` + "\n",
+	} {
+		assert.Contains(t, out, expect)
+	}
+	assert.NotContains(t, out, `server_downtime_seconds{server_id="1"`)
+}
+
+func TestServe(t *testing.T) {
+	collector := NewCollector()
+	collector.ObserveAPICall("Find", 0, nil)
+
+	server, addr, err := Serve("127.0.0.1:0", collector)
+	assert.NoError(t, err)
+	defer server.Close()
+
+	res, err := http.Get(fmt.Sprintf("http://%s/metrics", addr))
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(res.Body)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, res.Header.Get("Content-Type"), "text/plain; version=0.0.4")
+	assert.Contains(t, string(body), `cloud_plan_migrate_api_calls_total{method="Find"} 1`)
+	assert.Contains(t, string(body), `cloud_plan_migrate_servers{state="running"} 0`)
+}
+
+func TestLabels(t *testing.T) {
+	assert.Equal(t, `{name="a\"b\\c\nd"}`, labels("name", "a\"b\\c\nd"))
+}
@@ -0,0 +1,35 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// noResultClient closes the progress of CloneDisk without the cloned disk
+type noResultClient struct {
+	*fakeClient
+}
+
+func (c *noResultClient) CloneDisk(id int64, planID int64) (<-chan interface{}, error) {
+	progress := make(chan interface{})
+	close(progress)
+	return progress, nil
+}
+
+func TestMigration_cloneDisks(t *testing.T) {
+
+	t.Run("finished without result", func(t *testing.T) {
+		client := &noResultClient{fakeClient: &fakeClient{server: multipleDiskServer()}}
+
+		migration, err := NewMigration(client, []int64{serverID}, &Options{MaxWorkerCount: 1})
+		assert.NoError(t, err)
+
+		migration.Apply()
+
+		errs := migration.HasErrors()
+		assert.Len(t, errs, 1)
+		assert.EqualError(t, errs[0].Err, "cloning Disk[2] is finished without result")
+		assert.False(t, errs[0].stepPlanMigrate.started)
+	})
+}
@@ -0,0 +1,133 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/sacloud/cloud-plan-migrate/logging"
+)
+
+// Pause stops starting new servers, servers in progress are not affected
+func (m *Migration) Pause() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.paused = true
+}
+
+// Resume restarts starting servers stopped by Pause
+func (m *Migration) Resume() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.paused = false
+	m.resumed.Broadcast()
+}
+
+func (m *Migration) Paused() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.paused
+}
+
+// Drain stops starting new servers and lets Apply return after servers in progress are finished
+//
+// Servers not started yet are left as queued.
+func (m *Migration) Drain() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.draining = true
+	m.resumed.Broadcast()
+}
+
+func (m *Migration) Draining() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.draining
+}
+
+// Skip removes the queued server from the migration
+func (m *Migration) Skip(serverID int64) error {
+	status := m.findStatus(serverID)
+	if status == nil {
+		return fmt.Errorf("Server[%d] is not a target of the migration", serverID)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if status.state != "" {
+		return fmt.Errorf("Server[%d] is already %s", serverID, status.state)
+	}
+	status.state = ServerStateSkipped
+	status.logger.log(logging.LevelInfo, "skipped")
+
+	// wake up the worker waiting for resume
+	m.resumed.Broadcast()
+	return nil
+}
+
+// waitForStart blocks while the migration is paused, and returns false if the server is skipped or the migration is drained
+func (m *Migration) waitForStart(status *ServerStatus) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for m.paused && !m.draining && status.state != ServerStateSkipped {
+		m.resumed.Wait()
+	}
+	return !m.draining && status.state != ServerStateSkipped
+}
+
+// Rollback restores the failed server to the state before the migration
+//
+// The original disks are connected again, cloned disks are deleted and the server is booted if it was running.
+// Servers whose plan is already changed can't be rolled back.
+func (m *Migration) Rollback(serverID int64) error {
+	status := m.findStatus(serverID)
+	if status == nil {
+		return fmt.Errorf("Server[%d] is not a target of the migration", serverID)
+	}
+
+	m.lock.Lock()
+	if status.state != ServerStateDone || status.Err == nil || status.rollingBack {
+		m.lock.Unlock()
+		return fmt.Errorf("Server[%d] is not failed: %s", serverID, status.currentState())
+	}
+	status.rollingBack = true
+	m.lock.Unlock()
+
+	defer func() {
+		m.lock.Lock()
+		status.rollingBack = false
+		m.lock.Unlock()
+	}()
+
+	if status.migratedServerID != 0 {
+		return fmt.Errorf("Server[%d] can't be rolled back: plan is already changed to Server[%d]", serverID, status.migratedServerID)
+	}
+
+	status.logger.log(logging.LevelInfo, "Rollback started", "step", "rollback")
+	if err := m.rollbackServer(status); err != nil {
+		status.logger.log(logging.LevelError, "Rollback error", "step", "rollback", "error", err)
+		return fmt.Errorf("Rollback is failed: %s", err)
+	}
+	status.logger.log(logging.LevelInfo, "Rollback finished", "step", "rollback")
+
+	m.lock.Lock()
+	status.state = ServerStateRolledBack
+	m.lock.Unlock()
+
+	m.emit(EventServerRolledBack, status)
+	return nil
+}
+
+func (m *Migration) rollbackServer(status *ServerStatus) error {
+	return rollback(m.client, status.record(), func(i int) {
+		status.Disks[i].clonedID = 0
+	})
+}
+
+func (m *Migration) findStatus(serverID int64) *ServerStatus {
+	for _, s := range m.status {
+		if s.targetServerID == serverID {
+			return s
+		}
+	}
+	return nil
+}
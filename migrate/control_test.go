@@ -0,0 +1,150 @@
+package migrate
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigration_Pause(t *testing.T) {
+
+	t.Run("pause and resume", func(t *testing.T) {
+		m, err := NewMigration(&fakeClient{server: singleDiskServer()}, []int64{serverID}, &Options{MaxWorkerCount: 1})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		m.Pause()
+		assert.True(t, m.Paused())
+
+		doneC := make(chan bool)
+		go func() {
+			m.Apply()
+			doneC <- true
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, ServerStateQueued, m.Status()[0].State())
+
+		m.Resume()
+		<-doneC
+		assert.False(t, m.Paused())
+		assert.Equal(t, ServerStateDone, m.Status()[0].State())
+	})
+
+	t.Run("skip while paused", func(t *testing.T) {
+		client := &fakeClient{server: singleDiskServer()}
+		m, err := NewMigration(client, []int64{serverID}, &Options{MaxWorkerCount: 1})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		m.Pause()
+		doneC := make(chan bool)
+		go func() {
+			m.Apply()
+			doneC <- true
+		}()
+
+		assert.NoError(t, m.Skip(serverID))
+		<-doneC
+		assert.Equal(t, ServerStateSkipped, m.Status()[0].State())
+		assert.Equal(t, &Totals{Skipped: 1}, m.Totals())
+		assert.Empty(t, client.clonedPlanIDs)
+
+		assert.Error(t, m.Skip(serverID))
+		assert.Error(t, m.Skip(migratedServerID))
+	})
+}
+
+func TestMigration_Drain(t *testing.T) {
+	client := &fakeClient{server: singleDiskServer()}
+	m, err := NewMigration(client, []int64{serverID, migratedServerID}, &Options{MaxWorkerCount: 1})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	m.Pause()
+	doneC := make(chan bool)
+	go func() {
+		m.Apply()
+		doneC <- true
+	}()
+
+	m.Drain()
+	<-doneC
+	assert.True(t, m.Draining())
+	assert.Equal(t, &Totals{Queued: 2}, m.Totals())
+	assert.Empty(t, client.clonedPlanIDs)
+}
+
+func TestMigration_Rollback(t *testing.T) {
+
+	t.Run("before plan change", func(t *testing.T) {
+		client := &fakeClient{
+			server:        singleDiskServer(),
+			disconnectErr: fmt.Errorf("disconnect failed"),
+		}
+		listener := &recordListener{}
+		m, err := NewMigration(client, []int64{serverID}, &Options{MaxWorkerCount: 1, Listeners: []Listener{listener}})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		m.Apply()
+		assert.Equal(t, ServerStateFailed, m.Status()[0].State())
+
+		assert.NoError(t, m.Rollback(serverID))
+		rolledBack := listener.events[len(listener.events)-1]
+		assert.Equal(t, EventServerRolledBack, rolledBack.Type)
+		assert.Equal(t, 1, rolledBack.Totals.RolledBack)
+		assert.Equal(t, &ServerRecord{
+			ServerID:        serverID,
+			State:           ServerStateRolledBack,
+			OriginalDiskIDs: []int64{currentDiskID},
+			ClonedDiskIDs:   []int64{0},
+			Error:           "disconnect failed",
+		}, rolledBack.records[0])
+		assert.Equal(t, ServerStateRolledBack, m.Status()[0].State())
+		assert.Equal(t, []int64{currentDiskID}, client.connectedDiskIDs)
+		assert.Equal(t, []int64{clonedDiskID}, client.deletedDiskIDs)
+		assert.Equal(t, []int64{serverID}, client.bootedServerIDs)
+		assert.NotEmpty(t, m.Status()[0].Logs())
+
+		assert.Error(t, m.Rollback(serverID))
+	})
+
+	t.Run("after plan change", func(t *testing.T) {
+		client := &fakeClient{server: singleDiskServer()}
+		m, err := NewMigration(client, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			Hooks: &Hooks{
+				AfterMigrate: func(status *ServerStatus) error {
+					return fmt.Errorf("check failed")
+				},
+			},
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		m.Apply()
+		assert.Error(t, m.Rollback(serverID))
+		assert.Equal(t, ServerStateFailed, m.Status()[0].State())
+
+		// the hook runs after the disks are connected and the server is booted
+		assert.Equal(t, []int64{clonedDiskID}, client.connectedDiskIDs)
+		assert.Equal(t, []int64{migratedServerID}, client.bootedServerIDs)
+		assert.Empty(t, client.deletedDiskIDs)
+	})
+
+	t.Run("not failed", func(t *testing.T) {
+		m, err := NewMigration(&fakeClient{server: singleDiskServer()}, []int64{serverID}, &Options{MaxWorkerCount: 1})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Error(t, m.Rollback(serverID))
+	})
+}
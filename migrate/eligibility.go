@@ -0,0 +1,135 @@
+package migrate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/iaas"
+	"github.com/sacloud/libsacloud/sacloud"
+)
+
+// DefaultCloneThroughputMBps is the assumed clone throughput used when no history is available
+const DefaultCloneThroughputMBps = 30.0
+
+// Eligibility is a result of the pre-flight check for a server
+type Eligibility struct {
+	ServerID              int64         `json:"server_id"`
+	ServerName            string        `json:"server_name"`
+	Generation            string        `json:"generation"`
+	Core                  int           `json:"core"`
+	MemoryGB              int           `json:"memory"`
+	NewPlanAvailable      bool          `json:"new_plan_available"`
+	DiskCount             int           `json:"disk_count"`
+	TotalDiskSizeGB       int           `json:"total_disk_size_gb"`
+	DiskPlanSubstitutions []string      `json:"disk_plan_substitutions"`
+	State                 string        `json:"state"`
+	EstimatedCopyTime     time.Duration `json:"-"`
+	EstimatedCopySeconds  int           `json:"estimated_copy_seconds"`
+	Blockers              []string      `json:"blockers"`
+}
+
+func (e *Eligibility) Eligible() bool {
+	return len(e.Blockers) == 0
+}
+
+// CheckEligibility checks whether each server can be migrated
+//
+// The estimated copy time is calculated from the clone throughput in history.
+// A disk is treated as shared if it is connected to other servers among servers or by the API.
+// The plan and the disk plan requested by serverOptions(e.g. the inventory) are checked instead of the current ones.
+func CheckEligibility(client iaas.Client, servers []*sacloud.Server, history *ThroughputHistory, serverOptions map[int64]*ServerOptions) []*Eligibility {
+	type planKey struct{ core, memoryGB int }
+	plans := map[planKey]error{}
+
+	diskServers := map[int64]map[int64]bool{}
+	for _, server := range servers {
+		for _, disk := range server.Disks {
+			if diskServers[disk.ID] == nil {
+				diskServers[disk.ID] = map[int64]bool{}
+			}
+			diskServers[disk.ID][server.ID] = true
+		}
+	}
+
+	var results []*Eligibility
+	for _, server := range servers {
+		opts := serverOptions[server.ID]
+		if opts == nil {
+			opts = &ServerOptions{}
+		}
+		e := &Eligibility{
+			ServerID:   server.ID,
+			ServerName: server.Name,
+			Core:       server.GetCPU(),
+			MemoryGB:   server.GetMemoryGB(),
+			DiskCount:  len(server.Disks),
+			State:      server.GetInstanceStatus(),
+		}
+
+		generation := sacloud.PlanDefault
+		if server.ServerPlan != nil {
+			generation = server.ServerPlan.Generation
+		}
+		e.Generation = fmt.Sprintf("g%d", generation/100)
+
+		key := planKey{core: e.Core, memoryGB: e.MemoryGB}
+		if opts.Core > 0 && opts.MemoryGB > 0 {
+			key = planKey{core: opts.Core, memoryGB: opts.MemoryGB}
+		}
+		planErr, ok := plans[key]
+		if !ok {
+			_, planErr = client.FindServerPlan(key.core, key.memoryGB)
+			plans[key] = planErr
+		}
+		e.NewPlanAvailable = planErr == nil
+
+		for _, disk := range server.Disks {
+			e.TotalDiskSizeGB += disk.GetSizeGB()
+			planID := disk.GetPlanID()
+			if cloned := clonedDiskPlanID(&disk, opts.DiskPlanID); cloned != planID {
+				e.DiskPlanSubstitutions = append(e.DiskPlanSubstitutions,
+					fmt.Sprintf("Disk[%d]: %s => %s", disk.ID, diskPlanName(planID), diskPlanName(cloned)))
+			}
+		}
+		e.EstimatedCopyTime = estimateCopyTime(server, history, opts.DiskPlanID)
+		e.EstimatedCopySeconds = int(e.EstimatedCopyTime.Seconds())
+
+		if len(server.Disks) == 0 {
+			e.Blockers = append(e.Blockers, "server don't have any disks")
+		}
+		if generation == sacloud.PlanG2 {
+			e.Blockers = append(e.Blockers, "server is already use plan-gen2")
+		} else if !e.NewPlanAvailable {
+			e.Blockers = append(e.Blockers, fmt.Sprintf("new plan(%d core, %dGB memory) is not found: %s", key.core, key.memoryGB, planErr))
+		}
+		if server.Instance != nil && server.Instance.CDROM != nil {
+			e.Blockers = append(e.Blockers, fmt.Sprintf("ISO image[%d] is inserted", server.Instance.CDROM.ID))
+		}
+		for _, disk := range server.Disks {
+			if len(diskServers[disk.ID]) > 1 || (disk.Server != nil && disk.Server.ID != server.ID) {
+				e.Blockers = append(e.Blockers, fmt.Sprintf("Disk[%d] is shared with other servers", disk.ID))
+			}
+		}
+
+		results = append(results, e)
+	}
+	return results
+}
+
+// clonedDiskPlanID returns the plan of the disk cloned from disk, diskPlanID overrides the plan of disk if not 0
+func clonedDiskPlanID(disk *sacloud.Disk, diskPlanID int64) int64 {
+	if diskPlanID == 0 {
+		diskPlanID = disk.GetPlanID()
+	}
+	return iaas.ClonedDiskPlanID(diskPlanID, disk.GetSizeGB())
+}
+
+func diskPlanName(planID int64) string {
+	switch planID {
+	case int64(sacloud.DiskPlanSSDID):
+		return "SSD"
+	case int64(sacloud.DiskPlanHDDID):
+		return "HDD"
+	}
+	return fmt.Sprintf("%d", planID)
+}
@@ -0,0 +1,86 @@
+package migrate
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sacloud/libsacloud/sacloud"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckEligibility(t *testing.T) {
+
+	g1 := singleDiskServer()
+	g1.SetServerPlanByValue(1, 1, sacloud.PlanG1)
+	g1.Disks[0].Plan = sacloud.NewResource(int64(sacloud.DiskPlanHDDID))
+
+	g2 := multipleDiskServer()
+	g2.SetServerPlanByValue(1, 1, sacloud.PlanG2)
+	g2.Instance.CDROM = &sacloud.CDROM{Resource: sacloud.NewResource(5)}
+
+	history := &ThroughputHistory{Samples: map[string][]*ThroughputSample{
+		"SSD": {{SizeMB: 1024, Seconds: 1}},
+	}}
+	// disk 10 is connected to another server, disk 11 is shared with attached
+	shared := singleDiskServer()
+	shared.Resource = sacloud.NewResource(10)
+	shared.Disks[0].Resource = sacloud.NewResource(10)
+	shared.Disks[0].Server = &sacloud.Server{Resource: sacloud.NewResource(12)}
+	shared.Disks = append(shared.Disks, sacloud.Disk{Resource: sacloud.NewResource(11)})
+	attached := singleDiskServer()
+	attached.Resource = sacloud.NewResource(12)
+	attached.Disks[0].Resource = sacloud.NewResource(11)
+
+	results := CheckEligibility(&fakeClient{}, []*sacloud.Server{g1, g2, shared, attached}, history, nil)
+	assert.Len(t, results, 4)
+
+	assert.True(t, results[0].Eligible())
+	assert.Equal(t, "g1", results[0].Generation)
+	assert.True(t, results[0].NewPlanAvailable)
+	assert.Equal(t, 1, results[0].DiskCount)
+	assert.Equal(t, 20, results[0].TotalDiskSizeGB)
+	assert.Equal(t, []string{"Disk[2]: HDD => SSD"}, results[0].DiskPlanSubstitutions)
+	assert.Equal(t, "up", results[0].State)
+	assert.Equal(t, 20*time.Second, results[0].EstimatedCopyTime)
+
+	assert.False(t, results[1].Eligible())
+	assert.Equal(t, 40, results[1].TotalDiskSizeGB)
+	assert.Equal(t, []string{
+		"server is already use plan-gen2",
+		"ISO image[5] is inserted",
+	}, results[1].Blockers)
+
+	assert.False(t, results[2].Eligible())
+	assert.Equal(t, []string{
+		"Disk[10] is shared with other servers",
+		"Disk[11] is shared with other servers",
+	}, results[2].Blockers)
+	assert.Equal(t, []string{"Disk[11] is shared with other servers"}, results[3].Blockers)
+
+	t.Run("server options", func(t *testing.T) {
+		server := singleDiskServer()
+		server.SetServerPlanByValue(1, 1, sacloud.PlanG1)
+		server.Disks[0].Plan = sacloud.NewResource(int64(sacloud.DiskPlanHDDID))
+		server.Disks[0].SizeMB = 40 * 1024
+
+		results := CheckEligibility(&planClient{fakeClient: &fakeClient{}, missingCore: 4}, []*sacloud.Server{server}, history,
+			map[int64]*ServerOptions{serverID: {Core: 4, MemoryGB: 8, DiskPlanID: int64(sacloud.DiskPlanSSDID)}})
+		assert.Equal(t, []string{"new plan(4 core, 8GB memory) is not found: plan is not found"}, results[0].Blockers)
+		assert.Equal(t, []string{"Disk[2]: HDD => SSD"}, results[0].DiskPlanSubstitutions)
+		assert.Equal(t, 40*time.Second, results[0].EstimatedCopyTime)
+	})
+}
+
+// planClient doesn't have the plans with missingCore
+type planClient struct {
+	*fakeClient
+	missingCore int
+}
+
+func (c *planClient) FindServerPlan(core int, memoryGB int) (*sacloud.ProductServer, error) {
+	if core == c.missingCore {
+		return nil, fmt.Errorf("plan is not found")
+	}
+	return c.fakeClient.FindServerPlan(core, memoryGB)
+}
@@ -0,0 +1,179 @@
+package migrate
+
+import (
+	"sort"
+	"time"
+
+	"github.com/sacloud/libsacloud/sacloud"
+)
+
+// EstimatedOperationTime is the assumed time of operations other than cloning disks(shutdown, plan change, boot, ...)
+const EstimatedOperationTime = 3 * time.Minute
+
+// Estimate is a prediction of the migration
+type Estimate struct {
+	Servers []*ServerEstimate `json:"servers"`
+	Workers int               `json:"workers"`
+
+	// TotalTime is the wall-clock time until all servers are migrated
+	TotalTime        time.Duration `json:"-"`
+	TotalTimeSeconds int           `json:"total_time_seconds"`
+
+	// ExtraDiskSizeGB is the peak size of the cloned disks existing together with the original disks
+	ExtraDiskSizeGB int `json:"extra_disk_size_gb"`
+
+	// Throughputs is the clone throughput(MB/s) used for each disk plan
+	Throughputs map[string]float64 `json:"throughputs"`
+}
+
+// ServerEstimate is a prediction of the migration of a server
+type ServerEstimate struct {
+	ServerID        int64         `json:"server_id"`
+	ServerName      string        `json:"server_name"`
+	DiskSizeGB      int           `json:"disk_size_gb"`
+	CopyTime        time.Duration `json:"-"`
+	CopySeconds     int           `json:"copy_seconds"`
+	Downtime        time.Duration `json:"-"`
+	DowntimeSeconds int           `json:"downtime_seconds"`
+	Start           time.Duration `json:"-"`
+	StartSeconds    int           `json:"start_seconds"`
+
+	// PreClone means disks are cloned while the server is up, writes during the copy time are not migrated
+	// unless the filesystem is frozen by the hook
+	PreClone bool `json:"pre_clone"`
+}
+
+// EstimateMigration predicts the downtime of each server and the total time to migrate servers with the number of workers
+//
+// Servers are assigned to workers in the order of servers.
+// If deleteDisks is false, original disks are left, so all cloned disks are counted as extra disk size.
+// If preClone is true, the copy time is excluded from the downtime.
+// deleteDisks, preClone and the disk plan are overridden for each server by serverOptions(e.g. the inventory).
+func EstimateMigration(servers []*sacloud.Server, history *ThroughputHistory, workers int, deleteDisks bool, preClone bool, serverOptions map[int64]*ServerOptions) *Estimate {
+	if workers <= 0 {
+		workers = 1
+	}
+	e := &Estimate{
+		Workers:     workers,
+		Throughputs: map[string]float64{},
+	}
+
+	type event struct {
+		at     time.Duration
+		sizeGB int
+	}
+	var events []event
+	free := make([]time.Duration, workers)
+
+	for _, server := range servers {
+		opts := serverOptions[server.ID]
+		if opts == nil {
+			opts = &ServerOptions{}
+		}
+		s := &ServerEstimate{
+			ServerID:   server.ID,
+			ServerName: server.Name,
+			PreClone:   preClone,
+		}
+		if opts.PreClone != nil {
+			s.PreClone = *opts.PreClone
+		}
+		deleteServerDisks := deleteDisks
+		if opts.DeleteDisks != nil {
+			deleteServerDisks = *opts.DeleteDisks
+		}
+		for i := range server.Disks {
+			plan := diskPlanName(clonedDiskPlanID(&server.Disks[i], opts.DiskPlanID))
+			e.Throughputs[plan] = history.MBps(plan)
+			s.DiskSizeGB += server.Disks[i].GetSizeGB()
+		}
+		s.CopyTime = estimateCopyTime(server, history, opts.DiskPlanID)
+		s.Downtime = s.CopyTime + EstimatedOperationTime
+		busy := s.Downtime
+		if s.PreClone {
+			s.Downtime = EstimatedOperationTime
+		}
+
+		// assign to the worker that will be free first
+		w := 0
+		for i := range free {
+			if free[i] < free[w] {
+				w = i
+			}
+		}
+		s.Start = free[w]
+		free[w] += busy
+
+		events = append(events, event{at: s.Start, sizeGB: s.DiskSizeGB})
+		if deleteServerDisks {
+			events = append(events, event{at: free[w], sizeGB: -s.DiskSizeGB})
+		}
+
+		s.CopySeconds = int(s.CopyTime.Seconds())
+		s.DowntimeSeconds = int(s.Downtime.Seconds())
+		s.StartSeconds = int(s.Start.Seconds())
+		e.Servers = append(e.Servers, s)
+
+		if free[w] > e.TotalTime {
+			e.TotalTime = free[w]
+		}
+	}
+	e.TotalTimeSeconds = int(e.TotalTime.Seconds())
+
+	// disks are deleted before the next server starts on the same time
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].at == events[j].at {
+			return events[i].sizeGB < events[j].sizeGB
+		}
+		return events[i].at < events[j].at
+	})
+	current := 0
+	for _, ev := range events {
+		current += ev.sizeGB
+		if current > e.ExtraDiskSizeGB {
+			e.ExtraDiskSizeGB = current
+		}
+	}
+
+	return e
+}
+
+// estimateCopyTime returns the time to clone disks of the server
+//
+// Disks of a server are cloned in parallel, so it is the time of the slowest disk.
+// diskPlanID overrides the plan of disks if not 0.
+func estimateCopyTime(server *sacloud.Server, history *ThroughputHistory, diskPlanID int64) time.Duration {
+	var copyTime time.Duration
+	for i := range server.Disks {
+		disk := &server.Disks[i]
+		t := cloneTime(disk.GetSizeMB(), clonedDiskPlanID(disk, diskPlanID), history)
+		if t > copyTime {
+			copyTime = t
+		}
+	}
+	return copyTime
+}
+
+// estimatedDowntime returns the estimated time of the server from the start of the migration to boot
+func (s *ServerStatus) estimatedDowntime(history *ThroughputHistory) time.Duration {
+	return s.estimatedCopyTime(history) + EstimatedOperationTime
+}
+
+// estimatedCopyTime returns the time to clone disks of the server not cloned yet
+func (s *ServerStatus) estimatedCopyTime(history *ThroughputHistory) time.Duration {
+	var copyTime time.Duration
+	for _, d := range s.Disks {
+		if d.stepClone.done {
+			continue
+		}
+		if t := cloneTime(d.sizeMB, d.clonedPlanID, history); t > copyTime {
+			copyTime = t
+		}
+	}
+	return copyTime
+}
+
+func cloneTime(sizeMB int, clonedPlanID int64, history *ThroughputHistory) time.Duration {
+	t := float64(sizeMB) / history.MBps(diskPlanName(clonedPlanID)) * float64(time.Second)
+	return time.Duration(t).Round(time.Second)
+}
@@ -0,0 +1,101 @@
+package migrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sacloud/libsacloud/sacloud"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThroughputHistory(t *testing.T) {
+
+	t.Run("empty", func(t *testing.T) {
+		var history *ThroughputHistory
+		assert.Equal(t, DefaultCloneThroughputMBps, history.MBps("SSD"))
+		assert.Equal(t, DefaultCloneThroughputMBps, (&ThroughputHistory{}).MBps("SSD"))
+	})
+
+	t.Run("record and save", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "cloud-plan-migrate")
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "history.json")
+
+		history, err := LoadThroughputHistory(path)
+		assert.NoError(t, err)
+
+		history.Record("SSD", 1000, 10*time.Second)
+		history.Record("SSD", 3000, 10*time.Second)
+		history.Record("HDD", 0, time.Second) // ignored
+		assert.Equal(t, 200.0, history.MBps("SSD"))
+		assert.Equal(t, DefaultCloneThroughputMBps, history.MBps("HDD"))
+
+		assert.NoError(t, history.Save(path))
+		loaded, err := LoadThroughputHistory(path)
+		assert.NoError(t, err)
+		assert.Equal(t, 200.0, loaded.MBps("SSD"))
+	})
+}
+
+func TestEstimateMigration(t *testing.T) {
+
+	var servers []*sacloud.Server
+	for i := 0; i < 3; i++ {
+		server := singleDiskServer()
+		server.Disks[0].Plan = sacloud.NewResource(int64(sacloud.DiskPlanSSDID))
+		servers = append(servers, server)
+	}
+	history := &ThroughputHistory{Samples: map[string][]*ThroughputSample{
+		"SSD": {{SizeMB: 1024, Seconds: 1}},
+	}}
+	downtime := 20*time.Second + EstimatedOperationTime
+
+	t.Run("with cleanup", func(t *testing.T) {
+		e := EstimateMigration(servers, history, 2, true, false, nil)
+		assert.Len(t, e.Servers, 3)
+		assert.Equal(t, 20*time.Second, e.Servers[0].CopyTime)
+		assert.Equal(t, downtime, e.Servers[0].Downtime)
+		assert.Equal(t, time.Duration(0), e.Servers[1].Start)
+		assert.Equal(t, downtime, e.Servers[2].Start)
+		assert.Equal(t, 2*downtime, e.TotalTime)
+		assert.Equal(t, 40, e.ExtraDiskSizeGB)
+		assert.Equal(t, map[string]float64{"SSD": 1024}, e.Throughputs)
+	})
+
+	t.Run("without cleanup", func(t *testing.T) {
+		e := EstimateMigration(servers, history, 2, false, false, nil)
+		assert.Equal(t, 60, e.ExtraDiskSizeGB)
+	})
+
+	t.Run("single worker", func(t *testing.T) {
+		e := EstimateMigration(servers, history, 0, true, false, nil)
+		assert.Equal(t, 1, e.Workers)
+		assert.Equal(t, 3*downtime, e.TotalTime)
+		assert.Equal(t, 20, e.ExtraDiskSizeGB)
+	})
+
+	t.Run("pre-clone", func(t *testing.T) {
+		e := EstimateMigration(servers, history, 2, true, true, nil)
+		assert.True(t, e.Servers[0].PreClone)
+		assert.Equal(t, 20*time.Second, e.Servers[0].CopyTime)
+		assert.Equal(t, EstimatedOperationTime, e.Servers[0].Downtime)
+		assert.Equal(t, 2*downtime, e.TotalTime)
+	})
+
+	t.Run("server options", func(t *testing.T) {
+		preClone, keepDisks := true, false
+		e := EstimateMigration(servers, history, 1, true, false, map[int64]*ServerOptions{
+			serverID: {PreClone: &preClone, DeleteDisks: &keepDisks},
+		})
+		// all servers have the same ID in this test, so options are applied to every server
+		assert.True(t, e.Servers[0].PreClone)
+		assert.Equal(t, EstimatedOperationTime, e.Servers[0].Downtime)
+		assert.Equal(t, 60, e.ExtraDiskSizeGB)
+	})
+}
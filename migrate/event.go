@@ -0,0 +1,107 @@
+package migrate
+
+import "time"
+
+// EventType is the type of the migration event
+type EventType string
+
+const (
+	EventRunStarted      EventType = "run_started"
+	EventServerStarted   EventType = "server_started"
+	EventServerCompleted EventType = "server_completed"
+	EventServerFailed    EventType = "server_failed"
+	EventRunFinished     EventType = "run_finished"
+)
+
+// Event types of steps of the server migration, recorded in the journal to resume interrupted servers
+const (
+	EventServerShutDown    EventType = "server_shut_down"
+	EventDisksCloned       EventType = "disks_cloned"
+	EventDisksDisconnected EventType = "disks_disconnected"
+	EventServerPlanChanged EventType = "server_plan_changed"
+)
+
+// Event is a notification of the migration progress
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+
+	// ServerID/ServerName/MigratedServerID/Error are set only for server events
+	ServerID         int64  `json:"server_id,omitempty"`
+	ServerName       string `json:"server_name,omitempty"`
+	MigratedServerID int64  `json:"migrated_server_id,omitempty"`
+	Error            string `json:"error,omitempty"`
+
+	Totals *Totals `json:"totals"`
+
+	// Progress is the clone progress of the server, or all servers for run events
+	Progress *EventProgress `json:"progress"`
+
+	// records is the state of the server of the event, or all servers for run events
+	records []*ServerRecord
+}
+
+// EventProgress is the clone progress of disks at the event
+type EventProgress struct {
+	MigratedMB     int     `json:"migrated_mb"`
+	SizeMB         int     `json:"size_mb"`
+	Percentage     float64 `json:"percentage"`
+	ThroughputMBps float64 `json:"throughput_mbps"`
+	ETASeconds     int     `json:"eta_seconds"` // -1 if unknown
+}
+
+func newEventProgress(p *Progress) *EventProgress {
+	return &EventProgress{
+		MigratedMB:     p.MigratedMB,
+		SizeMB:         p.SizeMB,
+		Percentage:     p.Percentage(),
+		ThroughputMBps: p.ThroughputMBps,
+		ETASeconds:     p.ETASeconds(),
+	}
+}
+
+// Listener receives events of the migration
+//
+// Notify is called synchronously from workers, so it should not block.
+type Listener interface {
+	Notify(event *Event)
+}
+
+// emitStep emits the event of the server after steps, unless none of them are processed(e.g. the server is already down)
+func (m *Migration) emitStep(eventType EventType, status *ServerStatus, steps ...*step) {
+	for _, st := range steps {
+		if st != nil && st.needProcess {
+			m.emit(eventType, status)
+			return
+		}
+	}
+}
+
+func (m *Migration) emit(eventType EventType, status *ServerStatus) {
+	if len(m.listeners) == 0 {
+		return
+	}
+	event := &Event{
+		Type:   eventType,
+		Time:   time.Now(),
+		Totals: m.Totals(),
+	}
+	if status != nil {
+		event.ServerID = status.targetServerID
+		event.ServerName = status.serverName
+		event.MigratedServerID = status.migratedServerID
+		if err := status.GetErr(); err != nil {
+			event.Error = err.Error()
+		}
+		event.Progress = newEventProgress(status.Progress())
+		event.records = []*ServerRecord{status.record()}
+	} else {
+		event.Progress = newEventProgress(m.Progress())
+		for _, s := range m.status {
+			event.records = append(event.records, s.record())
+		}
+	}
+	for _, l := range m.listeners {
+		l.Notify(event)
+	}
+}
@@ -0,0 +1,109 @@
+package migrate
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxThroughputSamples is the number of samples kept for each disk plan
+const maxThroughputSamples = 50
+
+// ThroughputHistory is a history of the clone throughput recorded by previous runs
+type ThroughputHistory struct {
+	Samples map[string][]*ThroughputSample `json:"samples"`
+
+	lock sync.Mutex
+}
+
+// ThroughputSample is a result of cloning a disk
+type ThroughputSample struct {
+	SizeMB     int       `json:"size_mb"`
+	Seconds    float64   `json:"seconds"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// LoadThroughputHistory reads the history file
+//
+// If the file does not exist, empty history is returned.
+func LoadThroughputHistory(path string) (*ThroughputHistory, error) {
+	history := &ThroughputHistory{}
+	if path == "" {
+		return history, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return history, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// Save writes the history to the file
+func (h *ThroughputHistory) Save(path string) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Record adds a sample of the disk plan
+func (h *ThroughputHistory) Record(diskPlan string, sizeMB int, elapsed time.Duration) {
+	if h == nil || sizeMB <= 0 || elapsed <= 0 {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.Samples == nil {
+		h.Samples = map[string][]*ThroughputSample{}
+	}
+	samples := append(h.Samples[diskPlan], &ThroughputSample{
+		SizeMB:     sizeMB,
+		Seconds:    elapsed.Seconds(),
+		RecordedAt: time.Now(),
+	})
+	if len(samples) > maxThroughputSamples {
+		samples = samples[len(samples)-maxThroughputSamples:]
+	}
+	h.Samples[diskPlan] = samples
+}
+
+// MBps returns the average throughput of the disk plan
+//
+// If there are no samples for the disk plan, DefaultCloneThroughputMBps is returned.
+func (h *ThroughputHistory) MBps(diskPlan string) float64 {
+	if h == nil {
+		return DefaultCloneThroughputMBps
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var sizeMB, seconds float64
+	for _, s := range h.Samples[diskPlan] {
+		sizeMB += float64(s.SizeMB)
+		seconds += s.Seconds
+	}
+	if sizeMB == 0 || seconds == 0 {
+		return DefaultCloneThroughputMBps
+	}
+	return sizeMB / seconds
+}
@@ -0,0 +1,33 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// NewCommandHook returns Hook that executes external command
+//
+// The command is executed with SERVER_ID, SERVER_NAME and MIGRATED_SERVER_ID(empty before the plan is changed)
+// environment variables, and the hook fails if the command exits with non-zero status.
+func NewCommandHook(command string) Hook {
+	return func(status *ServerStatus) error {
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		} else {
+			cmd = exec.Command("sh", "-c", command)
+		}
+		cmd.Env = append(os.Environ(),
+			fmt.Sprintf("SERVER_ID=%s", status.ServerID()),
+			fmt.Sprintf("SERVER_NAME=%s", status.ServerName()),
+			fmt.Sprintf("MIGRATED_SERVER_ID=%s", status.MigratedServerID()),
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("command %q is failed: %s: %s", command, err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+}
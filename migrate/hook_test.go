@@ -0,0 +1,25 @@
+package migrate
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCommandHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is required")
+	}
+	status := &ServerStatus{targetServerID: serverID, serverName: "web", migratedServerID: migratedServerID}
+
+	t.Run("success", func(t *testing.T) {
+		hook := NewCommandHook(`test "$SERVER_ID/$SERVER_NAME/$MIGRATED_SERVER_ID" = "1/web/3"`)
+		assert.NoError(t, hook(status))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		hook := NewCommandHook("echo not ready; exit 1")
+		assert.EqualError(t, hook(status), `command "echo not ready; exit 1" is failed: exit status 1: not ready`)
+	})
+}
@@ -0,0 +1,325 @@
+package migrate
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/iaas"
+	"github.com/sacloud/libsacloud/sacloud"
+)
+
+// Event types of the rollback and the cleanup, recorded in the journal by commands after the run
+// (the rollback is also emitted by Migration.Rollback)
+const (
+	EventServerRolledBack EventType = "server_rolled_back"
+	EventDisksCleanedUp   EventType = "disks_cleaned_up"
+)
+
+// ServerRecord is the state of a server recorded in the journal
+type ServerRecord struct {
+	ServerID         int64       `json:"server_id"`
+	ServerName       string      `json:"server_name"`
+	State            ServerState `json:"state"`
+	MigratedServerID int64       `json:"migrated_server_id,omitempty"`
+
+	// OriginalDiskIDs and ClonedDiskIDs are in the same order, zero of ClonedDiskIDs means not cloned or already deleted
+	OriginalDiskIDs []int64 `json:"original_disk_ids"`
+	ClonedDiskIDs   []int64 `json:"cloned_disk_ids"`
+
+	ShutDown          bool `json:"shut_down,omitempty"` // the server was shut down by the migration
+	DisksDisconnected bool `json:"disks_disconnected,omitempty"`
+	DisksDeleted      bool `json:"disks_deleted,omitempty"` // the original disks are deleted
+
+	Error string `json:"error,omitempty"`
+}
+
+// JournalEntry is a line of the journal
+type JournalEntry struct {
+	RunID   string          `json:"run_id"`
+	Type    EventType       `json:"type"`
+	Time    time.Time       `json:"time"`
+	Servers []*ServerRecord `json:"servers"`
+}
+
+// JournalRun is the latest state of servers in a run folded from the journal
+type JournalRun struct {
+	RunID      string          `json:"run_id"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"` // zero if the run was interrupted
+	Servers    []*ServerRecord `json:"servers"`
+}
+
+// Server returns the record of the server, or nil if the server is not a target of the run
+func (r *JournalRun) Server(serverID int64) *ServerRecord {
+	for _, s := range r.Servers {
+		if s.ServerID == serverID {
+			return s
+		}
+	}
+	return nil
+}
+
+// Totals returns the number of servers in each state
+func (r *JournalRun) Totals() *Totals {
+	totals := &Totals{}
+	for _, s := range r.Servers {
+		totals.add(s.State)
+	}
+	return totals
+}
+
+// Journal is Listener appending the state of servers to the file at each event
+//
+// The journal is read by ReadJournal to show the status, resume, roll back or clean up after the run.
+type Journal struct {
+	path  string
+	runID string
+	lock  sync.Mutex
+	err   error
+}
+
+// NewJournal returns Journal appending entries of the run to the file
+func NewJournal(path, runID string) *Journal {
+	return &Journal{path: path, runID: runID}
+}
+
+// Notify appends the entry of the event, errors are returned by Err so as not to stop the migration
+func (j *Journal) Notify(event *Event) {
+	entry := &JournalEntry{
+		RunID:   j.runID,
+		Type:    event.Type,
+		Time:    event.Time,
+		Servers: event.records,
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if err := AppendJournal(j.path, entry); err != nil && j.err == nil {
+		j.err = err
+	}
+}
+
+// Err returns the first error of writing the journal
+func (j *Journal) Err() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.err
+}
+
+// AppendJournal appends the entry to the journal file
+func AppendJournal(path string, entry *JournalEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// ReadJournal reads the journal file and returns runs in the order of the start
+func ReadJournal(path string) ([]*JournalRun, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var runs []*JournalRun
+	index := map[string]*JournalRun{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		entry := &JournalEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return nil, fmt.Errorf("invalid journal at line %d: %s", line, err)
+		}
+		run, ok := index[entry.RunID]
+		if !ok {
+			run = &JournalRun{RunID: entry.RunID}
+			index[entry.RunID] = run
+			runs = append(runs, run)
+		}
+		switch entry.Type {
+		case EventRunStarted:
+			run.StartedAt = entry.Time
+		case EventRunFinished:
+			run.FinishedAt = entry.Time
+		}
+		for _, s := range entry.Servers {
+			if i := run.indexOf(s.ServerID); i >= 0 {
+				run.Servers[i] = s
+			} else {
+				run.Servers = append(run.Servers, s)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (r *JournalRun) indexOf(serverID int64) int {
+	for i, s := range r.Servers {
+		if s.ServerID == serverID {
+			return i
+		}
+	}
+	return -1
+}
+
+// RollbackServer restores the failed server recorded in the journal, like Migration.Rollback
+//
+// The record is updated as the rollback progresses, so it can be retried after an error.
+func RollbackServer(client iaas.Client, r *ServerRecord) error {
+	if r.State != ServerStateFailed {
+		return fmt.Errorf("Server[%d] is not failed: %s", r.ServerID, r.State)
+	}
+	if r.MigratedServerID != 0 {
+		return fmt.Errorf("Server[%d] can't be rolled back: plan is already changed to Server[%d]", r.ServerID, r.MigratedServerID)
+	}
+	if err := rollback(client, r, nil); err != nil {
+		return err
+	}
+	r.State = ServerStateRolledBack
+	return nil
+}
+
+// rollback connects the original disks again, deletes cloned disks and boots the server if it was shut down
+//
+// deleted is called with the index of each deleted cloned disk.
+func rollback(client iaas.Client, r *ServerRecord, deleted func(i int)) error {
+	if r.DisksDisconnected {
+		if err := client.DisconnectDisks(r.ServerID); err != nil {
+			return err
+		}
+		if err := client.ConnectDisks(r.ServerID, r.OriginalDiskIDs); err != nil {
+			return err
+		}
+		r.DisksDisconnected = false
+	}
+
+	for i, id := range r.ClonedDiskIDs {
+		if id != 0 {
+			if err := client.DeleteDisk(id); err != nil {
+				return err
+			}
+			r.ClonedDiskIDs[i] = 0
+			if deleted != nil {
+				deleted(i)
+			}
+		}
+	}
+
+	if r.ShutDown {
+		if err := client.Boot(r.ServerID); err != nil {
+			return err
+		}
+		r.ShutDown = false
+	}
+	return nil
+}
+
+// CleanupDisks deletes the original disks of the migrated server recorded in the journal
+//
+// Disks connected to any server are not deleted.
+func CleanupDisks(client iaas.Client, r *ServerRecord) error {
+	if r.State != ServerStateDone {
+		return fmt.Errorf("Server[%d] is not migrated: %s", r.ServerID, r.State)
+	}
+	if r.DisksDeleted {
+		return nil
+	}
+	for _, id := range r.OriginalDiskIDs {
+		disk, err := client.DiskByID(id)
+		if err != nil {
+			return err
+		}
+		if s := disk.Server; s != nil && s.Resource != nil && s.ID != 0 {
+			return fmt.Errorf("Disk[%d] is connected to Server[%d]", id, s.ID)
+		}
+	}
+	for _, id := range r.OriginalDiskIDs {
+		if err := client.DeleteDisk(id); err != nil {
+			return err
+		}
+	}
+	r.DisksDeleted = true
+	return nil
+}
+
+// recordedDisks returns the original disks of the server recorded in the journal
+func recordedDisks(client iaas.Client, r *ServerRecord) ([]sacloud.Disk, error) {
+	var disks []sacloud.Disk
+	for _, id := range r.OriginalDiskIDs {
+		disk, err := client.DiskByID(id)
+		if err != nil {
+			return nil, err
+		}
+		disks = append(disks, *disk)
+	}
+	return disks, nil
+}
+
+// restore marks steps finished in the run recorded in the journal as done, and reuses the cloned disks
+func (s *ServerStatus) restore(r *ServerRecord) {
+	if r.ShutDown {
+		s.stepShutdown.needProcess = true
+		s.stepShutdown.restore()
+	}
+	for i, id := range r.OriginalDiskIDs {
+		d := s.findDiskStatus(id)
+		if d == nil || i >= len(r.ClonedDiskIDs) || r.ClonedDiskIDs[i] == 0 {
+			continue
+		}
+		d.clonedID = r.ClonedDiskIDs[i]
+		d.stepClone.restore()
+	}
+	if r.DisksDisconnected && s.stepDisconnectDisks != nil {
+		s.stepDisconnectDisks.restore()
+	}
+}
+
+// record returns the current state of the server for the journal
+func (s *ServerStatus) record() *ServerRecord {
+	r := &ServerRecord{
+		ServerID:         s.targetServerID,
+		ServerName:       s.serverName,
+		State:            s.State(),
+		MigratedServerID: s.migratedServerID,
+		OriginalDiskIDs:  s.originalDiskIDs(),
+		ClonedDiskIDs:    s.clonedDiskIDs(),
+		ShutDown:         s.stepShutdown.needProcess && s.stepShutdown.started,
+	}
+	if s.stepDisconnectDisks != nil {
+		r.DisksDisconnected = s.stepDisconnectDisks.started
+	}
+	if r.State == ServerStateRolledBack {
+		// the original disks are connected again and the server is booted
+		r.ShutDown = false
+		r.DisksDisconnected = false
+	}
+	r.DisksDeleted = len(s.Disks) > 0
+	for _, st := range s.deleteDiskSteps() {
+		if st == nil || !st.needProcess || !st.done {
+			r.DisksDeleted = false
+		}
+	}
+	if err := s.GetErr(); err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
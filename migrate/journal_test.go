@@ -0,0 +1,138 @@
+package migrate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal.jsonl")
+
+	apply := func(client *fakeClient, runID string) {
+		journal := NewJournal(path, runID)
+		migration, err := NewMigration(client, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			Listeners:      []Listener{journal},
+		})
+		assert.NoError(t, err)
+		migration.Apply()
+		assert.NoError(t, journal.Err())
+	}
+	apply(&fakeClient{server: singleDiskServer()}, "run1")
+	apply(&fakeClient{server: singleDiskServer(), disconnectErr: fmt.Errorf("disconnect failed")}, "run2")
+
+	runs, err := ReadJournal(path)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 2)
+
+	done := runs[0].Server(serverID)
+	assert.Equal(t, "run1", runs[0].RunID)
+	assert.False(t, runs[0].FinishedAt.IsZero())
+	assert.Equal(t, ServerStateDone, done.State)
+	assert.Equal(t, migratedServerID, done.MigratedServerID)
+	assert.Equal(t, []int64{currentDiskID}, done.OriginalDiskIDs)
+	assert.False(t, done.DisksDeleted)
+
+	failed := runs[1].Server(serverID)
+	assert.Equal(t, 1, runs[1].Totals().Failed)
+	assert.Equal(t, ServerStateFailed, failed.State)
+	assert.Contains(t, failed.Error, "disconnect failed")
+	assert.True(t, failed.ShutDown)
+	assert.Equal(t, []int64{clonedDiskID}, failed.ClonedDiskIDs)
+
+	t.Run("rollback", func(t *testing.T) {
+		client := &fakeClient{server: singleDiskServer()}
+
+		assert.EqualError(t, RollbackServer(client, done), "Server[1] is not failed: done")
+
+		assert.NoError(t, RollbackServer(client, failed))
+		assert.Equal(t, ServerStateRolledBack, failed.State)
+		assert.Equal(t, []int64{clonedDiskID}, client.deletedDiskIDs)
+		assert.Equal(t, []int64{serverID}, client.bootedServerIDs)
+		assert.Equal(t, []int64{0}, failed.ClonedDiskIDs)
+
+		assert.NoError(t, AppendJournal(path, &JournalEntry{RunID: "run2", Type: EventServerRolledBack, Servers: []*ServerRecord{failed}}))
+		runs, err := ReadJournal(path)
+		assert.NoError(t, err)
+		assert.Equal(t, ServerStateRolledBack, runs[1].Server(serverID).State)
+	})
+
+	t.Run("cleanup", func(t *testing.T) {
+		client := &fakeClient{server: singleDiskServer()}
+
+		assert.NoError(t, CleanupDisks(client, done))
+		assert.True(t, done.DisksDeleted)
+		assert.Equal(t, []int64{currentDiskID}, client.deletedDiskIDs)
+
+		// already deleted
+		assert.NoError(t, CleanupDisks(client, done))
+		assert.Len(t, client.deletedDiskIDs, 1)
+	})
+}
+
+func TestMigration_Resume(t *testing.T) {
+
+	t.Run("after disconnecting disks", func(t *testing.T) {
+		// the server is shut down and its disks are disconnected by the interrupted run
+		server := singleDiskServer()
+		server.Disks = nil
+		server.Instance.Status = "down"
+		client := &fakeClient{server: server, disconnectErr: fmt.Errorf("disconnected again")}
+		record := &ServerRecord{
+			ServerID:          serverID,
+			State:             ServerStateRunning,
+			OriginalDiskIDs:   []int64{currentDiskID},
+			ClonedDiskIDs:     []int64{clonedDiskID},
+			ShutDown:          true,
+			DisksDisconnected: true,
+		}
+
+		migration, err := NewMigration(client, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			Servers:        map[int64]*ServerOptions{serverID: {Resume: record}},
+		})
+		assert.NoError(t, err)
+		migration.Apply()
+
+		assert.Empty(t, migration.HasErrors())
+		assert.Empty(t, client.clonedPlanIDs)
+		assert.Empty(t, client.calls)
+		assert.Equal(t, []int64{clonedDiskID}, client.connectedDiskIDs)
+		assert.Equal(t, []int64{migratedServerID}, client.bootedServerIDs)
+
+		resumed := migration.Status()[0].record()
+		assert.Equal(t, ServerStateDone, resumed.State)
+		assert.True(t, resumed.ShutDown)
+		assert.Equal(t, []int64{currentDiskID}, resumed.OriginalDiskIDs)
+	})
+
+	t.Run("after cloning disks", func(t *testing.T) {
+		client := &fakeClient{server: singleDiskServer()}
+		record := &ServerRecord{
+			ServerID:        serverID,
+			State:           ServerStateRunning,
+			OriginalDiskIDs: []int64{currentDiskID},
+			ClonedDiskIDs:   []int64{clonedDiskID},
+		}
+
+		migration, err := NewMigration(client, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			PreClone:       true,
+			Servers:        map[int64]*ServerOptions{serverID: {Resume: record}},
+		})
+		assert.NoError(t, err)
+		migration.Apply()
+
+		assert.Empty(t, migration.HasErrors())
+		assert.Equal(t, []string{"Shutdown"}, client.calls)
+		assert.Equal(t, []int64{clonedDiskID}, client.connectedDiskIDs)
+	})
+}
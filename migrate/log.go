@@ -1,5 +1,63 @@
 package migrate
 
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/logging"
+)
+
+// maxServerLogLines is the number of log lines kept for each server
+const maxServerLogLines = 200
+
 type Logger interface {
 	Printf(string, ...interface{})
 }
+
+// StructuredLogger is a Logger accepting leveled logs with key/value fields
+//
+// If Options.Logger implements it, logs of the migration are passed with fields
+// such as server_id, disk_id, step, elapsed and error instead of formatted text.
+type StructuredLogger interface {
+	Logger
+	Log(level logging.Level, msg string, keyvals ...interface{})
+}
+
+// serverLogger keeps recent log lines of a server and passes them to the base logger
+type serverLogger struct {
+	base       Logger
+	serverID   int64
+	serverName string
+	lines      []string
+	lock       sync.Mutex
+}
+
+func (l *serverLogger) log(level logging.Level, msg string, keyvals ...interface{}) {
+	fields := logging.FormatFields(keyvals...)
+	if fields != "" {
+		fields = " " + fields
+	}
+
+	l.lock.Lock()
+	l.lines = append(l.lines, fmt.Sprintf("%s %-5s %s%s", time.Now().Format("15:04:05"), strings.ToUpper(level.String()), msg, fields))
+	if len(l.lines) > maxServerLogLines {
+		l.lines = l.lines[len(l.lines)-maxServerLogLines:]
+	}
+	l.lock.Unlock()
+
+	switch base := l.base.(type) {
+	case nil:
+	case StructuredLogger:
+		base.Log(level, msg, append([]interface{}{"server_id", l.serverID, "server_name", l.serverName}, keyvals...)...)
+	default:
+		base.Printf(": Server[%d:%s] : %s%s%s", l.serverID, l.serverName, msg, fields, newline)
+	}
+}
+
+func (l *serverLogger) Lines() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]string{}, l.lines...)
+}
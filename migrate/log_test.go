@@ -0,0 +1,110 @@
+package migrate
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sacloud/cloud-plan-migrate/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+type logEntry struct {
+	level  logging.Level
+	msg    string
+	fields map[string]interface{}
+}
+
+type recordLogger struct {
+	entries []*logEntry
+	lines   []string
+	lock    sync.Mutex
+}
+
+func (l *recordLogger) Printf(format string, v ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *recordLogger) Log(level logging.Level, msg string, keyvals ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	fields := map[string]interface{}{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields[fmt.Sprint(keyvals[i])] = keyvals[i+1]
+	}
+	l.entries = append(l.entries, &logEntry{level: level, msg: msg, fields: fields})
+}
+
+func (l *recordLogger) find(msg string) *logEntry {
+	for _, e := range l.entries {
+		if e.msg == msg {
+			return e
+		}
+	}
+	return nil
+}
+
+type printfLogger struct {
+	lines []string
+	lock  sync.Mutex
+}
+
+func (l *printfLogger) Printf(format string, v ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestMigration_StructuredLog(t *testing.T) {
+	fakeClient := &fakeClient{
+		server:        singleDiskServer(),
+		disconnectErr: fmt.Errorf("disconnect failed"),
+	}
+	logger := &recordLogger{}
+
+	migration, err := NewMigration(fakeClient, []int64{serverID}, &Options{
+		MaxWorkerCount: 1,
+		Logger:         logger,
+	})
+	assert.NoError(t, err)
+	migration.Apply()
+
+	assert.Empty(t, logger.lines)
+
+	clone := logger.find("Clone Disk finished")
+	if assert.NotNil(t, clone) {
+		assert.Equal(t, logging.LevelInfo, clone.level)
+		assert.Equal(t, serverID, clone.fields["server_id"])
+		assert.Equal(t, "clone_disk", clone.fields["step"])
+		assert.Equal(t, int64(2), clone.fields["disk_id"])
+		assert.Contains(t, clone.fields, "elapsed")
+	}
+
+	disconnect := logger.find("Disconnect Disk error")
+	if assert.NotNil(t, disconnect) {
+		assert.Equal(t, logging.LevelError, disconnect.level)
+		assert.Equal(t, "disconnect_disk", disconnect.fields["step"])
+		assert.EqualError(t, disconnect.fields["error"].(error), "disconnect failed")
+	}
+}
+
+func TestMigration_PrintfLog(t *testing.T) {
+	fakeClient := &fakeClient{
+		server: singleDiskServer(),
+	}
+	logger := &printfLogger{}
+
+	migration, err := NewMigration(fakeClient, []int64{serverID}, &Options{
+		MaxWorkerCount: 1,
+		Logger:         logger,
+	})
+	assert.NoError(t, err)
+	migration.Apply()
+
+	server := fakeClient.server
+	assert.Contains(t, logger.lines,
+		fmt.Sprintf(": Server[%d:%s] : Clone Disk started step=clone_disk disk_id=2%s", server.ID, server.Name, newline))
+	assert.NotEmpty(t, migration.Status()[0].Logs())
+}
@@ -3,16 +3,91 @@ package migrate
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/sacloud/cloud-plan-migrate/iaas"
+	"github.com/sacloud/cloud-plan-migrate/logging"
 	"github.com/sacloud/libsacloud/sacloud"
 )
 
+// DefaultMaxWorkerCount is the number of servers migrated in parallel
+const DefaultMaxWorkerCount = 10
+
 type Options struct {
 	DisableBoot    bool
 	DeleteDisks    bool
+	StrictVerify   bool
 	MaxWorkerCount int
 	Logger         Logger
+	DiskPlanID     int64
+	Hooks          *Hooks
+
+	ReferenceUpdaters []ReferenceUpdater
+	Protection        *Protection
+
+	// History records the clone throughput of this run if not nil
+	History *ThroughputHistory
+
+	// PreClone clones disks before shutting down servers to minimize downtime
+	//
+	// The copy is crash-consistent, writes after the clone started are not migrated
+	// unless the BeforeShutdown hook freezes the filesystem.
+	PreClone bool
+
+	// Windows restricts the time to shut down servers, servers can be shut down at any time if empty
+	Windows Schedule
+
+	// Listeners receive events of the migration
+	Listeners []Listener
+
+	// Servers overrides options for each server, keyed by server ID
+	Servers map[int64]*ServerOptions
+}
+
+// ServerOptions overrides Options for a server
+//
+// Nil or zero values mean that the value of Options is used.
+type ServerOptions struct {
+	DisableBoot *bool
+	DeleteDisks *bool
+	Core        int
+	MemoryGB    int
+	DiskPlanID  int64
+	Hooks       *Hooks
+	PreClone    *bool
+
+	// Resume is the record of the server interrupted in the previous run,
+	// steps finished in the run are not processed again and the cloned disks are reused
+	Resume *ServerRecord
+}
+
+// Hook is called at each phase of the server migration, the migration of the server is aborted if it returns error
+type Hook func(status *ServerStatus) error
+
+type Hooks struct {
+	BeforeShutdown Hook // before cloning disks and shutting down the server
+	AfterMigrate   Hook // after the plan is changed and the disks are connected(and the server is booted)
+	AfterBoot      Hook // after the server is booted, not called if the boot is disabled
+}
+
+// mergeHooks returns hooks of base overridden by non-nil hooks of override
+func mergeHooks(base, override *Hooks) *Hooks {
+	merged := &Hooks{}
+	if base != nil {
+		*merged = *base
+	}
+	if override != nil {
+		if override.BeforeShutdown != nil {
+			merged.BeforeShutdown = override.BeforeShutdown
+		}
+		if override.AfterMigrate != nil {
+			merged.AfterMigrate = override.AfterMigrate
+		}
+		if override.AfterBoot != nil {
+			merged.AfterBoot = override.AfterBoot
+		}
+	}
+	return merged
 }
 
 type Migration struct {
@@ -21,6 +96,18 @@ type Migration struct {
 	working        []*ServerStatus
 	lock           sync.Mutex
 	maxWorkerCount int
+	strictVerify   bool
+	logger         Logger
+	updaters       []ReferenceUpdater
+	history        *ThroughputHistory
+	windows        Schedule
+	listeners      []Listener
+	startedAt      time.Time
+	finishedAt     time.Time
+
+	paused   bool
+	draining bool
+	resumed  *sync.Cond
 }
 
 func NewMigration(client iaas.Client, serverIDs []int64, options *Options) (*Migration, error) {
@@ -29,41 +116,89 @@ func NewMigration(client iaas.Client, serverIDs []int64, options *Options) (*Mig
 	if options == nil {
 		options = &Options{}
 	}
+	protected := &ProtectedError{}
 
 	for _, id := range serverIDs {
 
-		s := &ServerStatus{targetServerID: id}
+		s := &ServerStatus{targetServerID: id, logger: &serverLogger{base: options.Logger}}
+		serverOptions := options.Servers[id]
+		if serverOptions == nil {
+			serverOptions = &ServerOptions{}
+		}
+		disableBoot := options.DisableBoot
+		if serverOptions.DisableBoot != nil {
+			disableBoot = *serverOptions.DisableBoot
+		}
+		deleteDisks := options.DeleteDisks
+		if serverOptions.DeleteDisks != nil {
+			deleteDisks = *serverOptions.DeleteDisks
+		}
+		diskPlanID := options.DiskPlanID
+		if serverOptions.DiskPlanID != 0 {
+			diskPlanID = serverOptions.DiskPlanID
+		}
+		s.preClone = options.PreClone
+		if serverOptions.PreClone != nil {
+			s.preClone = *serverOptions.PreClone
+		}
+		s.hooks = mergeHooks(options.Hooks, serverOptions.Hooks)
 
 		server, err := client.ServerByID(id)
 		if err != nil {
 			return nil, err
 		}
 
+		if reason := options.Protection.Reason(server); reason != "" {
+			protected.Servers = append(protected.Servers, &ProtectedServer{ID: server.ID, Name: server.Name, Reason: reason})
+			continue
+		}
+
 		s.serverName = server.Name
-		serverLogPref := fmt.Sprintf(": Server[%d:%s] :", server.ID, server.Name)
+		s.originalInterfaces = server.Interfaces
+		s.logger.serverID = server.ID
+		s.logger.serverName = server.Name
 
-		newPlan, err := client.FindServerPlan(server.GetCPU(), server.GetMemoryGB())
+		core, memoryGB := server.GetCPU(), server.GetMemoryGB()
+		if serverOptions.Core > 0 && serverOptions.MemoryGB > 0 {
+			core, memoryGB = serverOptions.Core, serverOptions.MemoryGB
+		}
+		newPlan, err := client.FindServerPlan(core, memoryGB)
 		if err != nil {
 			return nil, err
 		}
 		s.newPlan = newPlan
 
-		var disks []*DiskStatus
-		for _, disk := range server.Disks {
-			diskLogPref := fmt.Sprintf(":   Disk[%d:%s] :", disk.ID, server.Name) // サーバ名を利用
+		serverDisks := server.Disks
+		if serverOptions.Resume != nil && serverOptions.Resume.DisksDisconnected {
+			// the original disks are already disconnected from the server
+			serverDisks, err = recordedDisks(client, serverOptions.Resume)
+			if err != nil {
+				return nil, err
+			}
+		}
 
+		var disks []*DiskStatus
+		for _, disk := range serverDisks {
+			sourcePlanID := diskPlanID
+			if sourcePlanID == 0 {
+				sourcePlanID = disk.GetPlanID()
+			}
 			d := &DiskStatus{
-				originalID: disk.ID,
-				sizeMB:     disk.GetSizeMB(),
+				originalID:   disk.ID,
+				sizeMB:       disk.GetSizeMB(),
+				planID:       diskPlanID,
+				clonedPlanID: iaas.ClonedDiskPlanID(sourcePlanID, disk.GetSizeGB()),
 				stepClone: &step{
 					needProcess: true,
-					logger:      options.Logger,
-					logPrefix:   fmt.Sprintf("%s %s", diskLogPref, "Clone Disk"),
+					logger:      s.logger,
+					name:        "Clone Disk",
+					fields:      []interface{}{"disk_id", disk.ID},
 				},
 				stepDelete: &step{
-					needProcess: options.DeleteDisks,
-					logger:      options.Logger,
-					logPrefix:   fmt.Sprintf("%s %s", diskLogPref, "Delete Disk"),
+					needProcess: deleteDisks,
+					logger:      s.logger,
+					name:        "Delete Disk",
+					fields:      []interface{}{"disk_id", disk.ID},
 				},
 			}
 			disks = append(disks, d)
@@ -72,39 +207,67 @@ func NewMigration(client iaas.Client, serverIDs []int64, options *Options) (*Mig
 		if len(disks) > 0 {
 			s.stepConnectDisks = &step{
 				needProcess: true,
-				logger:      options.Logger,
-				logPrefix:   fmt.Sprintf("%s %s", serverLogPref, "Connect Disk"),
+				logger:      s.logger,
+				name:        "Connect Disk",
 			}
 			s.stepDisconnectDisks = &step{
 				needProcess: true,
-				logger:      options.Logger,
-				logPrefix:   fmt.Sprintf("%s %s", serverLogPref, "Disconnect Disk"),
+				logger:      s.logger,
+				name:        "Disconnect Disk",
 			}
 		}
 		s.stepShutdown = &step{
 			needProcess: server.IsUp(),
-			logger:      options.Logger,
-			logPrefix:   fmt.Sprintf("%s %s", serverLogPref, "Shutdown Server"),
+			logger:      s.logger,
+			name:        "Shutdown Server",
 		}
 		s.stepPlanMigrate = &step{
 			needProcess: true,
-			logger:      options.Logger,
-			logPrefix:   fmt.Sprintf("%s %s", serverLogPref, "Migrate Server Plan"),
+			logger:      s.logger,
+			name:        "Migrate Server Plan",
+		}
+		s.stepUpdateReferences = &step{
+			needProcess: len(options.ReferenceUpdaters) > 0,
+			logger:      s.logger,
+			name:        "Update References",
+		}
+		s.stepVerify = &step{
+			needProcess: true,
+			logger:      s.logger,
+			name:        "Verify Network",
 		}
 		s.stepBoot = &step{
-			needProcess: !options.DisableBoot,
-			logger:      options.Logger,
-			logPrefix:   fmt.Sprintf("%s %s", serverLogPref, "Boot Server"),
+			needProcess: !disableBoot,
+			logger:      s.logger,
+			name:        "Boot Server",
+		}
+		if serverOptions.Resume != nil {
+			s.restore(serverOptions.Resume)
 		}
 
 		status = append(status, s)
 	}
 
-	return &Migration{
+	if len(protected.Servers) > 0 {
+		return nil, protected
+	}
+
+	m := &Migration{
 		client:         client,
 		status:         status,
 		maxWorkerCount: options.MaxWorkerCount,
-	}, nil
+		strictVerify:   options.StrictVerify,
+		logger:         options.Logger,
+		updaters:       options.ReferenceUpdaters,
+		history:        options.History,
+		windows:        options.Windows,
+		listeners:      options.Listeners,
+	}
+	m.resumed = sync.NewCond(&m.lock)
+	for _, s := range status {
+		s.lock = &m.lock
+	}
+	return m, nil
 }
 
 func (m *Migration) Apply() {
@@ -113,19 +276,51 @@ func (m *Migration) Apply() {
 	wg.Add(len(m.status))
 
 	limit := make(chan struct{}, m.maxWorkerCount)
+	m.startedAt = time.Now()
+	m.emit(EventRunStarted, nil)
 
 	for i := range m.status {
 		go func(status *ServerStatus) {
-			limit <- struct{}{}
+			defer wg.Done()
+
+			// wait for the window before taking a worker, not to block servers which can be started now
+			for {
+				ok, err := m.waitForWindow(status)
+				if err != nil {
+					m.setErr(status, err)
+					m.emit(EventServerFailed, status)
+					return
+				}
+				if !ok {
+					return
+				}
+				limit <- struct{}{}
+				if wait, err := m.windowDelay(status, time.Now()); err == nil && wait <= 0 {
+					break
+				}
+				// the window is closed while waiting for a worker
+				<-limit
+			}
+			defer func() { <-limit }()
+
+			if !m.waitForStart(status) {
+				return
+			}
 			m.addWorking(status)
 			m.applyServer(status)
 			m.removeWorking(status)
-			<-limit
-			wg.Done()
+
+			if status.GetErr() != nil {
+				m.emit(EventServerFailed, status)
+			} else {
+				m.emit(EventServerCompleted, status)
+			}
 		}(m.status[i])
 	}
 
 	wg.Wait()
+	m.finishedAt = time.Now()
+	m.emit(EventRunFinished, nil)
 }
 
 func (m *Migration) Working() []*ServerStatus {
@@ -137,7 +332,7 @@ func (m *Migration) Working() []*ServerStatus {
 func (m *Migration) HasErrors() []*ServerStatus {
 	var errs []*ServerStatus
 	for _, s := range m.status {
-		if s.Err != nil {
+		if s.GetErr() != nil {
 			errs = append(errs, s)
 		}
 	}
@@ -146,14 +341,17 @@ func (m *Migration) HasErrors() []*ServerStatus {
 
 func (m *Migration) addWorking(status *ServerStatus) {
 	m.lock.Lock()
-	defer m.lock.Unlock()
-
+	status.state = ServerStateRunning
 	m.working = append(m.working, status)
+	m.lock.Unlock()
+
+	m.emit(EventServerStarted, status)
 }
 
 func (m *Migration) removeWorking(status *ServerStatus) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
+	status.state = ServerStateDone
 	var result []*ServerStatus
 	for _, v := range m.working {
 		if v != status {
@@ -164,53 +362,144 @@ func (m *Migration) removeWorking(status *ServerStatus) {
 }
 
 func (m *Migration) applyServer(status *ServerStatus) {
-	// shutdown(if need)
-	if err := m.handleSteps(m.shutdownServer, status, status.stepShutdown); err != nil {
+	if err := m.runHook("BeforeShutdown", status.hooks.BeforeShutdown, status); err != nil {
 		return
 	}
 
-	// clone disk
-	if err := m.handleSteps(m.cloneDisks, status, status.cloneDiskSteps()...); err != nil {
-		return
+	if status.preClone {
+		// clone disk while the server is up
+		status.logger.log(logging.LevelWarn, "pre-clone: writes after cloning started are not migrated")
+		if err := m.handleSteps(m.cloneDisks, status, status.cloneDiskSteps()...); err != nil {
+			return
+		}
+		m.emitStep(EventDisksCloned, status, status.cloneDiskSteps()...)
+
+		// verify cloned disks before shutdown, so the server keeps running if the copy is broken
+		if err := m.verifyClonedDisks(status); err != nil {
+			m.setErr(status, err)
+			return
+		}
+
+		// the window may be closed during the long copy, so wait for the window again before shutting down
+		ok, err := m.waitForWindow(status)
+		if err == nil && !ok {
+			err = fmt.Errorf("migration is drained before shutdown, cloned disks are left")
+		}
+		if err != nil {
+			m.setErr(status, err)
+			return
+		}
+
+		// shutdown(if need)
+		if err := m.handleSteps(m.shutdownServer, status, status.stepShutdown); err != nil {
+			return
+		}
+		m.emitStep(EventServerShutDown, status, status.stepShutdown)
+	} else {
+		// shutdown(if need)
+		if err := m.handleSteps(m.shutdownServer, status, status.stepShutdown); err != nil {
+			return
+		}
+		m.emitStep(EventServerShutDown, status, status.stepShutdown)
+
+		// clone disk
+		if err := m.handleSteps(m.cloneDisks, status, status.cloneDiskSteps()...); err != nil {
+			return
+		}
+		m.emitStep(EventDisksCloned, status, status.cloneDiskSteps()...)
 	}
 
 	// disconnect disk
 	if err := m.handleSteps(m.disconnectDisks, status, status.stepDisconnectDisks); err != nil {
 		return
 	}
+	m.emitStep(EventDisksDisconnected, status, status.stepDisconnectDisks)
 
 	// migrate server plan
 	if err := m.handleSteps(m.migrateServerPlan, status, status.stepPlanMigrate); err != nil {
 		return
 	}
+	m.emitStep(EventServerPlanChanged, status, status.stepPlanMigrate)
 
 	// connect disk
 	if err := m.handleSteps(m.connectDisks, status, status.stepConnectDisks); err != nil {
 		return
 	}
 
+	// verify network interfaces
+	if err := m.handleSteps(m.verifyNetwork, status, status.stepVerify); err != nil {
+		return
+	}
+
 	// boot server
 	if err := m.handleSteps(m.bootServer, status, status.stepBoot); err != nil {
 		return
 	}
 
+	// references and hooks are processed after the disks are connected and the server is booted,
+	// not to leave the server diskless by errors
+	if err := m.handleSteps(m.updateReferences, status, status.stepUpdateReferences); err != nil {
+		return
+	}
+
+	if err := m.runHook("AfterMigrate", status.hooks.AfterMigrate, status); err != nil {
+		return
+	}
+
+	if status.stepBoot.needProcess {
+		if err := m.runHook("AfterBoot", status.hooks.AfterBoot, status); err != nil {
+			return
+		}
+	}
+
 	// delete disk
 	if err := m.handleSteps(m.deleteDisks, status, status.deleteDiskSteps()...); err != nil {
 		return
 	}
 }
 
+// setErr sets the error of the migration of the server
+func (m *Migration) setErr(status *ServerStatus, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	status.Err = err
+}
+
+func (m *Migration) runHook(name string, hook Hook, status *ServerStatus) error {
+	if hook == nil {
+		return nil
+	}
+	if err := hook(status); err != nil {
+		err = fmt.Errorf("%s hook is failed: %s", name, err)
+		status.logger.log(logging.LevelError, "hook is failed", "hook", name, "error", err)
+		m.setErr(status, err)
+		return err
+	}
+	return nil
+}
+
 func (m *Migration) handleSteps(stepFunc func(*ServerStatus) error, status *ServerStatus, steps ...*step) error {
+	// steps restored from the journal are already done
+	var pending []*step
 	for _, step := range steps {
+		if !step.done {
+			pending = append(pending, step)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	for _, step := range pending {
 		step.start()
 	}
 
 	if err := stepFunc(status); err != nil {
-		status.Err = err
+		m.setErr(status, err)
 		return err
 	}
 
-	for _, step := range steps {
+	for _, step := range pending {
 		step.finalize()
 	}
 
@@ -239,43 +528,53 @@ func (m *Migration) disconnectDisks(status *ServerStatus) error {
 }
 
 func (m *Migration) cloneDisks(status *ServerStatus) error {
-	errC := make(chan error)
+	// buffered so that goroutines can exit even after the first error is returned
+	errC := make(chan error, len(status.Disks))
 	doneC := make(chan bool)
 	var wg sync.WaitGroup
 	wg.Add(len(status.Disks))
 
 	for _, disk := range status.Disks {
 		go func(status *DiskStatus) {
-			if disk.stepClone.needProcess {
-				progress, err := m.client.CloneDisk(status.originalID)
-				if err != nil {
-					disk.stepClone.setError(err)
-					errC <- err
-					return
-				}
+			defer wg.Done()
+			if !status.stepClone.needProcess || status.stepClone.done {
+				return
+			}
 
-				var newDisk *sacloud.Disk
-				for {
-					res, ok := <-progress
-					if !ok && newDisk != nil {
-						status.clonedID = newDisk.ID
-						status.migratedMB = newDisk.GetMigratedMB()
-						status.stepClone.finalize()
-						break
-					}
-					switch d := res.(type) {
-					case *sacloud.Disk:
-						status.clonedID = d.ID
-						status.migratedMB = d.GetMigratedMB()
-						newDisk = d
-					case error:
-						status.stepClone.setError(d)
-						errC <- d
+			progress, err := m.client.CloneDisk(status.originalID, status.planID)
+			if err != nil {
+				status.stepClone.setError(err)
+				errC <- err
+				return
+			}
+
+			var newDisk *sacloud.Disk
+			for {
+				res, ok := <-progress
+				if !ok {
+					if newDisk == nil {
+						err := fmt.Errorf("cloning Disk[%d] is finished without result", status.originalID)
+						status.stepClone.setError(err)
+						errC <- err
 						return
 					}
+					status.clonedID = newDisk.ID
+					status.updateProgress(newDisk.GetMigratedMB())
+					status.stepClone.finalize()
+					m.history.Record(diskPlanName(status.clonedPlanID), status.sizeMB, status.stepClone.elapsed())
+					return
+				}
+				switch d := res.(type) {
+				case *sacloud.Disk:
+					status.clonedID = d.ID
+					status.updateProgress(d.GetMigratedMB())
+					newDisk = d
+				case error:
+					status.stepClone.setError(d)
+					errC <- d
+					return
 				}
 			}
-			wg.Done()
 		}(disk)
 	}
 
@@ -295,6 +594,26 @@ func (m *Migration) cloneDisks(status *ServerStatus) error {
 
 }
 
+// verifyClonedDisks checks that disks cloned before shutdown are available and completely copied from the original disks
+func (m *Migration) verifyClonedDisks(status *ServerStatus) error {
+	for _, d := range status.Disks {
+		disk, err := m.client.DiskByID(d.clonedID)
+		if err != nil {
+			return err
+		}
+		if !disk.IsAvailable() {
+			return fmt.Errorf("cloned Disk[%d] is not available", d.clonedID)
+		}
+		if source := disk.GetSourceDiskID(); source != d.originalID {
+			return fmt.Errorf("cloned Disk[%d] is copied from Disk[%d], not Disk[%d]", d.clonedID, source, d.originalID)
+		}
+		if disk.GetSizeMB() < d.sizeMB || disk.GetMigratedMB() < disk.GetSizeMB() {
+			return fmt.Errorf("cloned Disk[%d] is not completely copied: %d/%dMB", d.clonedID, disk.GetMigratedMB(), d.sizeMB)
+		}
+	}
+	return nil
+}
+
 func (m *Migration) migrateServerPlan(status *ServerStatus) error {
 	if status.stepPlanMigrate.needProcess {
 		newServer, err := m.client.ChangePlan(status.targetServerID, status.newPlan)
@@ -317,6 +636,66 @@ func (m *Migration) connectDisks(status *ServerStatus) error {
 	return nil
 }
 
+// References returns references to target servers without updating them
+func (m *Migration) References() (map[int64][]*Reference, error) {
+	references := make(map[int64][]*Reference)
+	for _, status := range m.status {
+		for _, updater := range m.updaters {
+			refs, err := updater.FindReferences(status.targetServerID)
+			if err != nil {
+				return nil, err
+			}
+			references[status.targetServerID] = append(references[status.targetServerID], refs...)
+		}
+	}
+	return references, nil
+}
+
+func (m *Migration) updateReferences(status *ServerStatus) error {
+	if status.stepUpdateReferences.needProcess {
+		for _, updater := range m.updaters {
+			refs, err := updater.FindReferences(status.targetServerID)
+			if err != nil {
+				status.stepUpdateReferences.setError(err)
+				return err
+			}
+			status.References = append(status.References, refs...)
+
+			for _, ref := range refs {
+				if err := updater.UpdateReference(ref, status.targetServerID, status.migratedServerID); err != nil {
+					status.stepUpdateReferences.setError(err)
+					return err
+				}
+				ref.Updated = true
+				status.stepUpdateReferences.log(logging.LevelInfo, "updated", "reference", ref.String())
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Migration) verifyNetwork(status *ServerStatus) error {
+	if status.stepVerify.needProcess {
+		server, err := m.client.ServerByID(status.migratedServerID)
+		if err != nil {
+			status.stepVerify.setError(err)
+			return err
+		}
+		status.migratedInterfaces = server.Interfaces
+		status.InterfaceDiffs = diffInterfaces(status.originalInterfaces, status.migratedInterfaces)
+
+		for _, diff := range status.InterfaceDiffs {
+			status.stepVerify.log(logging.LevelWarn, "warning", "diff", diff.String())
+		}
+		if m.strictVerify && len(status.InterfaceDiffs) > 0 {
+			err := fmt.Errorf("network interfaces are changed after plan migration: %d differences", len(status.InterfaceDiffs))
+			status.stepVerify.setError(err)
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *Migration) bootServer(status *ServerStatus) error {
 	if status.stepBoot.needProcess {
 		// boot
@@ -1,17 +1,23 @@
 package migrate
 
 import (
+	"bytes"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/sacloud/cloud-plan-migrate/iaas"
 	"github.com/sacloud/libsacloud/sacloud"
 	"github.com/stretchr/testify/assert"
 )
 
 var (
-	serverID      = int64(1)
-	currentDiskID = int64(2)
-	emptyID       = int64(0)
+	serverID         = int64(1)
+	currentDiskID    = int64(2)
+	emptyID          = int64(0)
+	migratedServerID = int64(3)
+	clonedDiskID     = int64(4)
 )
 
 func singleDiskServer() *sacloud.Server {
@@ -41,41 +47,128 @@ func multipleDiskServer() *sacloud.Server {
 }
 
 type fakeClient struct {
-	server *sacloud.Server
+	server        *sacloud.Server
+	servers       map[int64]*sacloud.Server // returned by ServerByID instead of server if exists
+	resources     []*iaas.TaggedResource
+	updated       []*iaas.TaggedResource
+	clonedPlanIDs []int64
+
+	disconnectErr    error // returned once
+	connectedDiskIDs []int64
+	deletedDiskIDs   []int64
+	bootedServerIDs  []int64
+	diskUnavailable  bool
+	diskIncomplete   bool
+	calls            []string
+
+	lock sync.Mutex // guards slices updated by workers and disks cloned in parallel
 }
 
 func (f *fakeClient) FindAll() ([]*sacloud.Server, error) {
 	// not implements
 	return nil, nil
 }
+func (f *fakeClient) Find(param *iaas.FindParameter) ([]*sacloud.Server, error) {
+	// not implements
+	return nil, nil
+}
 func (f *fakeClient) ServerByID(id int64) (*sacloud.Server, error) {
+	if s, ok := f.servers[id]; ok {
+		return s, nil
+	}
 	return f.server, nil
 }
 func (f *fakeClient) DiskByID(id int64) (*sacloud.Disk, error) {
-	return nil, nil
+	disk := &sacloud.Disk{Resource: sacloud.NewResource(id)}
+	disk.SizeMB = 20 * 1024
+	disk.MigratedMB = disk.SizeMB
+	if f.diskIncomplete {
+		disk.MigratedMB = disk.SizeMB / 2
+	}
+	if id == clonedDiskID {
+		disk.SetSourceDisk(currentDiskID)
+	}
+	if !f.diskUnavailable {
+		disk.Availability = sacloud.EAAvailable
+	}
+	return disk, nil
 }
 func (f *fakeClient) FindServerPlan(core int, memoryGB int) (*sacloud.ProductServer, error) {
 	return nil, nil
 }
 func (f *fakeClient) Shutdown(id int64) (err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls = append(f.calls, "Shutdown")
 	return nil
 }
 func (f *fakeClient) DisconnectDisks(serverID int64) error {
-	return nil
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	err := f.disconnectErr
+	f.disconnectErr = nil
+	return err
 }
-func (f *fakeClient) CloneDisk(id int64) (<-chan interface{}, error) {
-	return nil, nil
+func (f *fakeClient) CloneDisk(id int64, planID int64) (<-chan interface{}, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.clonedPlanIDs = append(f.clonedPlanIDs, planID)
+	f.calls = append(f.calls, "CloneDisk")
+	progress := make(chan interface{}, 1)
+	disk := &sacloud.Disk{Resource: sacloud.NewResource(clonedDiskID)}
+	disk.SizeMB = 20 * 1024
+	disk.MigratedMB = disk.SizeMB
+	progress <- disk
+	close(progress)
+	return progress, nil
 }
 func (f *fakeClient) ChangePlan(serverID int64, plan *sacloud.ProductServer) (*sacloud.Server, error) {
-	return nil, nil
+	return &sacloud.Server{Resource: sacloud.NewResource(migratedServerID)}, nil
 }
 func (f *fakeClient) ConnectDisks(serverID int64, diskIDs []int64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.connectedDiskIDs = append(f.connectedDiskIDs, diskIDs...)
 	return nil
 }
 func (f *fakeClient) Boot(id int64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.bootedServerIDs = append(f.bootedServerIDs, id)
 	return nil
 }
 func (f *fakeClient) DeleteDisk(id int64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.deletedDiskIDs = append(f.deletedDiskIDs, id)
+	return nil
+}
+func (f *fakeClient) FindTaggedResources() ([]*iaas.TaggedResource, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls = append(f.calls, "FindTaggedResources")
+	return f.resources, nil
+}
+func (f *fakeClient) TaggedResourceByID(resourceType string, id int64) (*iaas.TaggedResource, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, r := range f.resources {
+		if r.Type == resourceType && r.ID == id {
+			copied := *r
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("resource[%s:%d] is not found", resourceType, id)
+}
+func (f *fakeClient) UpdateTaggedResource(resource *iaas.TaggedResource) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for i, r := range f.resources {
+		if r.Type == resource.Type && r.ID == resource.ID {
+			f.resources[i] = resource
+		}
+	}
+	f.updated = append(f.updated, resource)
 	return nil
 }
 
@@ -116,6 +209,9 @@ func TestMigration_NewMigration(t *testing.T) {
 		assert.NotNil(t, status.stepPlanMigrate)
 		assert.True(t, status.stepPlanMigrate.needProcess)
 
+		assert.NotNil(t, status.stepVerify)
+		assert.True(t, status.stepVerify.needProcess)
+
 		assert.NotNil(t, status.stepBoot)
 		assert.True(t, status.stepBoot.needProcess)
 
@@ -172,6 +268,226 @@ func TestMigration_NewMigration(t *testing.T) {
 		assert.False(t, status.stepBoot.needProcess)
 		assert.True(t, status.Disks[0].stepDelete.needProcess)
 	})
+
+	t.Run("with server options", func(t *testing.T) {
+
+		fakeClient := &fakeClient{
+			server: singleDiskServer(),
+		}
+
+		disableBoot, deleteDisks := false, false
+		migration, err := NewMigration(fakeClient, []int64{serverID}, &Options{
+			DisableBoot: true,
+			DeleteDisks: true,
+			Servers: map[int64]*ServerOptions{
+				serverID: {
+					DisableBoot: &disableBoot,
+					DeleteDisks: &deleteDisks,
+				},
+			},
+		})
+		assert.NoError(t, err)
+
+		status := migration.status[0]
+		assert.True(t, status.stepBoot.needProcess)
+		assert.False(t, status.Disks[0].stepDelete.needProcess)
+	})
+}
+
+func TestMigration_NewMigrationWithProtection(t *testing.T) {
+
+	server := singleDiskServer()
+	server.Name = "db-01"
+	server.Tags = []string{"production"}
+
+	expects := []struct {
+		protection *Protection
+		reason     string
+	}{
+		{protection: nil},
+		{protection: &Protection{IDs: []int64{2}, Names: []string{"web-*"}, Tags: []string{"dev"}}},
+		{protection: &Protection{IDs: []int64{serverID}}, reason: "id 1"},
+		{protection: &Protection{Names: []string{"db-*"}}, reason: `name "db-*"`},
+		{protection: &Protection{Tags: []string{"production"}}, reason: `tag "production"`},
+	}
+
+	for _, expect := range expects {
+		migration, err := NewMigration(&fakeClient{server: server}, []int64{serverID}, &Options{
+			Protection: expect.protection,
+		})
+
+		if expect.reason == "" {
+			assert.NoError(t, err)
+			assert.NotNil(t, migration)
+			continue
+		}
+
+		assert.Nil(t, migration)
+		assert.IsType(t, &ProtectedError{}, err)
+		protected := err.(*ProtectedError).Servers
+		assert.Len(t, protected, 1)
+		assert.Equal(t, &ProtectedServer{ID: serverID, Name: "db-01", Reason: expect.reason}, protected[0])
+	}
+}
+
+func TestMigration_Apply(t *testing.T) {
+
+	t.Run("with hooks", func(t *testing.T) {
+		fakeClient := &fakeClient{
+			server: singleDiskServer(),
+		}
+
+		var called []string
+		hook := func(name string) Hook {
+			return func(status *ServerStatus) error {
+				called = append(called, fmt.Sprintf("%s:%s:%s", name, status.ServerID(), status.MigratedServerID()))
+				return nil
+			}
+		}
+
+		migration, err := NewMigration(fakeClient, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			DiskPlanID:     int64(sacloud.DiskPlanHDDID),
+			Servers: map[int64]*ServerOptions{
+				serverID: {
+					DiskPlanID: int64(sacloud.DiskPlanSSDID),
+					Hooks: &Hooks{
+						BeforeShutdown: hook("BeforeShutdown"),
+						AfterMigrate:   hook("AfterMigrate"),
+						AfterBoot:      hook("AfterBoot"),
+					},
+				},
+			},
+		})
+		assert.NoError(t, err)
+
+		migration.Apply()
+
+		assert.Empty(t, migration.HasErrors())
+		assert.Equal(t, []int64{int64(sacloud.DiskPlanSSDID)}, fakeClient.clonedPlanIDs)
+		assert.Equal(t, []string{
+			"BeforeShutdown:1:",
+			"AfterMigrate:1:3",
+			"AfterBoot:1:3",
+		}, called)
+	})
+
+	t.Run("merge hooks", func(t *testing.T) {
+		var called []string
+		hook := func(name string) Hook {
+			return func(status *ServerStatus) error {
+				called = append(called, name)
+				return nil
+			}
+		}
+
+		migration, err := NewMigration(&fakeClient{server: singleDiskServer()}, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			Hooks: &Hooks{
+				BeforeShutdown: hook("global:BeforeShutdown"),
+				AfterMigrate:   hook("global:AfterMigrate"),
+			},
+			Servers: map[int64]*ServerOptions{
+				serverID: {
+					Hooks: &Hooks{
+						AfterMigrate: hook("server:AfterMigrate"),
+						AfterBoot:    hook("server:AfterBoot"),
+					},
+				},
+			},
+		})
+		assert.NoError(t, err)
+
+		migration.Apply()
+
+		assert.Empty(t, migration.HasErrors())
+		assert.Equal(t, []string{
+			"global:BeforeShutdown",
+			"server:AfterMigrate",
+			"server:AfterBoot",
+		}, called)
+	})
+
+	t.Run("hook error", func(t *testing.T) {
+		fakeClient := &fakeClient{
+			server: singleDiskServer(),
+		}
+
+		migration, err := NewMigration(fakeClient, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			Hooks: &Hooks{
+				BeforeShutdown: func(status *ServerStatus) error {
+					return fmt.Errorf("freeze failed")
+				},
+			},
+		})
+		assert.NoError(t, err)
+
+		migration.Apply()
+
+		errs := migration.HasErrors()
+		assert.Len(t, errs, 1)
+		assert.EqualError(t, errs[0].Err, "BeforeShutdown hook is failed: freeze failed")
+		assert.False(t, errs[0].stepShutdown.started)
+		assert.Empty(t, fakeClient.clonedPlanIDs)
+	})
+
+	t.Run("pre-clone", func(t *testing.T) {
+		fakeClient := &fakeClient{
+			server: singleDiskServer(),
+		}
+
+		migration, err := NewMigration(fakeClient, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			PreClone:       true,
+		})
+		assert.NoError(t, err)
+
+		migration.Apply()
+
+		assert.Empty(t, migration.HasErrors())
+		assert.Equal(t, []string{"CloneDisk", "Shutdown"}, fakeClient.calls)
+	})
+
+	t.Run("pre-clone with unavailable disk", func(t *testing.T) {
+		fakeClient := &fakeClient{
+			server:          singleDiskServer(),
+			diskUnavailable: true,
+		}
+
+		migration, err := NewMigration(fakeClient, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			PreClone:       true,
+		})
+		assert.NoError(t, err)
+
+		migration.Apply()
+
+		errs := migration.HasErrors()
+		assert.Len(t, errs, 1)
+		assert.EqualError(t, errs[0].Err, "cloned Disk[4] is not available")
+		assert.False(t, errs[0].stepPlanMigrate.started)
+	})
+
+	t.Run("pre-clone with incompletely copied disk", func(t *testing.T) {
+		fakeClient := &fakeClient{
+			server:         singleDiskServer(),
+			diskIncomplete: true,
+		}
+
+		migration, err := NewMigration(fakeClient, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			PreClone:       true,
+		})
+		assert.NoError(t, err)
+
+		migration.Apply()
+
+		errs := migration.HasErrors()
+		assert.Len(t, errs, 1)
+		assert.EqualError(t, errs[0].Err, "cloned Disk[4] is not completely copied: 10240/20480MB")
+		assert.Equal(t, []string{"CloneDisk"}, fakeClient.calls)
+	})
 }
 
 func TestMigration_handleSteps(t *testing.T) {
@@ -206,3 +522,86 @@ func TestMigration_handleSteps(t *testing.T) {
 	assert.True(t, step2.elapsed() > 10*time.Millisecond)
 
 }
+
+type recordListener struct {
+	events []*Event
+	lock   sync.Mutex
+}
+
+func (l *recordListener) Notify(event *Event) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.events = append(l.events, event)
+}
+
+func TestMigration_Events(t *testing.T) {
+	fakeClient := &fakeClient{
+		server:        singleDiskServer(),
+		disconnectErr: fmt.Errorf("disconnect failed"),
+	}
+	listener := &recordListener{}
+
+	migration, err := NewMigration(fakeClient, []int64{serverID}, &Options{
+		MaxWorkerCount: 1,
+		Listeners:      []Listener{listener},
+	})
+	assert.NoError(t, err)
+
+	migration.Apply()
+
+	var types []EventType
+	for _, e := range listener.events {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []EventType{
+		EventRunStarted, EventServerStarted, EventServerShutDown, EventDisksCloned, EventServerFailed, EventRunFinished,
+	}, types)
+
+	assert.Equal(t, 1, listener.events[0].Totals.Queued)
+	assert.Equal(t, &EventProgress{SizeMB: 20 * 1024, ETASeconds: -1}, listener.events[0].Progress)
+	assert.Equal(t, ServerStateRunning, listener.events[1].records[0].State)
+	assert.True(t, listener.events[2].records[0].ShutDown)
+	assert.Equal(t, []int64{clonedDiskID}, listener.events[3].records[0].ClonedDiskIDs)
+	failed := listener.events[4]
+	assert.Equal(t, serverID, failed.ServerID)
+	assert.Contains(t, failed.Error, "disconnect failed")
+	assert.Equal(t, 1, listener.events[5].Totals.Failed)
+
+	// cloned before the failure of disconnecting
+	progress := &EventProgress{MigratedMB: 20 * 1024, SizeMB: 20 * 1024, Percentage: 100}
+	assert.Equal(t, progress, failed.Progress)
+	assert.Equal(t, progress, listener.events[5].Progress)
+}
+
+func TestMigration_Replay(t *testing.T) {
+	apply := func(client iaas.Client) *Report {
+		migration, err := NewMigration(client, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			DiskPlanID:     int64(sacloud.DiskPlanSSDID),
+		})
+		assert.NoError(t, err)
+		migration.Apply()
+		return migration.Report()
+	}
+	results := func(report *Report) []string {
+		var results []string
+		for _, s := range report.Servers {
+			results = append(results, fmt.Sprintf("%d:%s:%d:%s", s.ServerID, s.State, s.MigratedServerID, s.Error))
+		}
+		return results
+	}
+
+	buf := new(bytes.Buffer)
+	recorder := iaas.NewRecordingClient(&fakeClient{server: singleDiskServer()}, buf)
+	recorded := apply(recorder)
+	assert.NoError(t, recorder.Err())
+
+	replay, err := iaas.NewReplayClient(buf)
+	assert.NoError(t, err)
+	replayed := apply(replay)
+
+	assert.Equal(t, []string{"1:done:3:"}, results(recorded))
+	assert.Equal(t, results(recorded), results(replayed))
+	assert.Equal(t, recorded.Totals, replayed.Totals)
+	assert.Empty(t, replay.Unused())
+}
@@ -0,0 +1,117 @@
+package migrate
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// throughputWindow is the period of the moving average of the clone throughput
+const throughputWindow = 30 * time.Second
+
+// progressLogInterval is the interval(percentage) of logging the clone progress
+const progressLogInterval = 10
+
+type progressSample struct {
+	at         time.Time
+	migratedMB int
+}
+
+// cloneProgress tracks the clone progress of a disk
+type cloneProgress struct {
+	samples []progressSample
+	lock    sync.Mutex
+}
+
+func (p *cloneProgress) record(at time.Time, migratedMB int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.samples = append(p.samples, progressSample{at: at, migratedMB: migratedMB})
+
+	// keep at least 2 samples to calculate the throughput
+	i := 0
+	for i < len(p.samples)-2 && at.Sub(p.samples[i].at) > throughputWindow {
+		i++
+	}
+	p.samples = p.samples[i:]
+}
+
+// throughputMBps returns the moving average of the throughput, or 0 if it is unknown yet
+func (p *cloneProgress) throughputMBps() float64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.samples) < 2 {
+		return 0
+	}
+	first, last := p.samples[0], p.samples[len(p.samples)-1]
+	seconds := last.at.Sub(first.at).Seconds()
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(last.migratedMB-first.migratedMB) / seconds
+}
+
+// Progress is the clone progress of disks
+type Progress struct {
+	SizeMB         int
+	MigratedMB     int
+	ThroughputMBps float64
+}
+
+// Percentage returns the progress in percent
+func (p *Progress) Percentage() float64 {
+	if p.SizeMB == 0 {
+		return 0
+	}
+	return float64(p.MigratedMB) / float64(p.SizeMB) * 100
+}
+
+// ETA returns the estimated remaining time, or -1 if it is unknown
+func (p *Progress) ETA() time.Duration {
+	if p.MigratedMB >= p.SizeMB {
+		return 0
+	}
+	if p.ThroughputMBps <= 0 {
+		return -1
+	}
+	remaining := float64(p.SizeMB-p.MigratedMB) / p.ThroughputMBps
+	return time.Duration(remaining * float64(time.Second)).Round(time.Second)
+}
+
+// ETASeconds returns ETA in seconds, or -1 if it is unknown
+func (p *Progress) ETASeconds() int {
+	eta := p.ETA()
+	if eta < 0 {
+		return -1
+	}
+	return int(eta.Seconds())
+}
+
+func (p *Progress) String() string {
+	eta := "-"
+	if d := p.ETA(); d >= 0 {
+		eta = d.String()
+	}
+	return fmt.Sprintf("%5.1f%% %6.1fMB/s ETA %s", p.Percentage(), p.ThroughputMBps, eta)
+}
+
+// Progress returns the clone progress of all disks of the migration
+//
+// Throughput is the sum of the disks being cloned, so ETA of servers waiting for workers is approximate.
+func (m *Migration) Progress() *Progress {
+	total := &Progress{}
+	for _, s := range m.status {
+		for _, d := range s.Disks {
+			if !d.stepClone.needProcess {
+				continue
+			}
+			p := d.Progress()
+			total.SizeMB += p.SizeMB
+			total.MigratedMB += p.MigratedMB
+			total.ThroughputMBps += p.ThroughputMBps
+		}
+	}
+	return total
+}
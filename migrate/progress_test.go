@@ -0,0 +1,76 @@
+package migrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneProgress(t *testing.T) {
+	p := &cloneProgress{}
+	assert.Equal(t, 0.0, p.throughputMBps())
+
+	start := time.Now()
+	p.record(start, 0)
+	p.record(start.Add(10*time.Second), 100)
+	assert.Equal(t, 10.0, p.throughputMBps())
+
+	// samples older than the window are dropped
+	p.record(start.Add(throughputWindow+20*time.Second), 700)
+	assert.Equal(t, 15.0, p.throughputMBps())
+}
+
+func TestProgress(t *testing.T) {
+	expects := []struct {
+		name       string
+		progress   *Progress
+		percentage float64
+		eta        time.Duration
+		etaSeconds int
+	}{
+		{
+			name:       "running",
+			progress:   &Progress{SizeMB: 1000, MigratedMB: 250, ThroughputMBps: 25},
+			percentage: 25,
+			eta:        30 * time.Second,
+			etaSeconds: 30,
+		},
+		{
+			name:       "unknown throughput",
+			progress:   &Progress{SizeMB: 1000, MigratedMB: 250},
+			percentage: 25,
+			eta:        -1,
+			etaSeconds: -1,
+		},
+		{
+			name:       "done",
+			progress:   &Progress{SizeMB: 1000, MigratedMB: 1000},
+			percentage: 100,
+			eta:        0,
+		},
+	}
+
+	for _, expect := range expects {
+		t.Run(expect.name, func(t *testing.T) {
+			assert.Equal(t, expect.percentage, expect.progress.Percentage())
+			assert.Equal(t, expect.eta, expect.progress.ETA())
+			assert.Equal(t, expect.etaSeconds, expect.progress.ETASeconds())
+		})
+	}
+}
+
+func TestMigration_Progress(t *testing.T) {
+	client := &fakeClient{server: multipleDiskServer()}
+	m, err := NewMigration(client, []int64{serverID}, &Options{MaxWorkerCount: 1})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, &Progress{SizeMB: 40 * 1024}, m.Progress())
+
+	m.Apply()
+	progress := m.Progress()
+	assert.Equal(t, 100.0, progress.Percentage())
+	assert.Equal(t, time.Duration(0), progress.ETA())
+}
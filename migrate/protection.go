@@ -0,0 +1,88 @@
+package migrate
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/sacloud/libsacloud/sacloud"
+)
+
+// Protection is a list of servers that must never be migrated
+//
+// Names can be glob patterns.
+type Protection struct {
+	IDs   []int64  `json:"ids,omitempty"`
+	Names []string `json:"names,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// Merge returns new Protection that has both conditions of p and other
+func (p *Protection) Merge(other *Protection) *Protection {
+	merged := &Protection{}
+	for _, v := range []*Protection{p, other} {
+		if v == nil {
+			continue
+		}
+		merged.IDs = append(merged.IDs, v.IDs...)
+		merged.Names = append(merged.Names, v.Names...)
+		merged.Tags = append(merged.Tags, v.Tags...)
+	}
+	return merged
+}
+
+// Reason returns why the server is protected, or empty string if the server is not protected
+func (p *Protection) Reason(server *sacloud.Server) string {
+	if p == nil {
+		return ""
+	}
+	for _, id := range p.IDs {
+		if server.ID == id {
+			return fmt.Sprintf("id %d", id)
+		}
+	}
+	for _, name := range p.Names {
+		if matched, _ := path.Match(name, server.Name); matched || name == server.Name {
+			return fmt.Sprintf("name %q", name)
+		}
+	}
+	for _, tag := range p.Tags {
+		if server.HasTag(tag) {
+			return fmt.Sprintf("tag %q", tag)
+		}
+	}
+	return ""
+}
+
+// Check returns ProtectedError if any of servers are protected
+func (p *Protection) Check(servers []*sacloud.Server) error {
+	err := &ProtectedError{}
+	for _, s := range servers {
+		if reason := p.Reason(s); reason != "" {
+			err.Servers = append(err.Servers, &ProtectedServer{ID: s.ID, Name: s.Name, Reason: reason})
+		}
+	}
+	if len(err.Servers) > 0 {
+		return err
+	}
+	return nil
+}
+
+type ProtectedServer struct {
+	ID     int64
+	Name   string
+	Reason string
+}
+
+// ProtectedError is returned when protected servers are requested as target
+type ProtectedError struct {
+	Servers []*ProtectedServer
+}
+
+func (e *ProtectedError) Error() string {
+	var list []string
+	for _, s := range e.Servers {
+		list = append(list, fmt.Sprintf("\tServer[%d:%s] (protected by %s)", s.ID, s.Name, s.Reason))
+	}
+	return fmt.Sprintf("protected servers are included in targets: [\n%s\n]", strings.Join(list, ",\n"))
+}
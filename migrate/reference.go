@@ -0,0 +1,247 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sacloud/cloud-plan-migrate/iaas"
+)
+
+// ReferenceUpdater finds and rewrites references to the server ID that is changed by plan migration
+type ReferenceUpdater interface {
+	Name() string
+	FindReferences(serverID int64) ([]*Reference, error)
+	UpdateReference(ref *Reference, oldServerID, newServerID int64) error
+}
+
+// Reference is a reference to the server ID held by other resource or external system
+type Reference struct {
+	Updater      string `json:"updater"`
+	ResourceType string `json:"resource_type"`
+	ResourceID   int64  `json:"resource_id,omitempty"`
+	ResourceName string `json:"resource_name,omitempty"`
+	Field        string `json:"field"`
+	Value        string `json:"value"`
+	Updated      bool   `json:"updated"`
+}
+
+func (r *Reference) String() string {
+	if r.ResourceID == 0 {
+		return fmt.Sprintf("%s %s: %q", r.ResourceType, r.Field, r.Value)
+	}
+	return fmt.Sprintf("%s[%d:%s] %s: %q", r.ResourceType, r.ResourceID, r.ResourceName, r.Field, r.Value)
+}
+
+// NewResourceReferenceUpdater returns ReferenceUpdater that rewrites descriptions and tags of the resources in the account
+//
+// Resources are scanned once at the first FindReferences and shared by all servers of the run,
+// references are matched with the server ID not adjacent to other digits.
+func NewResourceReferenceUpdater(client iaas.Client) ReferenceUpdater {
+	return &resourceReferenceUpdater{client: client}
+}
+
+type resourceReferenceUpdater struct {
+	client iaas.Client
+
+	lock      sync.Mutex
+	scanned   bool
+	resources []*iaas.TaggedResource
+	locks     map[string]*sync.Mutex // serialize read-modify-write of each resource among workers
+}
+
+func (u *resourceReferenceUpdater) Name() string {
+	return "resource"
+}
+
+func (u *resourceReferenceUpdater) FindReferences(serverID int64) ([]*Reference, error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if !u.scanned {
+		resources, err := u.client.FindTaggedResources()
+		if err != nil {
+			return nil, err
+		}
+		u.resources = resources
+		u.scanned = true
+	}
+
+	strID := strconv.FormatInt(serverID, 10)
+	var refs []*Reference
+	for _, r := range u.resources {
+		if r.Type == iaas.ResourceTypeServer && r.ID == serverID {
+			continue
+		}
+		newRef := func(field, value string) *Reference {
+			return &Reference{
+				Updater:      u.Name(),
+				ResourceType: r.Type,
+				ResourceID:   r.ID,
+				ResourceName: r.Name,
+				Field:        field,
+				Value:        value,
+			}
+		}
+		if containsID(r.Description, strID) {
+			refs = append(refs, newRef("Description", r.Description))
+		}
+		for _, tag := range r.Tags {
+			if containsID(tag, strID) {
+				refs = append(refs, newRef("Tags", tag))
+			}
+		}
+	}
+	return refs, nil
+}
+
+func (u *resourceReferenceUpdater) UpdateReference(ref *Reference, oldServerID, newServerID int64) error {
+	lock := u.resourceLock(ref.ResourceType, ref.ResourceID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	resource, err := u.client.TaggedResourceByID(ref.ResourceType, ref.ResourceID)
+	if err != nil {
+		return err
+	}
+
+	oldID := strconv.FormatInt(oldServerID, 10)
+	newID := strconv.FormatInt(newServerID, 10)
+	changed := false
+
+	if desc := replaceID(resource.Description, oldID, newID); desc != resource.Description {
+		resource.Description = desc
+		changed = true
+	}
+	var tags []string
+	for _, tag := range resource.Tags {
+		newTag := replaceID(tag, oldID, newID)
+		if newTag != tag {
+			changed = true
+		}
+		tags = append(tags, newTag)
+	}
+	resource.Tags = tags
+
+	// 同一リソースへの複数の参照は最初の更新で書き換え済みとなる
+	if !changed {
+		return nil
+	}
+	if err := u.client.UpdateTaggedResource(resource); err != nil {
+		return err
+	}
+	u.updateScanned(resource)
+	return nil
+}
+
+func (u *resourceReferenceUpdater) resourceLock(resourceType string, id int64) *sync.Mutex {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	key := fmt.Sprintf("%s:%d", resourceType, id)
+	if u.locks == nil {
+		u.locks = map[string]*sync.Mutex{}
+	}
+	if _, ok := u.locks[key]; !ok {
+		u.locks[key] = &sync.Mutex{}
+	}
+	return u.locks[key]
+}
+
+// updateScanned replaces the scanned resource with the updated one
+func (u *resourceReferenceUpdater) updateScanned(resource *iaas.TaggedResource) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	for i, r := range u.resources {
+		if r.Type == resource.Type && r.ID == resource.ID {
+			u.resources[i] = resource
+		}
+	}
+}
+
+// indexID returns the index of the first id in s which is not adjacent to other digits, or -1
+func indexID(s, id string) int {
+	for offset := 0; offset < len(s); {
+		i := strings.Index(s[offset:], id)
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		end := i + len(id)
+		if (i == 0 || !isDigit(s[i-1])) && (end == len(s) || !isDigit(s[end])) {
+			return i
+		}
+		offset = i + 1
+	}
+	return -1
+}
+
+func containsID(s, id string) bool {
+	return indexID(s, id) >= 0
+}
+
+// replaceID replaces all ids in s which are not adjacent to other digits
+func replaceID(s, oldID, newID string) string {
+	var b strings.Builder
+	for {
+		i := indexID(s, oldID)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		b.WriteString(newID)
+		s = s[i+len(oldID):]
+	}
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// NewCommandReferenceUpdater returns ReferenceUpdater that executes external command to update references
+//
+// The command is executed with OLD_SERVER_ID and NEW_SERVER_ID environment variables.
+func NewCommandReferenceUpdater(command string) ReferenceUpdater {
+	return &commandReferenceUpdater{command: command}
+}
+
+type commandReferenceUpdater struct {
+	command string
+}
+
+func (u *commandReferenceUpdater) Name() string {
+	return "command"
+}
+
+func (u *commandReferenceUpdater) FindReferences(serverID int64) ([]*Reference, error) {
+	return []*Reference{
+		{
+			Updater:      u.Name(),
+			ResourceType: "external",
+			Field:        "Command",
+			Value:        u.command,
+		},
+	}, nil
+}
+
+func (u *commandReferenceUpdater) UpdateReference(ref *Reference, oldServerID, newServerID int64) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", u.command)
+	} else {
+		cmd = exec.Command("sh", "-c", u.command)
+	}
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("OLD_SERVER_ID=%d", oldServerID),
+		fmt.Sprintf("NEW_SERVER_ID=%d", newServerID),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("command %q is failed: %s: %s", u.command, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package migrate
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sacloud/cloud-plan-migrate/iaas"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceReferenceUpdater(t *testing.T) {
+
+	oldID := int64(113000000001)
+	newID := int64(113000000002)
+
+	client := &fakeClient{
+		resources: []*iaas.TaggedResource{
+			{Type: iaas.ResourceTypeServer, ID: oldID, Name: "target", Tags: []string{"id=113000000001"}},
+			{Type: iaas.ResourceTypeSimpleMonitor, ID: 1, Name: "monitor", Description: "watching 113000000001", Tags: []string{"server=113000000001", "web"}},
+			{Type: iaas.ResourceTypeSwitch, ID: 2, Name: "switch", Tags: []string{"web"}},
+		},
+	}
+	updater := NewResourceReferenceUpdater(client)
+
+	refs, err := updater.FindReferences(oldID)
+	assert.NoError(t, err)
+	assert.Len(t, refs, 2)
+	for _, ref := range refs {
+		assert.Equal(t, iaas.ResourceTypeSimpleMonitor, ref.ResourceType)
+		assert.Equal(t, int64(1), ref.ResourceID)
+	}
+	assert.Equal(t, "Description", refs[0].Field)
+	assert.Equal(t, "Tags", refs[1].Field)
+
+	for _, ref := range refs {
+		assert.NoError(t, updater.UpdateReference(ref, oldID, newID))
+	}
+
+	// the first update rewrites all references held by the resource
+	assert.Len(t, client.updated, 1)
+	updated := client.updated[0]
+	assert.Equal(t, "watching 113000000002", updated.Description)
+	assert.Equal(t, []string{"server=113000000002", "web"}, updated.Tags)
+}
+
+func TestResourceReferenceUpdater_Boundary(t *testing.T) {
+
+	oldID := int64(113000000001)
+	newID := int64(113000000002)
+
+	client := &fakeClient{
+		resources: []*iaas.TaggedResource{
+			{
+				Type:        iaas.ResourceTypeSimpleMonitor,
+				ID:          1,
+				Description: "113000000001,1130000000010 9113000000001 id:113000000001",
+				Tags:        []string{"server=1130000000012", "@113000000001"},
+			},
+			{Type: iaas.ResourceTypeSwitch, ID: 2, Description: "port 11300000000123"},
+		},
+	}
+	updater := NewResourceReferenceUpdater(client)
+
+	refs, err := updater.FindReferences(oldID)
+	assert.NoError(t, err)
+	assert.Len(t, refs, 2)
+	for _, ref := range refs {
+		assert.NoError(t, updater.UpdateReference(ref, oldID, newID))
+	}
+
+	assert.Len(t, client.updated, 1)
+	updated := client.updated[0]
+	assert.Equal(t, "113000000002,1130000000010 9113000000001 id:113000000002", updated.Description)
+	assert.Equal(t, []string{"server=1130000000012", "@113000000002"}, updated.Tags)
+}
+
+func TestResourceReferenceUpdater_Concurrent(t *testing.T) {
+
+	const count = 10
+	var ids []string
+	for i := 1; i <= count; i++ {
+		ids = append(ids, fmt.Sprintf("server=%d", 100+i))
+	}
+	client := &fakeClient{
+		resources: []*iaas.TaggedResource{
+			{Type: iaas.ResourceTypeSimpleMonitor, ID: 1, Tags: ids},
+		},
+	}
+	updater := NewResourceReferenceUpdater(client)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= count; i++ {
+		wg.Add(1)
+		go func(oldID int64) {
+			defer wg.Done()
+			refs, err := updater.FindReferences(oldID)
+			assert.NoError(t, err)
+			for _, ref := range refs {
+				assert.NoError(t, updater.UpdateReference(ref, oldID, oldID+100))
+			}
+		}(int64(100 + i))
+	}
+	wg.Wait()
+
+	// resources are scanned once, and no update is lost
+	assert.Equal(t, []string{"FindTaggedResources"}, client.calls)
+	var expected []string
+	for i := 1; i <= count; i++ {
+		expected = append(expected, fmt.Sprintf("server=%d", 200+i))
+	}
+	resource, err := client.TaggedResourceByID(iaas.ResourceTypeSimpleMonitor, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, resource.Tags)
+}
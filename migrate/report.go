@@ -0,0 +1,79 @@
+package migrate
+
+import (
+	"time"
+
+	"github.com/sacloud/libsacloud/sacloud"
+)
+
+type Report struct {
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Totals     *Totals         `json:"totals"`
+	Servers    []*ServerReport `json:"servers"`
+}
+
+type ServerReport struct {
+	ServerID           int64               `json:"server_id"`
+	ServerName         string              `json:"server_name"`
+	State              ServerState         `json:"state"`
+	MigratedServerID   int64               `json:"migrated_server_id,omitempty"`
+	OriginalInterfaces []sacloud.Interface `json:"original_interfaces"`
+	MigratedInterfaces []sacloud.Interface `json:"migrated_interfaces,omitempty"`
+	InterfaceDiffs     []*InterfaceDiff    `json:"interface_diffs,omitempty"`
+	References         []*Reference        `json:"references,omitempty"`
+	Error              string              `json:"error,omitempty"`
+
+	// Downtime is the time from shutdown to boot, zero if the server was not shut down or is not booted
+	Downtime        time.Duration `json:"-"`
+	DowntimeSeconds int           `json:"downtime_seconds,omitempty"`
+}
+
+func (m *Migration) Report() *Report {
+	report := &Report{
+		StartedAt:  m.startedAt,
+		FinishedAt: m.finishedAt,
+		Totals:     m.Totals(),
+	}
+	for _, s := range m.status {
+		r := &ServerReport{
+			ServerID:           s.targetServerID,
+			ServerName:         s.serverName,
+			State:              s.State(),
+			MigratedServerID:   s.migratedServerID,
+			OriginalInterfaces: s.originalInterfaces,
+			MigratedInterfaces: s.migratedInterfaces,
+			InterfaceDiffs:     s.InterfaceDiffs,
+			References:         s.References,
+			Downtime:           s.downtime(),
+		}
+		r.DowntimeSeconds = int(r.Downtime.Seconds())
+		if err := s.GetErr(); err != nil {
+			r.Error = err.Error()
+		}
+		report.Servers = append(report.Servers, r)
+	}
+	return report
+}
+
+// CurrentDowntime returns the time since the server was shut down, or zero if the server is not shut down by the migration or is booted
+func (s *ServerStatus) CurrentDowntime() time.Duration {
+	if !s.stepShutdown.needProcess || !s.stepShutdown.started {
+		return 0
+	}
+	if s.stepBoot.needProcess && s.stepBoot.done {
+		return 0
+	}
+	return time.Since(s.stepShutdown.startTime)
+}
+
+// downtime returns the time from shutdown to boot, or zero if the server was not shut down or is not booted
+func (s *ServerStatus) downtime() time.Duration {
+	if !s.stepShutdown.needProcess || !s.stepShutdown.started {
+		return 0
+	}
+	if !s.stepBoot.needProcess || !s.stepBoot.done || s.stepBoot.err != nil {
+		return 0
+	}
+	return s.stepBoot.endTime.Sub(s.stepShutdown.startTime)
+}
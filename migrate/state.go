@@ -0,0 +1,86 @@
+package migrate
+
+// ServerState is the state of a server in the migration
+type ServerState string
+
+const (
+	ServerStateQueued     ServerState = "queued"
+	ServerStateWaiting    ServerState = "waiting"
+	ServerStateRunning    ServerState = "running"
+	ServerStateDone       ServerState = "done"
+	ServerStateFailed     ServerState = "failed"
+	ServerStateSkipped    ServerState = "skipped"
+	ServerStateRolledBack ServerState = "rolled-back"
+)
+
+// Totals is the number of servers in each state
+type Totals struct {
+	Queued     int `json:"queued"`
+	Waiting    int `json:"waiting"`
+	Running    int `json:"running"`
+	Done       int `json:"done"`
+	Failed     int `json:"failed"`
+	Skipped    int `json:"skipped"`
+	RolledBack int `json:"rolled_back"`
+}
+
+func (t *Totals) Total() int {
+	return t.Queued + t.Waiting + t.Running + t.Done + t.Failed + t.Skipped + t.RolledBack
+}
+
+func (t *Totals) add(state ServerState) {
+	switch state {
+	case ServerStateQueued:
+		t.Queued++
+	case ServerStateWaiting:
+		t.Waiting++
+	case ServerStateRunning:
+		t.Running++
+	case ServerStateDone:
+		t.Done++
+	case ServerStateFailed:
+		t.Failed++
+	case ServerStateSkipped:
+		t.Skipped++
+	case ServerStateRolledBack:
+		t.RolledBack++
+	}
+}
+
+// State returns the state of the server
+func (s *ServerStatus) State() ServerState {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.currentState()
+}
+
+// currentState returns the state of the server, the caller must hold the lock
+func (s *ServerStatus) currentState() ServerState {
+	if s.state == ServerStateSkipped || s.state == ServerStateRolledBack {
+		return s.state
+	}
+	if s.Err != nil {
+		return ServerStateFailed
+	}
+	if s.state == "" {
+		if !s.nextWindow.IsZero() {
+			return ServerStateWaiting
+		}
+		return ServerStateQueued
+	}
+	return s.state
+}
+
+// Status returns status of all servers including queued and finished ones
+func (m *Migration) Status() []*ServerStatus {
+	return m.status
+}
+
+// Totals returns the number of servers in each state
+func (m *Migration) Totals() *Totals {
+	totals := &Totals{}
+	for _, s := range m.status {
+		totals.add(s.State())
+	}
+	return totals
+}
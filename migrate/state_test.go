@@ -0,0 +1,44 @@
+package migrate
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigration_Totals(t *testing.T) {
+
+	t.Run("done", func(t *testing.T) {
+		m, err := NewMigration(&fakeClient{server: singleDiskServer()}, []int64{serverID}, &Options{MaxWorkerCount: 1})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, ServerStateQueued, m.Status()[0].State())
+		assert.Equal(t, &Totals{Queued: 1}, m.Totals())
+
+		m.Apply()
+		assert.Equal(t, ServerStateDone, m.Status()[0].State())
+		assert.Equal(t, &Totals{Done: 1}, m.Totals())
+		assert.Equal(t, 1, m.Totals().Total())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		m, err := NewMigration(&fakeClient{server: singleDiskServer()}, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			Hooks: &Hooks{
+				BeforeShutdown: func(status *ServerStatus) error {
+					return fmt.Errorf("freeze failed")
+				},
+			},
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		m.Apply()
+		assert.Equal(t, ServerStateFailed, m.Status()[0].State())
+		assert.Equal(t, &Totals{Failed: 1}, m.Report().Totals)
+		assert.Equal(t, ServerStateFailed, m.Report().Servers[0].State)
+	})
+}
@@ -2,35 +2,88 @@ package migrate
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/sacloud/cloud-plan-migrate/logging"
 	"github.com/sacloud/libsacloud/sacloud"
 )
 
 type ServerStatus struct {
 	Disks []*DiskStatus
 
-	stepShutdown        *step
-	stepDisconnectDisks *step
-	stepConnectDisks    *step
-	stepPlanMigrate     *step
-	stepBoot            *step
-
+	stepShutdown         *step
+	stepDisconnectDisks  *step
+	stepConnectDisks     *step
+	stepPlanMigrate      *step
+	stepUpdateReferences *step
+	stepVerify           *step
+	stepBoot             *step
+
+	lock             *sync.Mutex // shared with Migration, guards state, Err and the window status
+	state            ServerState
+	rollingBack      bool
+	nextWindow       time.Time
+	windowMessage    string
 	targetServerID   int64
 	serverName       string
 	migratedServerID int64
 	newPlan          *sacloud.ProductServer
+	hooks            *Hooks
+	preClone         bool
+	logger           *serverLogger
+
+	originalInterfaces []sacloud.Interface
+	migratedInterfaces []sacloud.Interface
+	InterfaceDiffs     []*InterfaceDiff
+	References         []*Reference
 
 	Err error
 }
 
+// GetErr returns the error of the migration of the server
+func (s *ServerStatus) GetErr() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.Err
+}
+
+func (s *ServerStatus) ID() int64 {
+	return s.targetServerID
+}
+
 func (s *ServerStatus) ServerID() string {
 	return fmt.Sprintf("%d", s.targetServerID)
 }
 
+func (s *ServerStatus) MigratedServerID() string {
+	if s.migratedServerID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", s.migratedServerID)
+}
+
 func (s *ServerStatus) ServerName() string {
 	return s.serverName
 }
 
+// Progress returns the clone progress of disks of the server
+func (s *ServerStatus) Progress() *Progress {
+	total := &Progress{}
+	for _, d := range s.Disks {
+		p := d.Progress()
+		total.SizeMB += p.SizeMB
+		total.MigratedMB += p.MigratedMB
+		total.ThroughputMBps += p.ThroughputMBps
+	}
+	return total
+}
+
+// Logs returns recent log lines of the server
+func (s *ServerStatus) Logs() []string {
+	return s.logger.Lines()
+}
+
 func (s *ServerStatus) ShutdownStatus() string {
 	return s.stepShutdown.Status()
 }
@@ -39,10 +92,35 @@ func (s *ServerStatus) MigrationStatus() string {
 	return s.stepPlanMigrate.Status()
 }
 
+func (s *ServerStatus) VerifyStatus() string {
+	status := s.stepVerify.Status()
+	if s.stepVerify.done && len(s.InterfaceDiffs) > 0 {
+		status = fmt.Sprintf("%s(%d diffs)", status, len(s.InterfaceDiffs))
+	}
+	return status
+}
+
 func (s *ServerStatus) BootStatus() string {
 	return s.stepBoot.Status()
 }
 
+// Steps returns snapshots of the steps which need to be processed for the server, in the order of the migration
+func (s *ServerStatus) Steps() []*StepState {
+	steps := []*step{s.stepShutdown}
+	steps = append(steps, s.cloneDiskSteps()...)
+	steps = append(steps, s.stepDisconnectDisks, s.stepPlanMigrate, s.stepConnectDisks,
+		s.stepVerify, s.stepBoot, s.stepUpdateReferences)
+	steps = append(steps, s.deleteDiskSteps()...)
+
+	var states []*StepState
+	for _, st := range steps {
+		if st != nil && st.needProcess {
+			states = append(states, st.state())
+		}
+	}
+	return states
+}
+
 func (s *ServerStatus) setNewDiskID(old, new int64) {
 	d := s.findDiskStatus(old)
 	if d != nil {
@@ -59,6 +137,14 @@ func (s *ServerStatus) findDiskStatus(oldDiskID int64) *DiskStatus {
 	return nil
 }
 
+func (s *ServerStatus) originalDiskIDs() []int64 {
+	var ids []int64
+	for _, d := range s.Disks {
+		ids = append(ids, d.originalID)
+	}
+	return ids
+}
+
 func (s *ServerStatus) clonedDiskIDs() []int64 {
 	var ids []int64
 	for _, d := range s.Disks {
@@ -87,10 +173,15 @@ type DiskStatus struct {
 	stepClone  *step
 	stepDelete *step
 
-	originalID int64
-	sizeMB     int
-	migratedMB int
-	clonedID   int64
+	originalID   int64
+	planID       int64
+	clonedPlanID int64
+	sizeMB       int
+	migratedMB   int
+	clonedID     int64
+
+	progress       cloneProgress
+	loggedProgress int
 }
 
 func (d *DiskStatus) CloneStatus() string {
@@ -104,11 +195,35 @@ func (d *DiskStatus) CloneStatus() string {
 	}
 
 	if d.cloning() {
-		return fmt.Sprintf("ID:%s(%ds)\n%s", id, int(d.stepClone.elapsed().Seconds()), d.migratedStatus())
+		return fmt.Sprintf("ID:%s(%ds)\n%s\n%s", id, int(d.stepClone.elapsed().Seconds()), d.migratedStatus(), d.Progress())
 	}
 	return fmt.Sprintf("ID:%s", id)
 }
 
+// Progress returns the clone progress of the disk
+func (d *DiskStatus) Progress() *Progress {
+	p := &Progress{SizeMB: d.sizeMB, MigratedMB: d.migratedMB}
+	if d.stepClone.done {
+		p.MigratedMB = d.sizeMB
+	} else if d.cloning() {
+		p.ThroughputMBps = d.progress.throughputMBps()
+	}
+	return p
+}
+
+// updateProgress records the migrated size, and logs the progress at each progressLogInterval percent
+func (d *DiskStatus) updateProgress(migratedMB int) {
+	d.migratedMB = migratedMB
+	d.progress.record(time.Now(), migratedMB)
+
+	p := d.Progress()
+	if logged := int(p.Percentage()) / progressLogInterval * progressLogInterval; logged > d.loggedProgress {
+		d.loggedProgress = logged
+		d.stepClone.log(logging.LevelInfo, "progress", "migrated_mb", p.MigratedMB, "size_mb", p.SizeMB,
+			"percentage", int(p.Percentage()), "throughput_mbps", p.ThroughputMBps, "eta_seconds", p.ETASeconds())
+	}
+}
+
 func (d *DiskStatus) cloning() bool {
 	return d.stepClone.needProcess && d.stepClone.started && !d.stepClone.done
 }
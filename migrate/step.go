@@ -2,8 +2,11 @@ package migrate
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/logging"
 )
 
 const (
@@ -16,13 +19,15 @@ const (
 
 type step struct {
 	needProcess bool
-	logPrefix   string
+	name        string
+	fields      []interface{} // key/value pairs of the log other than the server, e.g. disk_id
 	started     bool
 	startTime   time.Time
+	endTime     time.Time
 	done        bool
 	err         error
 
-	logger Logger
+	logger *serverLogger
 }
 
 func (s *step) Error() error {
@@ -50,6 +55,9 @@ func (s *step) Status() string {
 }
 
 func (s *step) elapsed() time.Duration {
+	if s.done {
+		return s.endTime.Sub(s.startTime)
+	}
 	return time.Since(s.startTime)
 }
 
@@ -65,7 +73,7 @@ func (s *step) logStarted() {
 	}
 
 	if s.logger != nil {
-		s.logger.Printf("%s started%s", s.logPrefix, newline)
+		s.log(logging.LevelInfo, "started")
 	}
 }
 
@@ -75,17 +83,29 @@ func (s *step) finalize() {
 	defer lock.Unlock()
 
 	if !s.done {
+		s.endTime = time.Now()
 		s.done = true
 		s.logDone()
 	}
 }
 
+// restore marks the step as done in the previous run without processing it
+func (s *step) restore() {
+	s.startTime = time.Now()
+	s.endTime = s.startTime
+	s.started = true
+	s.done = true
+	if s.logger != nil {
+		s.log(logging.LevelInfo, "skipped, already done in the previous run")
+	}
+}
+
 func (s *step) logDone() {
 	if !s.needProcess {
 		return
 	}
 	if s.logger != nil {
-		s.logger.Printf("%s finished (elapsed:%ds)%s", s.logPrefix, int(s.elapsed().Seconds()), newline)
+		s.log(logging.LevelInfo, "finished", "elapsed", int(s.elapsed().Seconds()))
 	}
 }
 
@@ -99,6 +119,43 @@ func (s *step) logError() {
 		return
 	}
 	if s.logger != nil {
-		s.logger.Printf("%s error: %s%s", s.logPrefix, s.err, newline)
+		s.log(logging.LevelError, "error", "error", s.err)
+	}
+}
+
+// log writes the log of the step with the step name and fields, e.g. msg "started" is logged as "Clone Disk started"
+func (s *step) log(level logging.Level, msg string, keyvals ...interface{}) {
+	if s.logger == nil {
+		return
+	}
+	fields := append([]interface{}{"step", stepKey(s.name)}, s.fields...)
+	s.logger.log(level, fmt.Sprintf("%s %s", s.name, msg), append(fields, keyvals...)...)
+}
+
+// stepKey returns the name of the step for the log field, e.g. "clone_disk" for "Clone Disk"
+func stepKey(name string) string {
+	return strings.ToLower(strings.Replace(name, " ", "_", -1))
+}
+
+// StepState is a snapshot of a step of the server migration
+type StepState struct {
+	// Step is the name of the step for metrics/logs, e.g. "clone_disk"
+	Step    string
+	Started bool
+	Done    bool
+	Failed  bool
+	Elapsed time.Duration
+}
+
+func (s *step) state() *StepState {
+	state := &StepState{
+		Step:    stepKey(s.name),
+		Started: s.started,
+		Done:    s.done,
+		Failed:  s.err != nil,
+	}
+	if s.started {
+		state.Elapsed = s.elapsed()
 	}
+	return state
 }
@@ -0,0 +1,82 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/sacloud/libsacloud/sacloud"
+)
+
+const interfaceNone = "(none)"
+
+// InterfaceDiff is a difference of a network interface between original server and migrated server
+type InterfaceDiff struct {
+	Index    int    `json:"index"`
+	Field    string `json:"field"`
+	Original string `json:"original"`
+	Migrated string `json:"migrated"`
+}
+
+func (d *InterfaceDiff) String() string {
+	return fmt.Sprintf("NIC[%d] %s: %q => %q", d.Index, d.Field, d.Original, d.Migrated)
+}
+
+func diffInterfaces(original, migrated []sacloud.Interface) []*InterfaceDiff {
+	var diffs []*InterfaceDiff
+
+	count := len(original)
+	if len(migrated) > count {
+		count = len(migrated)
+	}
+
+	for i := 0; i < count; i++ {
+		if i >= len(original) {
+			diffs = append(diffs, &InterfaceDiff{Index: i, Field: "Interface", Original: interfaceNone, Migrated: interfaceID(&migrated[i])})
+			continue
+		}
+		if i >= len(migrated) {
+			diffs = append(diffs, &InterfaceDiff{Index: i, Field: "Interface", Original: interfaceID(&original[i]), Migrated: interfaceNone})
+			continue
+		}
+
+		o, m := &original[i], &migrated[i]
+		fields := []struct {
+			name     string
+			original string
+			migrated string
+		}{
+			{name: "Switch", original: interfaceSwitch(o), migrated: interfaceSwitch(m)},
+			{name: "PacketFilter", original: interfacePacketFilter(o), migrated: interfacePacketFilter(m)},
+			{name: "IPAddress", original: o.IPAddress, migrated: m.IPAddress},
+			{name: "UserIPAddress", original: o.UserIPAddress, migrated: m.UserIPAddress},
+		}
+		for _, f := range fields {
+			if f.original != f.migrated {
+				diffs = append(diffs, &InterfaceDiff{Index: i, Field: f.name, Original: f.original, Migrated: f.migrated})
+			}
+		}
+	}
+
+	return diffs
+}
+
+func interfaceID(i *sacloud.Interface) string {
+	return i.GetStrID()
+}
+
+func interfaceSwitch(i *sacloud.Interface) string {
+	switch i.UpstreamType() {
+	case sacloud.EUpstreamNetworkNone:
+		return interfaceNone
+	case sacloud.EUpstreamNetworkShared:
+		return "shared"
+	default:
+		return i.Switch.GetStrID()
+	}
+}
+
+func interfacePacketFilter(i *sacloud.Interface) string {
+	if i.PacketFilter == nil {
+		return interfaceNone
+	}
+	return i.PacketFilter.GetStrID()
+}
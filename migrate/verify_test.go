@@ -0,0 +1,51 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/sacloud/libsacloud/sacloud"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffInterfaces(t *testing.T) {
+
+	sharedNIC := func(ip string) sacloud.Interface {
+		nic := sacloud.Interface{Resource: sacloud.NewResource(101), IPAddress: ip}
+		nic.Switch = &sacloud.Switch{Resource: sacloud.NewResource(201), Scope: sacloud.ESCopeShared}
+		nic.Switch.Subnet = &sacloud.Subnet{}
+		return nic
+	}
+	switchNIC := func(switchID int64) sacloud.Interface {
+		nic := sacloud.Interface{Resource: sacloud.NewResource(102), UserIPAddress: "192.168.0.11"}
+		nic.Switch = &sacloud.Switch{Resource: sacloud.NewResource(switchID)}
+		return nic
+	}
+
+	t.Run("no differences", func(t *testing.T) {
+		original := []sacloud.Interface{sharedNIC("192.0.2.1"), switchNIC(301)}
+		migrated := []sacloud.Interface{sharedNIC("192.0.2.1"), switchNIC(301)}
+		assert.Empty(t, diffInterfaces(original, migrated))
+	})
+
+	t.Run("changed fields", func(t *testing.T) {
+		original := []sacloud.Interface{sharedNIC("192.0.2.1"), switchNIC(301)}
+		migrated := []sacloud.Interface{sharedNIC("192.0.2.2"), switchNIC(302)}
+		migrated[0].PacketFilter = &sacloud.PacketFilter{Resource: sacloud.NewResource(401)}
+
+		diffs := diffInterfaces(original, migrated)
+		assert.Len(t, diffs, 3)
+
+		assert.Equal(t, &InterfaceDiff{Index: 0, Field: "PacketFilter", Original: interfaceNone, Migrated: "401"}, diffs[0])
+		assert.Equal(t, &InterfaceDiff{Index: 0, Field: "IPAddress", Original: "192.0.2.1", Migrated: "192.0.2.2"}, diffs[1])
+		assert.Equal(t, &InterfaceDiff{Index: 1, Field: "Switch", Original: "301", Migrated: "302"}, diffs[2])
+	})
+
+	t.Run("missing interface", func(t *testing.T) {
+		original := []sacloud.Interface{sharedNIC("192.0.2.1"), switchNIC(301)}
+		migrated := []sacloud.Interface{sharedNIC("192.0.2.1")}
+
+		diffs := diffInterfaces(original, migrated)
+		assert.Len(t, diffs, 1)
+		assert.Equal(t, &InterfaceDiff{Index: 1, Field: "Interface", Original: "102", Migrated: interfaceNone}, diffs[0])
+	})
+}
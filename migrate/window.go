@@ -0,0 +1,222 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/logging"
+)
+
+// windowPollInterval is the interval of checking pause/drain/skip while waiting for a maintenance window
+var windowPollInterval = time.Second
+
+// Window is a maintenance window in which servers can be shut down
+type Window interface {
+	// Next returns the window containing t, or the first window after t
+	Next(t time.Time) (start time.Time, end time.Time, ok bool)
+	String() string
+}
+
+// PeriodWindow is a window of the specific period
+type PeriodWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (w *PeriodWindow) Next(t time.Time) (time.Time, time.Time, bool) {
+	if !t.Before(w.End) {
+		return time.Time{}, time.Time{}, false
+	}
+	return w.Start, w.End, true
+}
+
+func (w *PeriodWindow) String() string {
+	return fmt.Sprintf("%s/%s", w.Start.Format(windowTimeLayout), w.End.Format(windowTimeLayout))
+}
+
+// DailyWindow is a window repeated every day, End before Start means the window over midnight
+type DailyWindow struct {
+	Start time.Duration // from midnight
+	End   time.Duration // from midnight
+}
+
+func (w *DailyWindow) Next(t time.Time) (time.Time, time.Time, bool) {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	length := w.End - w.Start
+	if length <= 0 {
+		length += 24 * time.Hour
+	}
+
+	// the window started yesterday may contain t
+	for day := -1; day <= 1; day++ {
+		start := midnight.AddDate(0, 0, day).Add(w.Start)
+		end := start.Add(length)
+		if t.Before(end) {
+			return start, end, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+func (w *DailyWindow) String() string {
+	format := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%s-%s", format(w.Start), format(w.End))
+}
+
+const (
+	windowTimeLayout  = "2006-01-02 15:04"
+	windowClockLayout = "15:04"
+)
+
+// ParseWindow parses the window specification in local time
+//
+// Supported formats are "HH:MM-HH:MM"(daily) and "YYYY-MM-DD HH:MM/YYYY-MM-DD HH:MM"(period).
+func ParseWindow(spec string) (Window, error) {
+	if strings.Contains(spec, "/") {
+		parts := strings.SplitN(spec, "/", 2)
+		start, err := time.ParseInLocation(windowTimeLayout, strings.TrimSpace(parts[0]), time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %s", spec, err)
+		}
+		end, err := time.ParseInLocation(windowTimeLayout, strings.TrimSpace(parts[1]), time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %s", spec, err)
+		}
+		if !start.Before(end) {
+			return nil, fmt.Errorf("invalid window %q: end must be after start", spec)
+		}
+		return &PeriodWindow{Start: start, End: end}, nil
+	}
+
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid window %q: format must be HH:MM-HH:MM or YYYY-MM-DD HH:MM/YYYY-MM-DD HH:MM", spec)
+	}
+	var clocks []time.Duration
+	for _, p := range parts {
+		c, err := time.Parse(windowClockLayout, strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %s", spec, err)
+		}
+		clocks = append(clocks, time.Duration(c.Hour())*time.Hour+time.Duration(c.Minute())*time.Minute)
+	}
+	if clocks[0] == clocks[1] {
+		return nil, fmt.Errorf("invalid window %q: start and end are same", spec)
+	}
+	return &DailyWindow{Start: clocks[0], End: clocks[1]}, nil
+}
+
+// Schedule is a list of maintenance windows
+type Schedule []Window
+
+// NextStart returns the earliest time from now when the downtime fits in a window
+func (s Schedule) NextStart(now time.Time, downtime time.Duration) (time.Time, bool) {
+	var next time.Time
+	found := false
+	for _, w := range s {
+		after := now
+		// the current window may be too short for the rest, then check the next one
+		for i := 0; i < 2; i++ {
+			start, end, ok := w.Next(after)
+			if !ok {
+				break
+			}
+			if start.Before(now) {
+				start = now
+			}
+			if !start.Add(downtime).After(end) {
+				if !found || start.Before(next) {
+					next = start
+					found = true
+				}
+				break
+			}
+			after = end
+		}
+	}
+	return next, found
+}
+
+// waitForWindow blocks until the estimated downtime of the server fits in a maintenance window
+//
+// It returns false if the server is skipped or the migration is drained,
+// and returns error if no window fits the downtime.
+func (m *Migration) waitForWindow(status *ServerStatus) (bool, error) {
+	for {
+		wait, err := m.windowDelay(status, time.Now())
+		if err != nil {
+			m.setWindowStatus(status, time.Time{}, err.Error())
+			return false, err
+		}
+		if wait <= 0 {
+			m.setWindowStatus(status, time.Time{}, "")
+			return true, nil
+		}
+
+		m.setWindowStatus(status, time.Now().Add(wait), "")
+		if wait > windowPollInterval {
+			wait = windowPollInterval
+		}
+		time.Sleep(wait)
+
+		if !m.waitForStart(status) {
+			m.setWindowStatus(status, time.Time{}, "")
+			return false, nil
+		}
+	}
+}
+
+// windowDelay returns the duration until the server can be started in a maintenance window, zero if it can be started now
+func (m *Migration) windowDelay(status *ServerStatus, now time.Time) (time.Duration, error) {
+	if len(m.windows) == 0 {
+		return 0, nil
+	}
+	downtime := status.estimatedDowntime(m.history)
+
+	// with pre-clone, the server is shut down after cloning
+	var lead time.Duration
+	if status.preClone {
+		lead = status.estimatedCopyTime(m.history)
+		downtime -= lead
+	}
+
+	next, ok := m.windows.NextStart(now.Add(lead), downtime)
+	if !ok {
+		return 0, fmt.Errorf("no maintenance window fits estimated downtime(%s)", downtime)
+	}
+	next = next.Add(-lead)
+	if !next.After(now) {
+		return 0, nil
+	}
+	return next.Sub(now), nil
+}
+
+func (m *Migration) setWindowStatus(status *ServerStatus, next time.Time, message string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !next.IsZero() && status.nextWindow.IsZero() {
+		status.logger.log(logging.LevelInfo, "waiting for maintenance window", "next", next.Format(windowTimeLayout))
+	}
+	if message != "" && status.windowMessage == "" {
+		status.logger.log(logging.LevelWarn, message)
+	}
+	status.nextWindow = next
+	status.windowMessage = message
+}
+
+// WindowStatus returns the status of waiting for a maintenance window, or empty string if the server is not waiting
+func (s *ServerStatus) WindowStatus() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.windowMessage != "" {
+		return s.windowMessage
+	}
+	if !s.nextWindow.IsZero() {
+		return fmt.Sprintf("waiting for window(next: %s)", s.nextWindow.Format(windowTimeLayout))
+	}
+	return ""
+}
@@ -0,0 +1,227 @@
+package migrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sacloud/libsacloud/sacloud"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWindow(t *testing.T) {
+	w, err := ParseWindow("23:00-05:30")
+	assert.NoError(t, err)
+	assert.Equal(t, &DailyWindow{Start: 23 * time.Hour, End: 5*time.Hour + 30*time.Minute}, w)
+	assert.Equal(t, "23:00-05:30", w.String())
+
+	w, err = ParseWindow("2026-10-20 01:00/2026-10-20 05:00")
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-10-20 01:00/2026-10-20 05:00", w.String())
+
+	for _, spec := range []string{"", "01:00", "25:00-01:00", "01:00-01:00", "2026-10-20 05:00/2026-10-20 01:00"} {
+		_, err := ParseWindow(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestSchedule_NextStart(t *testing.T) {
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2026, 10, day, hour, min, 0, 0, time.Local)
+	}
+	nightly := Schedule{&DailyWindow{Start: 23 * time.Hour, End: 5 * time.Hour}}
+
+	expects := []struct {
+		name     string
+		schedule Schedule
+		now      time.Time
+		downtime time.Duration
+		next     time.Time
+		ok       bool
+	}{
+		{
+			name:     "in window",
+			schedule: nightly,
+			now:      at(20, 1, 0),
+			downtime: time.Hour,
+			next:     at(20, 1, 0),
+			ok:       true,
+		},
+		{
+			name:     "before window",
+			schedule: nightly,
+			now:      at(20, 12, 0),
+			downtime: time.Hour,
+			next:     at(20, 23, 0),
+			ok:       true,
+		},
+		{
+			name:     "rest of window is too short",
+			schedule: nightly,
+			now:      at(20, 4, 30),
+			downtime: time.Hour,
+			next:     at(20, 23, 0),
+			ok:       true,
+		},
+		{
+			name:     "downtime is longer than window",
+			schedule: nightly,
+			now:      at(20, 1, 0),
+			downtime: 7 * time.Hour,
+			ok:       false,
+		},
+		{
+			name:     "period",
+			schedule: append(Schedule{&PeriodWindow{Start: at(21, 1, 0), End: at(21, 3, 0)}}, nightly...),
+			now:      at(20, 12, 0),
+			downtime: 90 * time.Minute,
+			next:     at(20, 23, 0),
+			ok:       true,
+		},
+		{
+			name:     "period is over",
+			schedule: Schedule{&PeriodWindow{Start: at(19, 1, 0), End: at(19, 3, 0)}},
+			now:      at(20, 12, 0),
+			downtime: time.Minute,
+			ok:       false,
+		},
+	}
+
+	for _, expect := range expects {
+		t.Run(expect.name, func(t *testing.T) {
+			next, ok := expect.schedule.NextStart(expect.now, expect.downtime)
+			assert.Equal(t, expect.ok, ok)
+			if expect.ok {
+				assert.Equal(t, expect.next, next)
+			}
+		})
+	}
+}
+
+func TestMigration_WaitForWindow(t *testing.T) {
+	defer func(interval time.Duration) { windowPollInterval = interval }(windowPollInterval)
+	windowPollInterval = 10 * time.Millisecond
+
+	t.Run("wait for next window", func(t *testing.T) {
+		start := time.Now().Add(100 * time.Millisecond)
+		m, err := NewMigration(&fakeClient{server: singleDiskServer()}, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			Windows:        Schedule{&PeriodWindow{Start: start, End: start.Add(time.Hour)}},
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		doneC := make(chan bool)
+		go func() {
+			m.Apply()
+			doneC <- true
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, ServerStateWaiting, m.Status()[0].State())
+		assert.Contains(t, m.Status()[0].WindowStatus(), "waiting for window")
+
+		<-doneC
+		assert.Equal(t, ServerStateDone, m.Status()[0].State())
+		assert.Empty(t, m.Status()[0].WindowStatus())
+	})
+
+	t.Run("no window fits", func(t *testing.T) {
+		start := time.Now()
+		client := &fakeClient{server: singleDiskServer()}
+		m, err := NewMigration(client, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			Windows:        Schedule{&PeriodWindow{Start: start, End: start.Add(time.Minute)}},
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		listener := &recordListener{}
+		m.listeners = []Listener{listener}
+
+		m.Apply()
+		assert.Equal(t, ServerStateFailed, m.Status()[0].State())
+		assert.Contains(t, m.Status()[0].WindowStatus(), "no maintenance window fits")
+		assert.EqualError(t, m.Status()[0].GetErr(), "no maintenance window fits estimated downtime(14m23s)")
+		assert.Len(t, m.HasErrors(), 1)
+		assert.Equal(t, EventServerFailed, listener.events[1].Type)
+		assert.Empty(t, client.clonedPlanIDs)
+	})
+
+	t.Run("waiting server doesn't take a worker", func(t *testing.T) {
+		small := singleDiskServer()
+		small.ID = 5
+		small.Disks[0].SizeMB = 1024
+		client := &fakeClient{server: singleDiskServer(), servers: map[int64]*sacloud.Server{5: small}}
+
+		// only the small server fits the current window
+		now := time.Now()
+		next := now.Add(150 * time.Millisecond)
+		m, err := NewMigration(client, []int64{serverID, 5}, &Options{
+			MaxWorkerCount: 1,
+			Windows: Schedule{
+				&PeriodWindow{Start: now, End: now.Add(5 * time.Minute)},
+				&PeriodWindow{Start: next, End: next.Add(time.Hour)},
+			},
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		doneC := make(chan bool)
+		go func() {
+			m.Apply()
+			doneC <- true
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		assert.Equal(t, ServerStateWaiting, m.Status()[0].State())
+		assert.Equal(t, ServerStateDone, m.Status()[1].State())
+
+		<-doneC
+		assert.Equal(t, ServerStateDone, m.Status()[0].State())
+	})
+
+	t.Run("wait for window again after pre-clone", func(t *testing.T) {
+		now := time.Now()
+		window := &PeriodWindow{Start: now, End: now.Add(time.Hour)}
+		client := &windowClosingClient{fakeClient: &fakeClient{server: singleDiskServer()}, window: window}
+		m, err := NewMigration(client, []int64{serverID}, &Options{
+			MaxWorkerCount: 1,
+			PreClone:       true,
+			Windows:        Schedule{window},
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		doneC := make(chan bool)
+		go func() {
+			m.Apply()
+			doneC <- true
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		assert.Contains(t, m.Status()[0].WindowStatus(), "waiting for window")
+		client.lock.Lock()
+		assert.Equal(t, []string{"CloneDisk"}, client.calls)
+		client.lock.Unlock()
+
+		<-doneC
+		assert.Equal(t, ServerStateDone, m.Status()[0].State())
+		assert.Equal(t, []string{"CloneDisk", "Shutdown"}, client.calls)
+	})
+}
+
+// windowClosingClient closes the window while cloning disks, and the next window starts 100ms later
+type windowClosingClient struct {
+	*fakeClient
+	window *PeriodWindow
+}
+
+func (c *windowClosingClient) CloneDisk(id int64, planID int64) (<-chan interface{}, error) {
+	next := time.Now().Add(100 * time.Millisecond)
+	c.window.Start, c.window.End = next, next.Add(time.Hour)
+	return c.fakeClient.CloneDisk(id, planID)
+}
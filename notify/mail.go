@@ -0,0 +1,199 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+// Mailer sends the summary of the migration by email
+type Mailer struct {
+	// Addr is the address of the SMTP server(host:port)
+	Addr     string
+	Username string
+	Password string
+	From     string
+	To       []string
+
+	// Subject is the subject of the mail, the default subject with totals is used if empty
+	Subject string
+}
+
+// SendReport sends the summary built from the report
+func (m *Mailer) SendReport(report *migrate.Report) error {
+	msg, err := m.BuildMessage(report)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, m.From, m.To, msg)
+}
+
+// BuildMessage returns the multipart(text and HTML) mail message of the report
+func (m *Mailer) BuildMessage(report *migrate.Report) ([]byte, error) {
+	summary := newMailSummary(report)
+
+	textBody := new(bytes.Buffer)
+	if err := mailTextTemplate.Execute(textBody, summary); err != nil {
+		return nil, err
+	}
+	htmlBody := new(bytes.Buffer)
+	if err := mailHTMLTemplate.Execute(htmlBody, summary); err != nil {
+		return nil, err
+	}
+
+	subject := m.Subject
+	if subject == "" {
+		subject = fmt.Sprintf("[cloud-plan-migrate] Migration finished: Done %d, Failed %d (Total %d)",
+			report.Totals.Done, report.Totals.Failed, report.Totals.Total())
+	}
+
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	for _, part := range []struct {
+		contentType string
+		data        []byte
+	}{
+		{"text/plain; charset=UTF-8", textBody.Bytes()},
+		{"text/html; charset=UTF-8", htmlBody.Bytes()},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write(part.data); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	msg := new(bytes.Buffer)
+	fmt.Fprintf(msg, "From: %s\r\n", m.From)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(msg, "Content-Type: multipart/alternative; boundary=%q\r\n", w.Boundary())
+	fmt.Fprintf(msg, "\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+type mailSummary struct {
+	StartedAt  string
+	FinishedAt string
+	Totals     *migrate.Totals
+	Succeeded  []*mailServer
+	Failed     []*mailServer
+	Others     []*mailServer
+}
+
+type mailServer struct {
+	ID         int64
+	Name       string
+	State      migrate.ServerState
+	MigratedID string
+	Downtime   string
+	Error      string
+}
+
+func newMailSummary(report *migrate.Report) *mailSummary {
+	summary := &mailSummary{
+		StartedAt:  formatMailTime(report.StartedAt),
+		FinishedAt: formatMailTime(report.FinishedAt),
+		Totals:     report.Totals,
+	}
+	for _, s := range report.Servers {
+		server := &mailServer{
+			ID:         s.ServerID,
+			Name:       s.ServerName,
+			State:      s.State,
+			MigratedID: "-",
+			Downtime:   "-",
+			Error:      s.Error,
+		}
+		if s.MigratedServerID != 0 {
+			server.MigratedID = fmt.Sprintf("%d", s.MigratedServerID)
+		}
+		if s.Downtime > 0 {
+			server.Downtime = s.Downtime.Round(time.Second).String()
+		}
+
+		switch s.State {
+		case migrate.ServerStateDone:
+			summary.Succeeded = append(summary.Succeeded, server)
+		case migrate.ServerStateFailed:
+			summary.Failed = append(summary.Failed, server)
+		default:
+			summary.Others = append(summary.Others, server)
+		}
+	}
+	return summary
+}
+
+func formatMailTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05 MST")
+}
+
+var mailTextTemplate = template.Must(template.New("text").Parse(`Migration finished
+
+Started:  {{.StartedAt}}
+Finished: {{.FinishedAt}}
+Total: {{.Totals.Total}}  Done: {{.Totals.Done}}  Failed: {{.Totals.Failed}}  Queued: {{.Totals.Queued}}  Skipped: {{.Totals.Skipped}}  RolledBack: {{.Totals.RolledBack}}
+{{if .Succeeded}}
+Succeeded servers:
+{{range .Succeeded}}  Server[{{.ID}}:{{.Name}}] => Server[{{.MigratedID}}] downtime: {{.Downtime}}
+{{end}}{{end}}{{if .Failed}}
+Failed servers:
+{{range .Failed}}  Server[{{.ID}}:{{.Name}}] => Server[{{.MigratedID}}] downtime: {{.Downtime}}
+    Error: {{.Error}}
+{{end}}{{end}}{{if .Others}}
+Other servers:
+{{range .Others}}  Server[{{.ID}}:{{.Name}}] {{.State}}
+{{end}}{{end}}`))
+
+var mailHTMLTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<html>
+<body>
+<h2>Migration finished</h2>
+<p>Started: {{.StartedAt}}<br>Finished: {{.FinishedAt}}</p>
+<p>Total: {{.Totals.Total}} / Done: {{.Totals.Done}} / Failed: {{.Totals.Failed}} / Queued: {{.Totals.Queued}} / Skipped: {{.Totals.Skipped}} / RolledBack: {{.Totals.RolledBack}}</p>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Server</th><th>Name</th><th>State</th><th>Migrated Server</th><th>Downtime</th><th>Error</th></tr>
+{{range .Failed}}<tr style="color:#c00"><td>{{.ID}}</td><td>{{.Name}}</td><td>{{.State}}</td><td>{{.MigratedID}}</td><td>{{.Downtime}}</td><td>{{.Error}}</td></tr>
+{{end}}{{range .Succeeded}}<tr><td>{{.ID}}</td><td>{{.Name}}</td><td>{{.State}}</td><td>{{.MigratedID}}</td><td>{{.Downtime}}</td><td></td></tr>
+{{end}}{{range .Others}}<tr style="color:#888"><td>{{.ID}}</td><td>{{.Name}}</td><td>{{.State}}</td><td>-</td><td>-</td><td></td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
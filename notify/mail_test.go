@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"bufio"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"github.com/stretchr/testify/assert"
+)
+
+// smtpStandIn is a minimal SMTP server accepting a mail
+type smtpStandIn struct {
+	listener   net.Listener
+	from       string
+	recipients []string
+	data       string
+	done       chan struct{}
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStandIn{listener: l, done: make(chan struct{})}
+	go s.serve()
+	return s
+}
+
+func (s *smtpStandIn) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	c := textproto.NewConn(conn)
+	c.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			c.PrintfLine("250 localhost")
+		case "MAIL":
+			s.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			c.PrintfLine("250 OK")
+		case "RCPT":
+			s.recipients = append(s.recipients, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			c.PrintfLine("250 OK")
+		case "DATA":
+			c.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.data = string(data)
+			c.PrintfLine("250 OK")
+		case "QUIT":
+			c.PrintfLine("221 Bye")
+			return
+		default:
+			c.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+func (s *smtpStandIn) wait() {
+	s.listener.Close()
+	<-s.done
+}
+
+func testReport() *migrate.Report {
+	started := time.Date(2026, 10, 20, 1, 0, 0, 0, time.UTC)
+	return &migrate.Report{
+		StartedAt:  started,
+		FinishedAt: started.Add(time.Hour),
+		Totals:     &migrate.Totals{Done: 1, Failed: 1, Skipped: 1},
+		Servers: []*migrate.ServerReport{
+			{ServerID: 1, ServerName: "web1", State: migrate.ServerStateDone, MigratedServerID: 11, Downtime: 5 * time.Minute},
+			{ServerID: 2, ServerName: "<db>", State: migrate.ServerStateFailed, Error: "Disconnect Disk is failed"},
+			{ServerID: 3, ServerName: "web3", State: migrate.ServerStateSkipped},
+		},
+	}
+}
+
+func TestMailer_SendReport(t *testing.T) {
+	server := newSMTPStandIn(t)
+
+	mailer := &Mailer{
+		Addr: server.listener.Addr().String(),
+		From: "migrate@example.com",
+		To:   []string{"ops@example.com", "cab@example.com"},
+	}
+	err := mailer.SendReport(testReport())
+	assert.NoError(t, err)
+	server.wait()
+
+	assert.Equal(t, "migrate@example.com", server.from)
+	assert.Equal(t, []string{"ops@example.com", "cab@example.com"}, server.recipients)
+
+	msg, err := mail.ReadMessage(strings.NewReader(server.data))
+	assert.NoError(t, err)
+	assert.Equal(t, "[cloud-plan-migrate] Migration finished: Done 1, Failed 1 (Total 3)", msg.Header.Get("Subject"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	parts := map[string]string{}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		data, _ := ioutil.ReadAll(p) // quoted-printable is decoded by NextPart
+		parts[strings.SplitN(p.Header.Get("Content-Type"), ";", 2)[0]] = string(data)
+	}
+
+	text := parts["text/plain"]
+	assert.Contains(t, text, "Total: 3  Done: 1  Failed: 1")
+	assert.Contains(t, text, "Server[1:web1] => Server[11] downtime: 5m0s")
+	assert.Contains(t, text, "Server[2:<db>] => Server[-] downtime: -\n    Error: Disconnect Disk is failed")
+	assert.Contains(t, text, "Server[3:web3] skipped")
+
+	html := parts["text/html"]
+	assert.Contains(t, html, "<td>11</td><td>5m0s</td>")
+	assert.Contains(t, html, "&lt;db&gt;")
+	assert.NotContains(t, html, "<db>")
+}
+
+func TestMailer_BuildMessage_Subject(t *testing.T) {
+	mailer := &Mailer{
+		From:    "migrate@example.com",
+		To:      []string{"ops@example.com"},
+		Subject: "移行結果",
+	}
+	data, err := mailer.BuildMessage(testReport())
+	assert.NoError(t, err)
+
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(string(data))))
+	assert.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.NoError(t, err)
+	assert.Equal(t, "移行結果", subject)
+}
@@ -0,0 +1,205 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/logging"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+const (
+	// DefaultRetryMax is the number of retries after the first attempt
+	DefaultRetryMax = 3
+	// DefaultRetryInterval is the interval before the first retry, doubled for each retry
+	DefaultRetryInterval = 2 * time.Second
+	// DefaultTimeout is the timeout of each request
+	DefaultTimeout = 10 * time.Second
+
+	queueSize = 100
+)
+
+// Endpoint is a destination of notifications
+type Endpoint struct {
+	URL      string
+	Template *template.Template
+}
+
+// NewWebhookEndpoint returns Endpoint posting the payload rendered by tmpl, or the event itself as JSON if tmpl is nil
+func NewWebhookEndpoint(u string, tmpl *template.Template) *Endpoint {
+	if tmpl == nil {
+		tmpl = webhookTemplate
+	}
+	return &Endpoint{URL: u, Template: tmpl}
+}
+
+// NewSlackEndpoint returns Endpoint posting the message to the Slack-compatible incoming webhook
+func NewSlackEndpoint(u string) *Endpoint {
+	return &Endpoint{URL: u, Template: slackTemplate}
+}
+
+// host returns the host of the URL, webhook URLs often contain secrets so only the host is logged
+func (e *Endpoint) host() string {
+	u, err := url.Parse(e.URL)
+	if err != nil || u.Host == "" {
+		return "(invalid url)"
+	}
+	return u.Host
+}
+
+// Notifier posts migration events to endpoints in background
+//
+// Notifier implements migrate.Listener. Close must be called to deliver queued events before exit.
+type Notifier struct {
+	Endpoints     []*Endpoint
+	Client        *http.Client
+	RetryMax      int
+	RetryInterval time.Duration
+	Logger        migrate.Logger
+
+	// Secrets are replaced with logging.Redacted in errors of events like the report(e.g. API keys in messages of the API)
+	Secrets []string
+
+	queue chan *migrate.Event
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewNotifier returns Notifier with default retry settings and starts delivering events
+func NewNotifier(endpoints []*Endpoint, logger migrate.Logger) *Notifier {
+	n := &Notifier{
+		Endpoints:     endpoints,
+		Client:        &http.Client{Timeout: DefaultTimeout},
+		RetryMax:      DefaultRetryMax,
+		RetryInterval: DefaultRetryInterval,
+		Logger:        logger,
+	}
+	n.Start()
+	return n
+}
+
+// Start starts delivering events, it is called by NewNotifier
+func (n *Notifier) Start() {
+	n.queue = make(chan *migrate.Event, queueSize)
+	n.done = make(chan struct{})
+	go func() {
+		defer close(n.done)
+		for event := range n.queue {
+			for _, e := range n.Endpoints {
+				if err := n.Send(e, event); err != nil {
+					n.logError(event, e, err)
+				}
+			}
+		}
+	}()
+}
+
+// notifiedEvents are event types posted to endpoints, events of each step are recorded only in the journal
+var notifiedEvents = map[migrate.EventType]bool{
+	migrate.EventRunStarted:       true,
+	migrate.EventServerCompleted:  true,
+	migrate.EventServerFailed:     true,
+	migrate.EventServerRolledBack: true,
+	migrate.EventRunFinished:      true,
+}
+
+// Notify queues the event, or drops it if the queue is full not to block the migration
+func (n *Notifier) Notify(event *migrate.Event) {
+	if !notifiedEvents[event.Type] {
+		return
+	}
+	select {
+	case n.queue <- n.redact(event):
+	default:
+		n.logDropped(event)
+	}
+}
+
+// redact returns the copy of the event with secrets redacted, the event is shared with other listeners
+func (n *Notifier) redact(event *migrate.Event) *migrate.Event {
+	redacted := *event
+	for _, secret := range n.Secrets {
+		redacted.Error = strings.Replace(redacted.Error, secret, logging.Redacted, -1)
+	}
+	return &redacted
+}
+
+// Close waits until all queued events are delivered
+func (n *Notifier) Close() {
+	n.once.Do(func() {
+		close(n.queue)
+		<-n.done
+	})
+}
+
+// Send posts the event to the endpoint with retries
+func (n *Notifier) Send(endpoint *Endpoint, event *migrate.Event) error {
+	buf := new(bytes.Buffer)
+	if err := endpoint.Template.Execute(buf, event); err != nil {
+		return fmt.Errorf("rendering payload is failed: %s", err)
+	}
+	payload := buf.Bytes()
+
+	interval := n.RetryInterval
+	var err error
+	for i := 0; i <= n.RetryMax; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+			interval *= 2
+		}
+		var retryable bool
+		retryable, err = n.post(endpoint.URL, payload)
+		if err == nil || !retryable {
+			return err
+		}
+	}
+	return err
+}
+
+// post posts the payload and returns whether the request can be retried on error
+func (n *Notifier) post(u string, payload []byte) (bool, error) {
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Post(u, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode/100 == 2 {
+		return false, nil
+	}
+	retryable := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+	return retryable, fmt.Errorf("unexpected status: %s", res.Status)
+}
+
+func (n *Notifier) logDropped(event *migrate.Event) {
+	switch logger := n.Logger.(type) {
+	case nil:
+	case migrate.StructuredLogger:
+		logger.Log(logging.LevelWarn, "Notification is dropped: the queue is full", "event", string(event.Type), "server_id", event.ServerID)
+	default:
+		logger.Printf(": Notification %s is dropped: the queue is full", event.Type)
+	}
+}
+
+func (n *Notifier) logError(event *migrate.Event, endpoint *Endpoint, err error) {
+	switch logger := n.Logger.(type) {
+	case nil:
+	case migrate.StructuredLogger:
+		logger.Log(logging.LevelWarn, "Notify is failed", "event", string(event.Type), "host", endpoint.host(), "error", err)
+	default:
+		logger.Printf(": Notify %s to %s is failed: %s", event.Type, endpoint.host(), err)
+	}
+}
@@ -0,0 +1,205 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"github.com/stretchr/testify/assert"
+)
+
+// receiver is a local HTTP server recording received payloads
+type receiver struct {
+	*httptest.Server
+	payloads []string
+	statuses []int // response status for each request, 200 after all consumed
+	lock     sync.Mutex
+}
+
+func newReceiver(statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		r.payloads = append(r.payloads, string(body))
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	return r
+}
+
+func (r *receiver) received() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.payloads
+}
+
+func testNotifier(endpoints ...*Endpoint) *Notifier {
+	n := &Notifier{
+		Endpoints:     endpoints,
+		Client:        &http.Client{Timeout: time.Second},
+		RetryMax:      2,
+		RetryInterval: time.Millisecond,
+	}
+	n.Start()
+	return n
+}
+
+func testEvents() []*migrate.Event {
+	now := time.Date(2026, 10, 20, 1, 0, 0, 0, time.UTC)
+	return []*migrate.Event{
+		{Type: migrate.EventRunStarted, Time: now, Totals: &migrate.Totals{Queued: 2}},
+		{Type: migrate.EventServerCompleted, Time: now, ServerID: 1, ServerName: "web1", MigratedServerID: 11,
+			Totals: &migrate.Totals{Queued: 1, Done: 1}},
+		{Type: migrate.EventServerFailed, Time: now, ServerID: 2, ServerName: "web\"2", Error: "boot is failed",
+			Totals: &migrate.Totals{Done: 1, Failed: 1}},
+		// step events are not notified
+		{Type: migrate.EventDisksCloned, Time: now, ServerID: 3, ServerName: "web3", Totals: &migrate.Totals{Done: 1, Failed: 1}},
+		{Type: migrate.EventRunFinished, Time: now, Totals: &migrate.Totals{Done: 1, Failed: 1}},
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	t.Run("webhook", func(t *testing.T) {
+		r := newReceiver()
+		defer r.Close()
+
+		n := testNotifier(NewWebhookEndpoint(r.URL, nil))
+		for _, e := range testEvents() {
+			n.Notify(e)
+		}
+		n.Close()
+
+		payloads := r.received()
+		assert.Len(t, payloads, 4)
+
+		var event migrate.Event
+		assert.NoError(t, json.Unmarshal([]byte(payloads[2]), &event))
+		assert.Equal(t, migrate.EventServerFailed, event.Type)
+		assert.Equal(t, int64(2), event.ServerID)
+		assert.Equal(t, "boot is failed", event.Error)
+		assert.Equal(t, 1, event.Totals.Failed)
+	})
+
+	t.Run("slack", func(t *testing.T) {
+		r := newReceiver()
+		defer r.Close()
+
+		n := testNotifier(NewSlackEndpoint(r.URL))
+		for _, e := range testEvents() {
+			n.Notify(e)
+		}
+		n.Close()
+
+		payloads := r.received()
+		assert.Equal(t, []string{
+			`{"text": "Migration started: 2 servers"}`,
+			`{"text": "Server[1:web1] is migrated to Server[11] (1/2 finished)"}`,
+			`{"text": "Server[2:web\"2] is failed: boot is failed (2/2 finished)"}`,
+			`{"text": "Migration finished: Done: 1  Failed: 1  Queued: 0  Skipped: 0  RolledBack: 0"}`,
+		}, payloads)
+	})
+
+	t.Run("custom template", func(t *testing.T) {
+		r := newReceiver()
+		defer r.Close()
+
+		tmpl, err := ParseTemplate(`{"event": {{json .Type}}, "server": {{.ServerID}}}`)
+		assert.NoError(t, err)
+
+		n := testNotifier(NewWebhookEndpoint(r.URL, tmpl))
+		n.Notify(testEvents()[1])
+		n.Close()
+
+		assert.Equal(t, []string{`{"event": "server_completed", "server": 1}`}, r.received())
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		r := newReceiver(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+		defer r.Close()
+
+		n := testNotifier()
+		err := n.Send(NewSlackEndpoint(r.URL), testEvents()[0])
+		assert.NoError(t, err)
+		assert.Len(t, r.received(), 3)
+	})
+
+	t.Run("retry exceeded", func(t *testing.T) {
+		r := newReceiver(500, 500, 500, 500)
+		defer r.Close()
+
+		n := testNotifier()
+		err := n.Send(NewSlackEndpoint(r.URL), testEvents()[0])
+		assert.EqualError(t, err, "unexpected status: 500 Internal Server Error")
+		assert.Len(t, r.received(), 3)
+	})
+
+	t.Run("client error is not retried", func(t *testing.T) {
+		r := newReceiver(http.StatusNotFound)
+		defer r.Close()
+
+		n := testNotifier()
+		err := n.Send(NewSlackEndpoint(r.URL), testEvents()[0])
+		assert.EqualError(t, err, "unexpected status: 404 Not Found")
+		assert.Len(t, r.received(), 1)
+	})
+
+	t.Run("redact secrets", func(t *testing.T) {
+		r := newReceiver()
+		defer r.Close()
+
+		n := testNotifier(NewWebhookEndpoint(r.URL, nil))
+		n.Secrets = []string{"top-secret"}
+		event := &migrate.Event{Type: migrate.EventServerFailed, ServerID: 1, Error: "401 Unauthorized: token=top-secret"}
+		n.Notify(event)
+		n.Close()
+
+		payloads := r.received()
+		assert.Len(t, payloads, 1)
+		assert.Contains(t, payloads[0], `"error":"401 Unauthorized: token=[REDACTED]"`)
+		assert.NotContains(t, payloads[0], "top-secret")
+
+		// the event is shared with other listeners
+		assert.Equal(t, "401 Unauthorized: token=top-secret", event.Error)
+	})
+
+	t.Run("drop events when the queue is full", func(t *testing.T) {
+		logger := &printfLogger{}
+		// not started, so queued events are not consumed
+		n := &Notifier{Logger: logger, queue: make(chan *migrate.Event, 1)}
+
+		done := make(chan bool)
+		go func() {
+			n.Notify(testEvents()[0])
+			n.Notify(testEvents()[1])
+			done <- true
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Notify is blocked")
+		}
+
+		assert.Len(t, n.queue, 1)
+		assert.Equal(t, []string{": Notification server_completed is dropped: the queue is full"}, logger.lines)
+	})
+}
+
+type printfLogger struct {
+	lines []string
+}
+
+func (l *printfLogger) Printf(format string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/template"
+
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+)
+
+var funcs = template.FuncMap{
+	"json":    toJSON,
+	"message": Message,
+}
+
+var (
+	webhookTemplate = template.Must(ParseTemplate(`{{json .}}`))
+	slackTemplate   = template.Must(ParseTemplate(`{"text": {{json (message .)}}}`))
+)
+
+// ParseTemplate parses the payload template
+//
+// The template is executed with migrate.Event, and functions below are available.
+//
+//	json:    encodes the value as JSON(e.g. {{json .ServerName}})
+//	message: returns the human readable message of the event
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("payload").Funcs(funcs).Parse(text)
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Message returns the human readable message of the event
+func Message(event *migrate.Event) string {
+	t := event.Totals
+	if t == nil {
+		t = &migrate.Totals{}
+	}
+	switch event.Type {
+	case migrate.EventRunStarted:
+		return fmt.Sprintf("Migration started: %d servers", t.Total())
+	case migrate.EventServerCompleted:
+		return fmt.Sprintf("Server[%d:%s] is migrated to Server[%d] (%d/%d finished)",
+			event.ServerID, event.ServerName, event.MigratedServerID, t.Done+t.Failed, t.Total())
+	case migrate.EventServerFailed:
+		return fmt.Sprintf("Server[%d:%s] is failed: %s (%d/%d finished)",
+			event.ServerID, event.ServerName, event.Error, t.Done+t.Failed, t.Total())
+	case migrate.EventServerRolledBack:
+		return fmt.Sprintf("Server[%d:%s] is rolled back", event.ServerID, event.ServerName)
+	case migrate.EventRunFinished:
+		return fmt.Sprintf("Migration finished: Done: %d  Failed: %d  Queued: %d  Skipped: %d  RolledBack: %d",
+			t.Done, t.Failed, t.Queued+t.Waiting, t.Skipped, t.RolledBack)
+	}
+	return string(event.Type)
+}
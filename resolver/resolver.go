@@ -0,0 +1,411 @@
+package resolver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sacloud/cloud-plan-migrate/iaas"
+	"github.com/sacloud/cloud-plan-migrate/inventory"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"github.com/sacloud/cloud-plan-migrate/selector"
+	"github.com/sacloud/libsacloud/sacloud"
+)
+
+// Query is the condition of target servers
+type Query struct {
+	Args      []string // IDs or names, each value can contain multiple lines
+	Selector  []string
+	Inventory string // path of the inventory file
+	Wave      string
+	Exclude   []string // IDs, names(glob) or tags(tag=<value>)
+	DenyList  *migrate.Protection
+}
+
+// HasTargets reports whether any condition of target servers is specified
+func (q *Query) HasTargets() bool {
+	return len(q.Args) > 0 || len(q.Selector) > 0 || q.Inventory != ""
+}
+
+// MatchKind is the kind of the condition matched with a target server
+type MatchKind string
+
+const (
+	MatchID        MatchKind = "id"
+	MatchName      MatchKind = "name"
+	MatchSelector  MatchKind = "selector"
+	MatchInventory MatchKind = "inventory"
+)
+
+// Match is a condition matched with a target server
+type Match struct {
+	Kind  MatchKind
+	Value string // the argument, the selector expression or the line of the inventory
+}
+
+func (m *Match) String() string {
+	return fmt.Sprintf("%s(%s)", m.Kind, m.Value)
+}
+
+// Target is a resolved target server with why it is a target
+type Target struct {
+	Server  *sacloud.Server
+	Matches []*Match
+	Options *migrate.ServerOptions // options from the inventory, nil if not specified
+}
+
+// Reason returns matched conditions joined by comma
+func (t *Target) Reason() string {
+	var list []string
+	for _, m := range t.Matches {
+		list = append(list, m.String())
+	}
+	return strings.Join(list, ", ")
+}
+
+func (t *Target) matched(kind MatchKind) bool {
+	for _, m := range t.Matches {
+		if m.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Excluded is a server matched with the query but excluded by Query.Exclude
+type Excluded struct {
+	Server *sacloud.Server
+	Reason string
+}
+
+// Result is resolved target servers
+type Result struct {
+	Targets  []*Target
+	Excluded []*Excluded
+
+	// Protection is excluded servers and the deny-list to guard them during the migration
+	Protection *migrate.Protection
+}
+
+// Servers returns servers of targets
+func (r *Result) Servers() []*sacloud.Server {
+	var servers []*sacloud.Server
+	for _, t := range r.Targets {
+		servers = append(servers, t.Server)
+	}
+	return servers
+}
+
+// IDs returns IDs of targets
+func (r *Result) IDs() []int64 {
+	var ids []int64
+	for _, t := range r.Targets {
+		ids = append(ids, t.Server.ID)
+	}
+	return ids
+}
+
+// ServerOptions returns options of targets keyed by server ID, or nil if no target has options
+func (r *Result) ServerOptions() map[int64]*migrate.ServerOptions {
+	var options map[int64]*migrate.ServerOptions
+	for _, t := range r.Targets {
+		if t.Options != nil {
+			if options == nil {
+				options = map[int64]*migrate.ServerOptions{}
+			}
+			options[t.Server.ID] = t.Options
+		}
+	}
+	return options
+}
+
+// AmbiguousNameError is returned when servers matched with a name argument have the same name
+type AmbiguousNameError struct {
+	Arg     string
+	Name    string
+	Servers []*sacloud.Server
+}
+
+func (e *AmbiguousNameError) Error() string {
+	var ids []string
+	for _, s := range e.Servers {
+		ids = append(ids, s.GetStrID())
+	}
+	return fmt.Sprintf("name %q is ambiguous: servers [%s] have the same name %q, specify them by ID",
+		e.Arg, strings.Join(ids, ", "), e.Name)
+}
+
+// Resolver finds target servers by IDs/names, selector or inventory
+type Resolver struct {
+	client iaas.Client
+
+	// Choose is called when servers matched with a name argument have the same name,
+	// and returns servers to be targets among them.
+	// If nil, AmbiguousNameError is returned.
+	Choose func(name string, servers []*sacloud.Server) ([]*sacloud.Server, error)
+}
+
+// New returns Resolver using the client
+func New(client iaas.Client) *Resolver {
+	return &Resolver{client: client}
+}
+
+// Resolve returns target servers matched with the query
+//
+// Names are matched like the Name filter of the API(containing all of space separated keywords).
+// The selector narrows servers matched by names or the inventory, but not servers specified by ID.
+// Excluded servers are removed from targets, ProtectedError is returned if any of targets is in the deny-list.
+func (r *Resolver) Resolve(q *Query) (*Result, error) {
+	targetSelector, err := selector.ParseAll(q.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid selector: %s", err)
+	}
+
+	var targets []*Target
+	switch {
+	case q.Inventory != "":
+		if len(q.Args) > 0 {
+			return nil, fmt.Errorf("ID or Name argument can't be used with --inventory option")
+		}
+		targets, err = r.resolveInventory(q.Inventory, q.Wave)
+	case len(q.Args) > 0:
+		targets, err = r.resolveArgs(q.Args)
+	default:
+		if len(q.Selector) == 0 {
+			return nil, fmt.Errorf("ID or Name argument or --selector option is required")
+		}
+		targets, err = r.resolveSelector(targetSelector)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// servers specified by ID are targets regardless of the selector
+	if targetSelector != nil {
+		var filtered []*Target
+		for _, t := range targets {
+			if targetSelector.Match(t.Server) {
+				t.Matches = append(t.Matches, &Match{Kind: MatchSelector, Value: targetSelector.String()})
+				filtered = append(filtered, t)
+			} else if t.matched(MatchID) {
+				filtered = append(filtered, t)
+			}
+		}
+		targets = filtered
+	}
+
+	result := &Result{}
+
+	// exclude
+	excludes := ParseExcludes(q.Exclude)
+	for _, t := range targets {
+		if reason := excludes.Reason(t.Server); reason != "" {
+			result.Excluded = append(result.Excluded, &Excluded{Server: t.Server, Reason: reason})
+			continue
+		}
+		result.Targets = append(result.Targets, t)
+	}
+
+	// guard protected servers
+	if err := q.DenyList.Check(result.Servers()); err != nil {
+		return nil, err
+	}
+	result.Protection = excludes.Merge(q.DenyList)
+
+	if len(result.Targets) == 0 {
+		return nil, fmt.Errorf("Target resource is not found")
+	}
+	return result, nil
+}
+
+func (r *Resolver) resolveInventory(path, wave string) ([]*Target, error) {
+	entries, err := inventory.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Reading inventory is failed: %s", err)
+	}
+	if wave != "" {
+		entries = inventory.FilterByWave(entries, wave)
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("Reading inventory is failed: wave %q is not found", wave)
+		}
+	}
+
+	param := &iaas.FindParameter{}
+	for _, e := range entries {
+		param.Ids = append(param.Ids, e.ServerID)
+	}
+	servers, err := r.client.Find(param)
+	if err != nil {
+		return nil, fmt.Errorf("Find ID is failed: %s", err)
+	}
+
+	var targets []*Target
+	for _, e := range entries {
+		server := findServer(servers, e.ServerID)
+		if server == nil {
+			return nil, fmt.Errorf("Find ID is failed: Not Found[inventory %s: %d]", e.Position(), e.ServerID)
+		}
+		targets = append(targets, &Target{
+			Server:  server,
+			Matches: []*Match{{Kind: MatchInventory, Value: e.Position()}},
+			Options: inventoryServerOptions(e),
+		})
+	}
+	return targets, nil
+}
+
+func (r *Resolver) resolveArgs(args []string) ([]*Target, error) {
+	param := &iaas.FindParameter{}
+	for _, arg := range args {
+		for _, a := range strings.Split(arg, "\n") {
+			if id, err := strconv.ParseInt(a, 10, 64); err == nil {
+				param.Ids = append(param.Ids, id)
+			} else {
+				param.Names = append(param.Names, a)
+			}
+		}
+	}
+
+	servers, err := r.client.Find(param)
+	if err != nil {
+		return nil, fmt.Errorf("Find ID is failed: %s", err)
+	}
+
+	matches := map[int64][]*Match{}
+	for _, id := range param.Ids {
+		if findServer(servers, id) == nil {
+			return nil, fmt.Errorf("Find ID is failed: Not Found[with search param %d]", id)
+		}
+		matches[id] = append(matches[id], &Match{Kind: MatchID, Value: fmt.Sprintf("%d", id)})
+	}
+	for _, name := range param.Names {
+		var matched []*sacloud.Server
+		for _, s := range servers {
+			if matchNameLike(s.Name, name) {
+				matched = append(matched, s)
+			}
+		}
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("Find ID is failed: Not Found[with search param %q]", name)
+		}
+		matched, err := r.disambiguate(name, matched)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range matched {
+			matches[s.ID] = append(matches[s.ID], &Match{Kind: MatchName, Value: name})
+		}
+	}
+
+	var targets []*Target
+	for _, s := range servers {
+		if m, ok := matches[s.ID]; ok {
+			targets = append(targets, &Target{Server: s, Matches: m})
+		}
+	}
+	return targets, nil
+}
+
+func (r *Resolver) resolveSelector(targetSelector selector.Selector) ([]*Target, error) {
+	servers, err := r.client.Find(&iaas.FindParameter{Tags: selector.RequiredTags(targetSelector)})
+	if err != nil {
+		return nil, fmt.Errorf("Find ID is failed: %s", err)
+	}
+	if len(selector.Filter(servers, targetSelector)) == 0 {
+		return nil, fmt.Errorf("Find ID is failed: Not Found[with selector %s]", targetSelector)
+	}
+
+	var targets []*Target
+	for _, s := range servers {
+		targets = append(targets, &Target{Server: s})
+	}
+	return targets, nil
+}
+
+// disambiguate asks Choose which servers to be targets if servers have the same name
+func (r *Resolver) disambiguate(arg string, servers []*sacloud.Server) ([]*sacloud.Server, error) {
+	var names []string
+	groups := map[string][]*sacloud.Server{}
+	for _, s := range servers {
+		if _, ok := groups[s.Name]; !ok {
+			names = append(names, s.Name)
+		}
+		groups[s.Name] = append(groups[s.Name], s)
+	}
+
+	var result []*sacloud.Server
+	for _, name := range names {
+		group := groups[name]
+		if len(group) == 1 {
+			result = append(result, group...)
+			continue
+		}
+		if r.Choose == nil {
+			return nil, &AmbiguousNameError{Arg: arg, Name: name, Servers: group}
+		}
+		chosen, err := r.Choose(name, group)
+		if err != nil {
+			return nil, err
+		}
+		// ignore servers not in the group
+		for _, c := range chosen {
+			if s := findServer(group, c.ID); s != nil {
+				result = append(result, s)
+			}
+		}
+	}
+	return result, nil
+}
+
+// ParseExcludes parses values of --exclude option
+//
+// Each value is treated as ID if numeric, tag if prefixed with "tag=", otherwise name.
+func ParseExcludes(values []string) *migrate.Protection {
+	excludes := &migrate.Protection{}
+	for _, v := range values {
+		switch {
+		case strings.HasPrefix(v, "tag="):
+			excludes.Tags = append(excludes.Tags, strings.TrimPrefix(v, "tag="))
+		default:
+			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+				excludes.IDs = append(excludes.IDs, id)
+			} else {
+				excludes.Names = append(excludes.Names, v)
+			}
+		}
+	}
+	return excludes
+}
+
+func inventoryServerOptions(e *inventory.Entry) *migrate.ServerOptions {
+	opts := &migrate.ServerOptions{
+		DeleteDisks: e.CleanupDisk,
+		PreClone:    e.PreClone,
+		Core:        e.Core,
+		MemoryGB:    e.MemoryGB,
+	}
+	if e.Boot != nil {
+		disableBoot := !*e.Boot
+		opts.DisableBoot = &disableBoot
+	}
+	return opts
+}
+
+func findServer(servers []*sacloud.Server, id int64) *sacloud.Server {
+	for _, s := range servers {
+		if s.ID == id {
+			return s
+		}
+	}
+	return nil
+}
+
+// matchNameLike reports whether name contains all of space separated keywords like the Name filter of the API
+func matchNameLike(name, keywords string) bool {
+	for _, k := range strings.Fields(keywords) {
+		if !strings.Contains(name, k) {
+			return false
+		}
+	}
+	return true
+}
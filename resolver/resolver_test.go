@@ -0,0 +1,212 @@
+package resolver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sacloud/cloud-plan-migrate/iaas"
+	"github.com/sacloud/cloud-plan-migrate/migrate"
+	"github.com/sacloud/libsacloud/sacloud"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClient finds servers like the API: by any of Ids or Names(keywords), and having all of Tags
+type fakeClient struct {
+	iaas.Client
+	servers []*sacloud.Server
+	params  []*iaas.FindParameter
+}
+
+func (f *fakeClient) Find(param *iaas.FindParameter) ([]*sacloud.Server, error) {
+	f.params = append(f.params, param)
+	var res []*sacloud.Server
+	for _, s := range f.servers {
+		matched := len(param.Ids) == 0 && len(param.Names) == 0
+		for _, id := range param.Ids {
+			matched = matched || s.ID == id
+		}
+		for _, name := range param.Names {
+			matched = matched || matchNameLike(s.Name, name)
+		}
+		for _, tag := range param.Tags {
+			matched = matched && s.HasTag(tag)
+		}
+		if matched {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+func testServer(id int64, name string, tags ...string) *sacloud.Server {
+	server := &sacloud.Server{Resource: sacloud.NewResource(id)}
+	server.Name = name
+	server.Tags = tags
+	return server
+}
+
+func TestResolver_Resolve(t *testing.T) {
+
+	web1 := testServer(101, "web-01", "web")
+	web2 := testServer(102, "web-02", "web")
+	db := testServer(103, "db-01", "db")
+	dup1 := testServer(104, "app", "app")
+	dup2 := testServer(105, "app", "app")
+	client := &fakeClient{servers: []*sacloud.Server{web1, web2, db, dup1, dup2}}
+
+	t.Run("id and name", func(t *testing.T) {
+		result, err := New(client).Resolve(&Query{Args: []string{"103", "web 01"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{101, 103}, result.IDs())
+		assert.Equal(t, "name(web 01)", result.Targets[0].Reason())
+		assert.Equal(t, "id(103)", result.Targets[1].Reason())
+		assert.Nil(t, result.ServerOptions())
+	})
+
+	t.Run("multiple lines", func(t *testing.T) {
+		result, err := New(client).Resolve(&Query{Args: []string{"101\n102"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{101, 102}, result.IDs())
+	})
+
+	t.Run("name and selector", func(t *testing.T) {
+		result, err := New(client).Resolve(&Query{Args: []string{"web", "db"}, Selector: []string{"web"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{101, 102}, result.IDs())
+		assert.Equal(t, "name(web), selector(tag=web)", result.Targets[0].Reason())
+	})
+
+	t.Run("id and selector", func(t *testing.T) {
+		// ID is not filtered by the selector
+		result, err := New(client).Resolve(&Query{Args: []string{"103", "web"}, Selector: []string{"web"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{101, 102, 103}, result.IDs())
+		assert.Equal(t, "name(web), selector(tag=web)", result.Targets[0].Reason())
+		assert.Equal(t, "id(103)", result.Targets[2].Reason())
+	})
+
+	t.Run("selector", func(t *testing.T) {
+		result, err := New(client).Resolve(&Query{Selector: []string{"db"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{103}, result.IDs())
+		assert.Equal(t, []string{"db"}, client.params[len(client.params)-1].Tags)
+
+		_, err = New(client).Resolve(&Query{Selector: []string{"unknown"}})
+		assert.EqualError(t, err, "Find ID is failed: Not Found[with selector tag=unknown]")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := New(client).Resolve(&Query{Args: []string{"999"}})
+		assert.EqualError(t, err, "Find ID is failed: Not Found[with search param 999]")
+
+		_, err = New(client).Resolve(&Query{Args: []string{"unknown"}})
+		assert.EqualError(t, err, `Find ID is failed: Not Found[with search param "unknown"]`)
+
+		_, err = New(client).Resolve(&Query{})
+		assert.EqualError(t, err, "ID or Name argument or --selector option is required")
+	})
+
+	t.Run("ambiguous name", func(t *testing.T) {
+		_, err := New(client).Resolve(&Query{Args: []string{"app"}})
+		assert.Error(t, err)
+		ambiguous, ok := err.(*AmbiguousNameError)
+		assert.True(t, ok)
+		assert.Equal(t, "app", ambiguous.Name)
+		assert.Equal(t, []*sacloud.Server{dup1, dup2}, ambiguous.Servers)
+		assert.EqualError(t, err, `name "app" is ambiguous: servers [104, 105] have the same name "app", specify them by ID`)
+
+		// specified by ID
+		result, err := New(client).Resolve(&Query{Args: []string{"105"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{105}, result.IDs())
+	})
+
+	t.Run("choose", func(t *testing.T) {
+		r := New(client)
+		var asked []string
+		r.Choose = func(name string, servers []*sacloud.Server) ([]*sacloud.Server, error) {
+			asked = append(asked, name)
+			return []*sacloud.Server{servers[1], web1}, nil // web1 is ignored
+		}
+		result, err := r.Resolve(&Query{Args: []string{"app"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"app"}, asked)
+		assert.Equal(t, []int64{105}, result.IDs())
+
+		r.Choose = func(name string, servers []*sacloud.Server) ([]*sacloud.Server, error) {
+			return nil, fmt.Errorf("canceled")
+		}
+		_, err = r.Resolve(&Query{Args: []string{"app"}})
+		assert.EqualError(t, err, "canceled")
+	})
+
+	t.Run("exclude", func(t *testing.T) {
+		result, err := New(client).Resolve(&Query{Args: []string{"web", "db"}, Exclude: []string{"web-02", "tag=db"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{101}, result.IDs())
+		assert.Equal(t, []*Excluded{
+			{Server: web2, Reason: `name "web-02"`},
+			{Server: db, Reason: `tag "db"`},
+		}, result.Excluded)
+		assert.Equal(t, &migrate.Protection{Names: []string{"web-02"}, Tags: []string{"db"}}, result.Protection)
+
+		_, err = New(client).Resolve(&Query{Args: []string{"db"}, Exclude: []string{"103"}})
+		assert.EqualError(t, err, "Target resource is not found")
+	})
+
+	t.Run("deny-list", func(t *testing.T) {
+		denyList := &migrate.Protection{Tags: []string{"db"}}
+		_, err := New(client).Resolve(&Query{Args: []string{"web", "db"}, DenyList: denyList})
+		assert.Error(t, err)
+		assert.IsType(t, &migrate.ProtectedError{}, err)
+
+		result, err := New(client).Resolve(&Query{Args: []string{"web"}, DenyList: denyList})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{101, 102}, result.IDs())
+		assert.Equal(t, &migrate.Protection{Tags: []string{"db"}}, result.Protection)
+	})
+
+	t.Run("inventory", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "resolver")
+		assert.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "inventory.csv")
+		src := "server_id,wave,core,memory,cleanup_disk,boot\n102,1,,,true,false\n101,1\n103,2\n"
+		assert.NoError(t, ioutil.WriteFile(path, []byte(src), 0600))
+
+		result, err := New(client).Resolve(&Query{Inventory: path, Wave: "1"})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{102, 101}, result.IDs())
+		assert.Equal(t, "inventory(line 2)", result.Targets[0].Reason())
+
+		yes := true
+		assert.Equal(t, map[int64]*migrate.ServerOptions{
+			102: {DeleteDisks: &yes, DisableBoot: &yes},
+			101: {},
+		}, result.ServerOptions())
+
+		_, err = New(client).Resolve(&Query{Inventory: path, Args: []string{"101"}})
+		assert.EqualError(t, err, "ID or Name argument can't be used with --inventory option")
+
+		_, err = New(client).Resolve(&Query{Inventory: path, Wave: "3"})
+		assert.EqualError(t, err, `Reading inventory is failed: wave "3" is not found`)
+
+		assert.NoError(t, ioutil.WriteFile(path, []byte("server_id\n999\n"), 0600))
+		_, err = New(client).Resolve(&Query{Inventory: path})
+		assert.EqualError(t, err, "Find ID is failed: Not Found[inventory line 2: 999]")
+
+		// entries of JSON are reported by the index, not the line
+		jsonPath := filepath.Join(dir, "inventory.json")
+		assert.NoError(t, ioutil.WriteFile(jsonPath, []byte(`[{"server_id": 101}, {"server_id": 999}]`), 0600))
+		_, err = New(client).Resolve(&Query{Inventory: jsonPath})
+		assert.EqualError(t, err, "Find ID is failed: Not Found[inventory entry 2: 999]")
+		assert.NoError(t, ioutil.WriteFile(jsonPath, []byte(`[{"server_id": 101}]`), 0600))
+		result, err = New(client).Resolve(&Query{Inventory: jsonPath})
+		assert.NoError(t, err)
+		assert.Equal(t, "inventory(entry 1)", result.Targets[0].Reason())
+	})
+}
@@ -0,0 +1,299 @@
+package selector
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Parse parses the selector expression
+//
+// Expression syntax:
+//
+//	expr := term | "(" expr ")" | "!" expr | expr "&&" expr | expr "||" expr
+//	term := key op value | tag:"<tag>" | tag
+//
+// Available keys are tag, name, desc, plan, core, memory(GB), state and disk(GB).
+// Available operators are "=", "!=", "~"(regexp), ">", ">=", "<" and "<=".
+// The value of "=" and "!=" can be a glob pattern.
+// tag:"<tag>" and a term without known key are exact matches of tag, they are never glob patterns.
+// "and", "or" and "not" can be used instead of "&&", "||" and "!", and
+// terms separated by spaces are joined by "&&".
+//
+// For compatibility with the selector of tags, the whole expression is treated as a tag
+// if it has neither known keys nor "&&", "||", "and", "or" and "not"(e.g. "my tag" or "!important"),
+// or if it can't be parsed and has none of them except known keys(e.g. "disk=ssd").
+func Parse(expr string) (Selector, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty selector")
+	}
+	if !hasOperator(tokens) && !hasKey(tokens) {
+		return newTagSelector(strings.TrimSpace(expr)), nil
+	}
+	p := &parser{tokens: tokens}
+	selector, err := p.parseOr()
+	if err == nil && !p.eof() {
+		err = fmt.Errorf("unexpected token %q in selector %q", p.peek().value, expr)
+	}
+	if err != nil {
+		if !hasOperator(tokens) {
+			return newTagSelector(strings.TrimSpace(expr)), nil
+		}
+		return nil, err
+	}
+	return selector, nil
+}
+
+// ParseAll parses each expression and joins them by "&&"
+func ParseAll(exprs []string) (Selector, error) {
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	var conditions []Selector
+	for _, expr := range exprs {
+		s, err := Parse(expr)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, s)
+	}
+	if len(conditions) == 1 {
+		return conditions[0], nil
+	}
+	return &andSelector{conditions: conditions}, nil
+}
+
+type tokenType int
+
+const (
+	tokenTerm tokenType = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	typ   tokenType
+	value string
+}
+
+func tokenize(expr string) ([]*token, error) {
+	var tokens []*token
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, &token{typ: tokenLParen, value: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, &token{typ: tokenRParen, value: ")"})
+			i++
+		case r == '!':
+			tokens = append(tokens, &token{typ: tokenNot, value: "!"})
+			i++
+		case hasPrefixAt(runes, i, "&&"):
+			tokens = append(tokens, &token{typ: tokenAnd, value: "&&"})
+			i += 2
+		case hasPrefixAt(runes, i, "||"):
+			tokens = append(tokens, &token{typ: tokenOr, value: "||"})
+			i += 2
+		default:
+			var term []rune
+			quoted := false
+			for ; i < len(runes); i++ {
+				c := runes[i]
+				if c == '"' {
+					quoted = !quoted
+					continue
+				}
+				if !quoted && (unicode.IsSpace(c) || c == '(' || c == ')' || hasPrefixAt(runes, i, "&&") || hasPrefixAt(runes, i, "||")) {
+					break
+				}
+				term = append(term, c)
+			}
+			if quoted {
+				return nil, fmt.Errorf("unterminated quote in selector %q", expr)
+			}
+
+			value := string(term)
+			switch strings.ToLower(value) {
+			case "and":
+				tokens = append(tokens, &token{typ: tokenAnd, value: value})
+			case "or":
+				tokens = append(tokens, &token{typ: tokenOr, value: value})
+			case "not":
+				tokens = append(tokens, &token{typ: tokenNot, value: value})
+			default:
+				tokens = append(tokens, &token{typ: tokenTerm, value: value})
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// hasOperator returns true if tokens have "&&", "||" or their words, "!" isn't included because tags may start with it
+func hasOperator(tokens []*token) bool {
+	for _, t := range tokens {
+		if t.typ == tokenAnd || t.typ == tokenOr || (t.typ == tokenNot && t.value != "!") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKey returns true if tokens have a term with known key
+func hasKey(tokens []*token) bool {
+	for _, t := range tokens {
+		if t.typ == tokenTerm && termKey(t.value) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefixAt(runes []rune, i int, prefix string) bool {
+	return strings.HasPrefix(string(runes[i:]), prefix)
+}
+
+type parser struct {
+	tokens []*token
+	pos    int
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() *token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() *token {
+	t := p.tokens[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *parser) parseOr() (Selector, error) {
+	var conditions []Selector
+	for {
+		s, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, s)
+		if p.eof() || p.peek().typ != tokenOr {
+			break
+		}
+		p.next()
+	}
+	if len(conditions) == 1 {
+		return conditions[0], nil
+	}
+	return &orSelector{conditions: conditions}, nil
+}
+
+func (p *parser) parseAnd() (Selector, error) {
+	var conditions []Selector
+	for {
+		s, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, s)
+		if p.eof() {
+			break
+		}
+		switch p.peek().typ {
+		case tokenAnd:
+			p.next()
+			continue
+		case tokenTerm, tokenNot, tokenLParen:
+			// スペース区切りはAND条件
+			continue
+		}
+		break
+	}
+	if len(conditions) == 1 {
+		return conditions[0], nil
+	}
+	return &andSelector{conditions: conditions}, nil
+}
+
+func (p *parser) parseNot() (Selector, error) {
+	if p.eof() {
+		return nil, fmt.Errorf("unexpected end of selector")
+	}
+	t := p.next()
+	switch t.typ {
+	case tokenNot:
+		s, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notSelector{condition: s}, nil
+	case tokenLParen:
+		s, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.eof() || p.next().typ != tokenRParen {
+			return nil, fmt.Errorf("missing \")\" in selector")
+		}
+		return s, nil
+	case tokenTerm:
+		return parseTerm(t.value)
+	}
+	return nil, fmt.Errorf("unexpected token %q in selector", t.value)
+}
+
+var operators = []string{opNotEqual, opGreaterEqual, opLessEqual, opEqual, opMatch, opGreater, opLess}
+
+func parseTerm(term string) (Selector, error) {
+	if strings.HasPrefix(term, tagPrefix) {
+		return newTagSelector(strings.TrimPrefix(term, tagPrefix)), nil
+	}
+	if key := termKey(term); key != "" && key != tagPrefix {
+		i := len(key)
+		for _, op := range operators {
+			if strings.HasPrefix(term[i:], op) {
+				return newTermSelector(strings.ToLower(key), op, term[i+len(op):])
+			}
+		}
+	}
+	// "env=prod"のようなタグとして扱う
+	return newTagSelector(term), nil
+}
+
+// tagPrefix is the prefix of the term matched with the tag exactly
+const tagPrefix = "tag:"
+
+// termKey returns the known key of the term, or tagPrefix for tag:"<tag>"
+func termKey(term string) string {
+	if strings.HasPrefix(term, tagPrefix) {
+		return tagPrefix
+	}
+	for i := range term {
+		for _, op := range operators {
+			if !strings.HasPrefix(term[i:], op) {
+				continue
+			}
+			key := strings.ToLower(term[:i])
+			if inStrings(key, stringKeys) || inStrings(key, numericKeys) {
+				return term[:i]
+			}
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,293 @@
+package selector
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sacloud/libsacloud/sacloud"
+)
+
+// Selector is a condition to select target servers
+type Selector interface {
+	Match(server *sacloud.Server) bool
+	String() string
+}
+
+// Filter returns servers matched with the selector
+func Filter(servers []*sacloud.Server, selector Selector) []*sacloud.Server {
+	if selector == nil {
+		return servers
+	}
+	var res []*sacloud.Server
+	for _, s := range servers {
+		if selector.Match(s) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// RequiredTags returns tags which all of matched servers must have
+//
+// It is used for narrowing down the servers by the API before evaluating the selector.
+func RequiredTags(selector Selector) []string {
+	switch s := selector.(type) {
+	case *andSelector:
+		var tags []string
+		for _, c := range s.conditions {
+			tags = append(tags, RequiredTags(c)...)
+		}
+		return tags
+	case *termSelector:
+		if s.key == keyTag && s.op == opEqual && (s.literal || !isGlob(s.value)) {
+			return []string{s.value}
+		}
+	}
+	return nil
+}
+
+type andSelector struct {
+	conditions []Selector
+}
+
+func (s *andSelector) Match(server *sacloud.Server) bool {
+	for _, c := range s.conditions {
+		if !c.Match(server) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *andSelector) String() string {
+	return joinSelectors(s.conditions, " && ")
+}
+
+type orSelector struct {
+	conditions []Selector
+}
+
+func (s *orSelector) Match(server *sacloud.Server) bool {
+	for _, c := range s.conditions {
+		if c.Match(server) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *orSelector) String() string {
+	return joinSelectors(s.conditions, " || ")
+}
+
+type notSelector struct {
+	condition Selector
+}
+
+func (s *notSelector) Match(server *sacloud.Server) bool {
+	return !s.condition.Match(server)
+}
+
+func (s *notSelector) String() string {
+	return fmt.Sprintf("!%s", s.condition)
+}
+
+func joinSelectors(conditions []Selector, sep string) string {
+	var list []string
+	for _, c := range conditions {
+		list = append(list, c.String())
+	}
+	return fmt.Sprintf("(%s)", strings.Join(list, sep))
+}
+
+const (
+	keyTag         = "tag"
+	keyName        = "name"
+	keyDescription = "desc"
+	keyPlan        = "plan"
+	keyCore        = "core"
+	keyMemory      = "memory"
+	keyState       = "state"
+	keyDisk        = "disk"
+)
+
+const (
+	opEqual        = "="
+	opNotEqual     = "!="
+	opMatch        = "~"
+	opGreater      = ">"
+	opGreaterEqual = ">="
+	opLess         = "<"
+	opLessEqual    = "<="
+)
+
+var (
+	stringKeys  = []string{keyTag, keyName, keyDescription, keyPlan, keyState}
+	numericKeys = []string{keyCore, keyMemory, keyDisk}
+)
+
+type termSelector struct {
+	key     string
+	op      string
+	value   string
+	number  int
+	regexp  *regexp.Regexp
+	literal bool // value is not a glob pattern
+}
+
+// newTagSelector returns the selector matched with the tag exactly
+func newTagSelector(tag string) *termSelector {
+	return &termSelector{key: keyTag, op: opEqual, value: tag, literal: true}
+}
+
+func newTermSelector(key, op, value string) (*termSelector, error) {
+	s := &termSelector{key: key, op: op, value: value}
+
+	switch {
+	case inStrings(key, stringKeys):
+		switch op {
+		case opEqual, opNotEqual:
+			if isGlob(value) {
+				if _, err := path.Match(value, ""); err != nil {
+					return nil, fmt.Errorf("invalid pattern %q: %s", value, err)
+				}
+			}
+		case opMatch:
+			r, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regexp %q: %s", value, err)
+			}
+			s.regexp = r
+		default:
+			return nil, fmt.Errorf("operator %q is not supported for %q", op, key)
+		}
+		if key == keyPlan && op != opMatch {
+			if _, err := planGeneration(value); err != nil {
+				return nil, err
+			}
+		}
+	case inStrings(key, numericKeys):
+		if op == opMatch {
+			return nil, fmt.Errorf("operator %q is not supported for %q", op, key)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q requires number: %q", key, value)
+		}
+		s.number = n
+	default:
+		return nil, fmt.Errorf("unknown key: %q", key)
+	}
+	return s, nil
+}
+
+func (s *termSelector) String() string {
+	if s.literal && (isGlob(s.value) || strings.ContainsAny(s.value, " \t\"()!&|")) {
+		return fmt.Sprintf("%s%q", tagPrefix, s.value)
+	}
+	return fmt.Sprintf("%s%s%s", s.key, s.op, s.value)
+}
+
+func (s *termSelector) Match(server *sacloud.Server) bool {
+	switch s.key {
+	case keyTag:
+		for _, tag := range server.Tags {
+			if s.matchString(tag) {
+				return s.op != opNotEqual
+			}
+		}
+		return s.op == opNotEqual
+	case keyName:
+		return s.matchStringWithNegation(server.Name)
+	case keyDescription:
+		return s.matchStringWithNegation(server.Description)
+	case keyState:
+		return s.matchStringWithNegation(server.GetInstanceStatus())
+	case keyPlan:
+		if s.op == opMatch {
+			return s.regexp.MatchString(planName(server))
+		}
+		gen, _ := planGeneration(s.value)
+		matched := server.ServerPlan != nil && server.ServerPlan.Generation == gen
+		return matched == (s.op == opEqual)
+	case keyCore:
+		return s.compare(server.GetCPU())
+	case keyMemory:
+		return s.compare(server.GetMemoryGB())
+	case keyDisk:
+		// いずれかのディスクが条件を満たせば対象とする
+		for _, disk := range server.Disks {
+			if s.compare(disk.GetSizeGB()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *termSelector) matchString(v string) bool {
+	switch {
+	case s.op == opMatch:
+		return s.regexp.MatchString(v)
+	case !s.literal && isGlob(s.value):
+		matched, _ := path.Match(s.value, v)
+		return matched
+	default:
+		return s.value == v
+	}
+}
+
+func (s *termSelector) matchStringWithNegation(v string) bool {
+	return s.matchString(v) != (s.op == opNotEqual)
+}
+
+func (s *termSelector) compare(v int) bool {
+	switch s.op {
+	case opEqual:
+		return v == s.number
+	case opNotEqual:
+		return v != s.number
+	case opGreater:
+		return v > s.number
+	case opGreaterEqual:
+		return v >= s.number
+	case opLess:
+		return v < s.number
+	case opLessEqual:
+		return v <= s.number
+	}
+	return false
+}
+
+func planGeneration(v string) (sacloud.PlanGenerations, error) {
+	switch strings.ToLower(v) {
+	case "g1", "1", "100":
+		return sacloud.PlanG1, nil
+	case "g2", "2", "200":
+		return sacloud.PlanG2, nil
+	}
+	return sacloud.PlanDefault, fmt.Errorf("plan must be g1 or g2: %q", v)
+}
+
+func planName(server *sacloud.Server) string {
+	if server.ServerPlan == nil {
+		return ""
+	}
+	return server.ServerPlan.Name
+}
+
+func isGlob(v string) bool {
+	return strings.ContainsAny(v, "*?[")
+}
+
+func inStrings(v string, list []string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package selector
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sacloud/libsacloud/sacloud"
+	"github.com/stretchr/testify/assert"
+)
+
+func testServer(name string, tags []string, gen sacloud.PlanGenerations, core, memoryGB int, status string, diskSizeGB ...int) *sacloud.Server {
+	server := &sacloud.Server{Resource: sacloud.NewResource(1)}
+	server.Name = name
+	server.Description = name + " server"
+	server.Tags = tags
+	server.SetServerPlanByValue(core, memoryGB, gen)
+	server.Instance = &sacloud.Instance{
+		EServerInstanceStatus: &sacloud.EServerInstanceStatus{Status: status},
+	}
+	for _, size := range diskSizeGB {
+		disk := sacloud.Disk{}
+		disk.SizeMB = size * 1024
+		server.Disks = append(server.Disks, disk)
+	}
+	return server
+}
+
+func TestParse(t *testing.T) {
+
+	web := testServer("web-01", []string{"web", "env=prod"}, sacloud.PlanG1, 2, 4, "up", 20, 100)
+	db := testServer("db-01", []string{"db", "env=prod"}, sacloud.PlanG1, 4, 16, "down", 250)
+	dev := testServer("web-dev", []string{"web", "env=dev"}, sacloud.PlanG2, 1, 1, "up", 20)
+
+	expects := []struct {
+		expr    string
+		matched []*sacloud.Server
+	}{
+		{expr: "web", matched: []*sacloud.Server{web, dev}},
+		{expr: "env=prod", matched: []*sacloud.Server{web, db}},
+		{expr: "web && env=prod", matched: []*sacloud.Server{web}},
+		{expr: "core>=2 web", matched: []*sacloud.Server{web}},
+		{expr: "web || db", matched: []*sacloud.Server{web, db, dev}},
+		{expr: "tag=web && !tag=env=dev", matched: []*sacloud.Server{web}},
+		{expr: "not tag=web", matched: []*sacloud.Server{db}},
+		{expr: "tag=env=*", matched: []*sacloud.Server{web, db, dev}},
+		{expr: "name=web-*", matched: []*sacloud.Server{web, dev}},
+		{expr: `name~^web-\d+$`, matched: []*sacloud.Server{web}},
+		{expr: `desc="db-01 server"`, matched: []*sacloud.Server{db}},
+		{expr: "plan=g1", matched: []*sacloud.Server{web, db}},
+		{expr: "core>=2 memory<16", matched: []*sacloud.Server{web}},
+		{expr: "state=down", matched: []*sacloud.Server{db}},
+		{expr: "disk>50", matched: []*sacloud.Server{web, db}},
+		{expr: "(name=db-* || disk<=20) && plan!=g2", matched: []*sacloud.Server{web, db}},
+		{expr: `tag:"env=prod" || tag:env=dev`, matched: []*sacloud.Server{web, db, dev}},
+	}
+
+	for _, expect := range expects {
+		s, err := Parse(expect.expr)
+		assert.NoError(t, err, expect.expr)
+		assert.Equal(t, expect.matched, Filter([]*sacloud.Server{web, db, dev}, s), expect.expr)
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	exprs := []string{
+		"",
+		"(web || db",
+		"web || db)",
+		"web &&",
+		`name="web`,
+		"name~[ && web",
+		"core=a || web",
+		"core~1 and web",
+		"not plan=g3",
+	}
+	for _, expr := range exprs {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestRequiredTags(t *testing.T) {
+	s, err := ParseAll([]string{"web && env=prod", "name=web-*", "db || dev", "tag=env=*", "a*b", "my tag"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web", "env=prod", "a*b", "my tag"}, RequiredTags(s))
+}
+
+func TestParse_LegacyTag(t *testing.T) {
+
+	// tags which look like expressions are matched exactly as the selector of tags
+	tags := []string{
+		"my tag",
+		"web env=prod",
+		"disk=ssd",
+		"core=x",
+		"plan=foo",
+		"a*b",
+		"[web]",
+		"!important",
+		"(web",
+		"web)",
+		"(web)",
+	}
+	for _, tag := range tags {
+		s, err := Parse(tag)
+		if !assert.NoError(t, err, tag) {
+			continue
+		}
+		server := testServer("web-01", []string{tag}, sacloud.PlanG1, 1, 1, "up", 20)
+		other := testServer("web-02", []string{"a-b", "ab", "web", "important"}, sacloud.PlanG1, 1, 1, "up", 20)
+		assert.Equal(t, []*sacloud.Server{server}, Filter([]*sacloud.Server{server, other}, s), tag)
+		assert.Equal(t, []string{tag}, RequiredTags(s), tag)
+	}
+
+	// tags with known keys are specified by tag:"<tag>"
+	for _, tag := range []string{"name=web", "state=up", "plan=g1"} {
+		s, err := Parse(fmt.Sprintf("tag:%q", tag))
+		if !assert.NoError(t, err, tag) {
+			continue
+		}
+		server := testServer("db-01", []string{tag}, sacloud.PlanG2, 1, 1, "down", 20)
+		other := testServer("web", []string{"web"}, sacloud.PlanG1, 1, 1, "up", 20)
+		assert.Equal(t, []*sacloud.Server{server}, Filter([]*sacloud.Server{server, other}, s), tag)
+	}
+
+	s, err := Parse(`tag:"a*b"`)
+	assert.NoError(t, err)
+	assert.Equal(t, `tag:"a*b"`, s.String())
+}